@@ -4,9 +4,11 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
 )
 
 // RunAtomic runs operation which has to be atomic.
@@ -21,3 +23,25 @@ func RunAtomic(operation func() (isCompleted bool), log ss.LogSource) error {
 	}
 	return fmt.Errorf(`failed to execute atomic operation %d times`, count+1)
 }
+
+// RunOptimistic runs operation which uses optimistic locking (see
+// ddb.Versioned). The operation is repeated only if it fails by version
+// or transaction conflict, any other error is returned as is.
+func RunOptimistic(operation func() error, log ss.LogSource) error {
+	count := 0
+	for ; count < 10; count++ {
+		err := operation()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ddb.ErrVersionConflict) &&
+			!errors.Is(err, ddb.ErrTransactionConflict) {
+			return err
+		}
+		log.Log().Debug(
+			ss.
+				NewLogMsg("repeating optimistic operation %d time...", count+2).
+				AddErr(err))
+	}
+	return fmt.Errorf(`failed to execute optimistic operation %d times`, count+1)
+}
@@ -0,0 +1,229 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package outboxlambda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/palchukovsky/ss"
+	apidbevent "github.com/palchukovsky/ss/api/dbevent"
+	"github.com/palchukovsky/ss/ddb"
+	sslambda "github.com/palchukovsky/ss/lambda"
+	dbeventlambda "github.com/palchukovsky/ss/lambda/dbevent"
+	"github.com/palchukovsky/ss/push"
+)
+
+// maxAttempts is the number of sending attempts before the message is
+// dropped. Each failed attempt updates the record with the next attempt time,
+// so the record update event starts the next attempt after the delay.
+const maxAttempts = 5
+
+// retryDelay is the delay before the second attempt, each next attempt
+// doubles it. The stream handler waits the delay, so the delay is limited by
+// the part of the lambda timeout, to not block other messages of the stream
+// shard for long.
+const retryDelay = 100 * time.Millisecond
+
+type pushService interface {
+	PushE(
+		ss.UserID,
+		func() push.Message,
+		[]ss.FirebaseCloudMessagingToken,
+		ss.LogStream,
+	) ([]ss.FirebaseCloudMessagingToken, error)
+	Sync(ss.LogStream)
+}
+
+type gateway interface {
+	Post(ss.ConnectionID, []byte) error
+}
+
+// Lambda sends messages from the outbox table, and deletes each message
+// after it's sent.
+type lambda struct {
+	db            ddb.Client
+	push          pushService
+	gateway       gateway
+	retryDelay    time.Duration
+	maxRetryDelay time.Duration
+}
+
+func newLambda() dbeventlambda.Lambda {
+	db := ddb.GetClientInstance()
+	return lambda{
+		db:            db,
+		push:          push.NewService(db),
+		gateway:       sslambda.NewGateway(),
+		retryDelay:    retryDelay,
+		maxRetryDelay: ss.S.Config().AWS.LambdaTimeout / 4,
+	}
+}
+
+func (lambda lambda) Execute(request dbeventlambda.Request) error {
+	for _, event := range request.GetEvents() {
+		if err := lambda.execute(request, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lambda lambda) execute(
+	request dbeventlambda.Request,
+	event events.DynamoDBEventRecord,
+) error {
+	switch events.DynamoDBOperationType(event.EventName) {
+	case events.DynamoDBOperationTypeInsert,
+		events.DynamoDBOperationTypeModify:
+		break
+	default:
+		return nil
+	}
+
+	var message ddb.OutboxRecord
+	apidbevent.UnmarshalEventsDynamoDBAttributeValues(
+		event.Change.NewImage,
+		&message)
+
+	request.PushLogSession(func() ss.LogPrefix {
+		return ss.
+			NewLogPrefix(func() []ss.LogMsgAttr { return nil }).
+			AddVal("outbox", message.ID)
+	})
+	defer func() { request.PopLogSession(recover()) }()
+
+	// The event could be repeated by the batch retry after the message is
+	// already sent, or the message could have the newer attempt.
+	attempts := message.Attempts
+	if !lambda.db.Find(&message).Request() || message.Attempts != attempts {
+		return nil
+	}
+
+	if err := lambda.waitNextAttempt(message); err != nil {
+		return err
+	}
+
+	sent, err := lambda.send(message, request.Log())
+	if err == nil {
+		lambda.db.DeleteIfExisting(message).Request()
+		return nil
+	}
+
+	logMessage := ss.
+		NewLogMsg("failed to send outbox message, attempt %d", attempts+1).
+		AddErr(err).
+		AddDump(message)
+	if attempts+1 >= maxAttempts {
+		request.Log().Error(logMessage)
+		lambda.db.DeleteIfExisting(message).Request()
+		return nil
+	}
+	request.Log().Warn(logMessage)
+	actions := []ddb.UpdateAction{
+		ddb.Increment("attempts", 1),
+		ddb.Set(
+			"next",
+			time.Now().Add(lambda.getRetryDelay(attempts)).UnixMilli()),
+	}
+	if len(sent) > 0 {
+		actions = append(actions, ddb.Add("sent", sent))
+	}
+	lambda.db.Update(message).Apply(actions...).Request()
+	return nil
+}
+
+func (lambda lambda) getRetryDelay(attempts int) time.Duration {
+	result := lambda.retryDelay << attempts
+	if result > lambda.maxRetryDelay {
+		result = lambda.maxRetryDelay
+	}
+	return result
+}
+
+// waitNextAttempt blocks until the next attempt time, but not longer than
+// the max retry delay. If the lambda times out before, the error is returned,
+// so the batch is retried by the stream.
+func (lambda lambda) waitNextAttempt(message ddb.OutboxRecord) error {
+	if message.NextAttempt == 0 {
+		return nil
+	}
+	delay := time.Until(time.UnixMilli(message.NextAttempt))
+	if delay > lambda.maxRetryDelay {
+		delay = lambda.maxRetryDelay
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ss.S.SubscribeForLambdaTimeout():
+		return errors.New("lambda timeout before next outbox attempt")
+	}
+}
+
+// send sends the message, and returns FCM tokens of devices which got
+// the push message at this attempt.
+func (lambda lambda) send(
+	message ddb.OutboxRecord,
+	log ss.LogStream,
+) ([]string, error) {
+	switch message.Kind {
+	case ddb.OutboxMessageKindPush:
+		user, err := ss.ParseUserID(message.Recipient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse push recipient: %w", err)
+		}
+		sent := make([]ss.FirebaseCloudMessagingToken, len(message.Sent))
+		for i, token := range message.Sent {
+			sent[i] = ss.NewFirebaseCloudMessagingToken(token)
+		}
+		sent, err = lambda.push.PushE(
+			user,
+			func() push.Message { return newPushMessage(message) },
+			sent,
+			log)
+		lambda.push.Sync(log)
+		result := make([]string, len(sent))
+		for i, token := range sent {
+			result[i] = string(token)
+		}
+		return result, err
+	case ddb.OutboxMessageKindGateway:
+		err := lambda.gateway.Post(
+			ss.ConnectionID(message.Recipient),
+			message.Data)
+		if errors.Is(err, sslambda.ErrGatewayConnectionGone) {
+			log.Debug(
+				ss.NewLogMsg("no connection to send outbox message").AddErr(err))
+			return nil, nil
+		}
+		return nil, err
+	}
+	return nil, fmt.Errorf("unknown outbox message kind %q", message.Kind)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type pushMessage struct{ source ddb.OutboxRecord }
+
+func newPushMessage(source ddb.OutboxRecord) push.Message {
+	return pushMessage{source: source}
+}
+
+func (message pushMessage) GetType() push.EntityTypeName {
+	return push.EntityTypeName(message.source.Type)
+}
+
+func (message pushMessage) GetData() interface{} {
+	return json.RawMessage(message.source.Data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
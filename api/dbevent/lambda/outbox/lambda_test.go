@@ -0,0 +1,313 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package outboxlambda
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	sslambda "github.com/palchukovsky/ss/lambda"
+	mock_ss "github.com/palchukovsky/ss/mock"
+	"github.com/palchukovsky/ss/push"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testLog accepts all records, panics are repeated, so the test fails.
+type testLog struct{ ss.Log }
+
+func (testLog) Debug(*ss.LogMsg) {}
+func (testLog) Warn(*ss.LogMsg)  {}
+func (testLog) Error(*ss.LogMsg) {}
+
+func setTestService(test *testing.T, lambdaTimeout <-chan struct{}) {
+	mock := gomock.NewController(test)
+	test.Cleanup(mock.Finish)
+
+	service := mock_ss.NewMockService(mock)
+	service.EXPECT().Log().AnyTimes().Return(testLog{})
+	service.EXPECT().Config().AnyTimes().Return(ss.ServiceConfig{})
+	service.EXPECT().
+		NewBuildEntityName(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(name string) string { return "test_" + name })
+	service.EXPECT().
+		SubscribeForLambdaTimeout().
+		AnyTimes().
+		Return(lambdaTimeout)
+	service.EXPECT().
+		AddLambdaScopeHandler(gomock.Any()).
+		AnyTimes().
+		Return(func() {})
+	ss.Set(service)
+}
+
+type testRequest struct {
+	events []events.DynamoDBEventRecord
+}
+
+func newTestRequest(record ddb.OutboxRecord) testRequest {
+	id := record.ID
+	return testRequest{
+		events: []events.DynamoDBEventRecord{{
+			EventName: string(events.DynamoDBOperationTypeModify),
+			Change: events.DynamoDBStreamRecord{
+				NewImage: map[string]events.DynamoDBAttributeValue{
+					"id": events.NewBinaryAttribute(id[:]),
+					"attempts": events.NewNumberAttribute(
+						strconv.Itoa(record.Attempts)),
+				},
+			},
+		}},
+	}
+}
+
+func (testRequest) Log() ss.LogStream                  { return testLog{} }
+func (testRequest) PushLogSession(func() ss.LogPrefix) {}
+func (testRequest) PopLogSession(panicValue interface{}) {
+	if panicValue != nil {
+		panic(panicValue)
+	}
+}
+func (request testRequest) GetEvents() []events.DynamoDBEventRecord {
+	return request.events
+}
+
+// testPush sends the message to devices of the test lambda, and fails for
+// devices from the failed set.
+type testPush struct{ lambda *testLambda }
+
+func (push testPush) PushE(
+	user ss.UserID,
+	_ func() push.Message,
+	sent []ss.FirebaseCloudMessagingToken,
+	_ ss.LogStream,
+) ([]ss.FirebaseCloudMessagingToken, error) {
+	push.lambda.users = append(push.lambda.users, user)
+	skip := map[ss.FirebaseCloudMessagingToken]bool{}
+	for _, device := range sent {
+		skip[device] = true
+	}
+	var result []ss.FirebaseCloudMessagingToken
+	var err error
+	for _, device := range push.lambda.devices {
+		if skip[device] {
+			continue
+		}
+		push.lambda.pushes = append(push.lambda.pushes, device)
+		if push.lambda.failedDevices[device] {
+			err = errors.New("test push error")
+			continue
+		}
+		result = append(result, device)
+	}
+	return result, err
+}
+
+func (testPush) Sync(ss.LogStream) {}
+
+type testGateway struct{ lambda *testLambda }
+
+func (gateway testGateway) Post(connection ss.ConnectionID, _ []byte) error {
+	gateway.lambda.posts = append(gateway.lambda.posts, connection)
+	return gateway.lambda.gatewayErr
+}
+
+type testLambda struct {
+	lambda
+	client        ddb.Client
+	users         []ss.UserID
+	devices       []ss.FirebaseCloudMessagingToken
+	failedDevices map[ss.FirebaseCloudMessagingToken]bool
+	pushes        []ss.FirebaseCloudMessagingToken
+	posts         []ss.ConnectionID
+	gatewayErr    error
+}
+
+func newTestLambda(test *testing.T) *testLambda {
+	setTestService(test, make(chan struct{}))
+	db := ddbtest.NewDB()
+	db.CreateTable(ddb.OutboxRecord{})
+	result := &testLambda{
+		client:        ddbtest.NewClient(db),
+		devices:       []ss.FirebaseCloudMessagingToken{"a", "b"},
+		failedDevices: map[ss.FirebaseCloudMessagingToken]bool{},
+	}
+	result.lambda = lambda{
+		db:            result.client,
+		push:          testPush{lambda: result},
+		gateway:       testGateway{lambda: result},
+		maxRetryDelay: time.Minute,
+	}
+	return result
+}
+
+func (lambda *testLambda) create(message ddb.OutboxMessage) ddb.OutboxRecord {
+	result := ddb.NewOutboxRecord(message)
+	lambda.client.CreateOrReplace(result).Request()
+	return result
+}
+
+// find returns the record from the database, false if it's deleted.
+func (lambda *testLambda) find(record *ddb.OutboxRecord) bool {
+	return lambda.client.Find(record).Request()
+}
+
+func newTestPushMessage(user ss.UserID) ddb.OutboxMessage {
+	return ddb.OutboxMessage{
+		Kind:      ddb.OutboxMessageKindPush,
+		Recipient: user.String(),
+		Type:      "test",
+		Data:      []byte(`{}`),
+	}
+}
+
+func newTestGatewayMessage() ddb.OutboxMessage {
+	return ddb.OutboxMessage{
+		Kind:      ddb.OutboxMessageKindGateway,
+		Recipient: "connection",
+		Data:      []byte(`{"m":"test"}`),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_OutboxLambda_Lambda_Success(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+
+	record := lambda.create(newTestGatewayMessage())
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal([]ss.ConnectionID{"connection"}, lambda.posts)
+	assert.False(lambda.find(&record))
+
+	// The repeated event doesn't send the message again.
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal(1, len(lambda.posts))
+
+	user := ss.NewUserID()
+	record = lambda.create(newTestPushMessage(user))
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal([]ss.UserID{user}, lambda.users)
+	assert.Equal(lambda.devices, lambda.pushes)
+	assert.False(lambda.find(&record))
+}
+
+func Test_OutboxLambda_Lambda_GoneConnection(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+	lambda.gatewayErr = fmt.Errorf("%w: test", sslambda.ErrGatewayConnectionGone)
+
+	record := lambda.create(newTestGatewayMessage())
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal(1, len(lambda.posts))
+	// The message for the closed connection is not retried.
+	assert.False(lambda.find(&record))
+}
+
+func Test_OutboxLambda_Lambda_Retry(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+	lambda.retryDelay = time.Hour
+	lambda.gatewayErr = errors.New("test gateway error")
+
+	record := lambda.create(newTestGatewayMessage())
+	event := newTestRequest(record)
+	start := time.Now()
+	assert.NoError(lambda.Execute(event))
+	assert.Equal(1, len(lambda.posts))
+	assert.True(lambda.find(&record))
+	assert.Equal(1, record.Attempts)
+	// The delay is limited by the max retry delay.
+	next := time.UnixMilli(record.NextAttempt)
+	assert.False(next.Before(start.Add(time.Minute).Truncate(time.Millisecond)))
+	assert.False(next.After(time.Now().Add(time.Minute)))
+
+	// The stale event doesn't start new attempt.
+	assert.NoError(lambda.Execute(event))
+	assert.Equal(1, len(lambda.posts))
+}
+
+func Test_OutboxLambda_Lambda_PushRetry(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+	lambda.maxRetryDelay = 0
+	lambda.failedDevices["b"] = true
+
+	record := lambda.create(newTestPushMessage(ss.NewUserID()))
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal([]ss.FirebaseCloudMessagingToken{"a", "b"}, lambda.pushes)
+	assert.True(lambda.find(&record))
+	assert.Equal(1, record.Attempts)
+	assert.Equal([]string{"a"}, record.Sent)
+
+	// The next attempt sends the message only to devices which didn't get it.
+	lambda.failedDevices["b"] = false
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal([]ss.FirebaseCloudMessagingToken{"a", "b", "b"}, lambda.pushes)
+	assert.False(lambda.find(&record))
+}
+
+func Test_OutboxLambda_Lambda_MaxAttempts(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+	lambda.maxRetryDelay = 0
+	lambda.gatewayErr = errors.New("test gateway error")
+
+	record := lambda.create(newTestGatewayMessage())
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		assert.NoError(lambda.Execute(newTestRequest(record)))
+		assert.Equal(attempt, len(lambda.posts))
+		assert.True(lambda.find(&record))
+		assert.Equal(attempt, record.Attempts)
+	}
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Equal(maxAttempts, len(lambda.posts))
+	assert.False(lambda.find(&record))
+}
+
+func Test_OutboxLambda_Lambda_Wait(test *testing.T) {
+	assert := assert.New(test)
+	lambda := newTestLambda(test)
+	lambda.retryDelay = time.Hour
+	lambda.gatewayErr = errors.New("test gateway error")
+
+	record := lambda.create(newTestGatewayMessage())
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.True(lambda.find(&record))
+
+	{
+		lambdaTimeout := make(chan struct{})
+		close(lambdaTimeout)
+		setTestService(test, lambdaTimeout)
+		// The next attempt is not started before its time, the batch is
+		// retried.
+		assert.Error(lambda.Execute(newTestRequest(record)))
+		assert.Equal(1, len(lambda.posts))
+		assert.True(lambda.find(&record))
+		assert.Equal(1, record.Attempts)
+		setTestService(test, make(chan struct{}))
+	}
+
+	// The handler doesn't wait longer than the max retry delay, even if
+	// the next attempt time is later.
+	lambda.maxRetryDelay = 10 * time.Millisecond
+	start := time.Now()
+	assert.NoError(lambda.Execute(newTestRequest(record)))
+	assert.Less(time.Since(start), time.Second)
+	assert.Equal(2, len(lambda.posts))
+	assert.True(lambda.find(&record))
+	assert.Equal(2, record.Attempts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -57,42 +57,22 @@ func (deleter *deleter) SpawnUpdate() {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-type connectionRecord struct {
-	db.ConnectionUserIndex
-	db.ConnectionKeyValue
-}
-
-func (record *connectionRecord) Clear() { *record = connectionRecord{} }
-
 func (deleter *deleter) deleteConnections() {
 
-	var record connectionRecord
-	it := deleter.
-		db.
-		Index(&record).
-		Query("user = :u", ddb.Values{":u": deleter.user}).
-		RequestPaged()
+	it := db.FindUserConnections(deleter.user, deleter.db)
 
+	keys := []ddb.KeyRecord{}
 	for it.Next() {
-		deleter.barrier.Add(1)
-		go func(key db.ConnectionKeyValue) {
-			defer deleter.barrier.Done()
-			defer func() { ss.S.Log().CheckExit(recover()) }()
-
-			trans := deleter.db.Delete(db.NewConnectionKey(key.ID))
-			trans.AllowConditionalCheckFail()
-
-			if trans.Request().IsSuccess() {
-				deleter.request.Log().Info(
-					ss.NewLogMsg("deleted connection").Add(key.ID))
-			} else {
-				deleter.request.Log().Debug(
-					ss.NewLogMsg("no connection record found").Add(key.ID))
-			}
-
-		}(record.ConnectionKeyValue)
-
+		id := it.Get()
+		deleter.request.Log().Debug(ss.NewLogMsg("deleting connection").Add(id))
+		keys = append(keys, db.NewConnectionKey(id))
 	}
+	if len(keys) == 0 {
+		deleter.request.Log().Debug(ss.NewLogMsg("no connection records found"))
+		return
+	}
+
+	deleter.delete(keys, "connections")
 
 }
 
@@ -103,41 +83,62 @@ type deviceRecord struct {
 	db.DeviceKeyValue
 }
 
-func (record *deviceRecord) Clear() { *record = deviceRecord{} }
-
 func (deleter *deleter) deleteDevices() {
-	deleter.barrier.Add(1)
-	go func() {
-		defer deleter.barrier.Done()
-		defer func() { ss.S.Log().CheckExit(recover()) }()
 
-		var record deviceRecord
-		it := deleter.
-			db.
-			Index(&record).
-			Query("user = :u", ddb.Values{":u": deleter.user}).
-			RequestPaged()
-
-		for it.Next() {
-			deleter.barrier.Add(1)
-			go func(key db.DeviceKeyValue) {
-				defer deleter.barrier.Done()
-				defer func() { ss.S.Log().CheckExit(recover()) }()
-
-				trans := deleter.db.Delete(db.NewDeviceKey(key.FCMToken))
-				trans.AllowConditionalCheckFail()
-
-				if trans.Request().IsSuccess() {
-					deleter.request.Log().Info(
-						ss.NewLogMsg("deleted device").Add(key.FCMToken))
-				} else {
-					deleter.request.Log().Debug(
-						ss.NewLogMsg("no device record found").Add(key.FCMToken))
-				}
-
-			}(record.DeviceKeyValue)
+	it := ddb.
+		NewTableIndex[deviceRecord](deleter.db).
+		Query("user = :u", ddb.Values{":u": deleter.user}).
+		RequestPaged()
+
+	keys := []ddb.KeyRecord{}
+	for it.Next() {
+		token := it.Get().FCMToken
+		deleter.request.Log().Debug(ss.NewLogMsg("deleting device").Add(token))
+		keys = append(keys, db.NewDeviceKey(token))
+	}
+	if len(keys) == 0 {
+		deleter.request.Log().Debug(ss.NewLogMsg("no device records found"))
+		return
+	}
+
+	deleter.delete(keys, "devices")
+
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// delete deletes records by one batch, unprocessed records are retried by
+// the client retry policy, records which are not deleted after all attempts
+// are reported once.
+func (deleter *deleter) delete(keys []ddb.KeyRecord, name string) {
+	batch := deleter.db.BatchWrite()
+	for _, key := range keys {
+		batch.Delete(key)
+	}
+	result, err := batch.RequestE()
+	if result == nil {
+		// The batch is not requested at all.
+		deleter.request.Log().Panic(
+			ss.NewLogMsg("failed to delete %d %s", len(keys), name).AddErr(err))
+	}
+
+	if fails := result.GetFails(); len(fails) > 0 {
+		failed := make([]ddb.KeyRecord, 0, len(fails))
+		for _, fail := range fails {
+			failed = append(failed, keys[fail.Index])
 		}
-	}()
+		deleter.request.Log().Panic(
+			ss.
+				NewLogMsg(
+					"failed to delete %d of %d %s",
+					len(failed),
+					len(keys),
+					name).
+				AddErr(err).
+				AddDump(failed))
+	}
+
+	deleter.request.Log().Info(ss.NewLogMsg("deleted %d %s", len(keys), name))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
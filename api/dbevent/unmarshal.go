@@ -4,12 +4,10 @@
 package apidbevent
 
 import (
-	"encoding/json"
-
 	"github.com/aws/aws-lambda-go/events"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
 )
 
 // UnmarshalEventsDynamoDBAttributeValues unmarshals db-event.
@@ -17,29 +15,8 @@ func UnmarshalEventsDynamoDBAttributeValues(
 	source map[string]events.DynamoDBAttributeValue,
 	result interface{},
 ) {
-
-	attrs := make(map[string]*dynamodb.AttributeValue)
-	for k, v := range source {
-		var attr dynamodb.AttributeValue
-		bytes, err := v.MarshalJSON()
-		if err != nil {
-			ss.S.Log().Panic(
-				ss.NewLogMsg(`failed to convert from events-value to attribute`).
-					AddErr(err).
-					AddDump(source))
-		}
-		if err := json.Unmarshal(bytes, &attr); err != nil {
-			ss.S.Log().Panic(
-				ss.NewLogMsg(`failed to unmarshal events-value JSON into attribute`).
-					AddErr(err).
-					AddDump(source).
-					AddDump(string(bytes)).
-					AddDump(attrs))
-		}
-		attrs[k] = &attr
-	}
-
-	if err := dynamodbattribute.UnmarshalMap(attrs, result); err != nil {
+	attrs := convertEventsDynamoDBAttributeValues(source)
+	if err := ddb.UnmarshalMap(attrs, result); err != nil {
 		ss.S.Log().Panic(
 			ss.NewLogMsg(`failed to unmarshal events DynamoDB attribute values`).
 				AddErr(err).
@@ -47,3 +24,51 @@ func UnmarshalEventsDynamoDBAttributeValues(
 				AddDump(attrs))
 	}
 }
+
+func convertEventsDynamoDBAttributeValues(
+	source map[string]events.DynamoDBAttributeValue,
+) map[string]types.AttributeValue {
+	result := make(map[string]types.AttributeValue, len(source))
+	for k, v := range source {
+		result[k] = convertEventsDynamoDBAttributeValue(v)
+	}
+	return result
+}
+
+func convertEventsDynamoDBAttributeValue(
+	source events.DynamoDBAttributeValue,
+) types.AttributeValue {
+	switch source.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: source.String()}
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: source.Number()}
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: source.Binary()}
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: source.Boolean()}
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: source.IsNull()}
+	case events.DataTypeList:
+		list := source.List()
+		result := make([]types.AttributeValue, len(list))
+		for i, v := range list {
+			result[i] = convertEventsDynamoDBAttributeValue(v)
+		}
+		return &types.AttributeValueMemberL{Value: result}
+	case events.DataTypeMap:
+		return &types.AttributeValueMemberM{
+			Value: convertEventsDynamoDBAttributeValues(source.Map()),
+		}
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: source.StringSet()}
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: source.NumberSet()}
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: source.BinarySet()}
+	}
+	ss.S.Log().Panic(
+		ss.NewLogMsg(`unknown events DynamoDB attribute value type`).
+			AddDump(source.DataType()))
+	return nil
+}
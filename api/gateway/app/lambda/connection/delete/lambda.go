@@ -30,13 +30,14 @@ func (lambda lambda) Execute(request ws.Request) error {
 		db.
 		Delete(db.NewConnectionKey(request.GetConnectionID()))
 	trans.AllowConditionalCheckFail()
-	if !trans.Request().IsSuccess() {
+	var connection db.Connection
+	if !trans.RequestAndReturn(&connection).IsSuccess() {
 		request.Log().Warn(
 			ss.
 				NewLogMsg(`failed to find connection to delete`).
 				Add(request.GetConnectionID()))
 		return nil
 	}
-	request.Log().Debug(ss.NewLogMsg("disconnected"))
+	request.Log().Debug(ss.NewLogMsg("disconnected").AddDump(connection))
 	return nil
 }
@@ -12,8 +12,8 @@ type FirebaseIndex struct {
 	ssdb.UserExternalFirabaseIDIndex
 	ID                            ss.UserID `json:"id"`
 	Name                          string    `json:"name"`
-	Email                         string    `json:"email,omitempty"`
-	PhoneNumber                   string    `json:"phone,omitempty"`
+	Email                         string    `json:"email,omitempty" ddb:"encrypt"`
+	PhoneNumber                   string    `json:"phone,omitempty" ddb:"encrypt"`
 	PhotoURL                      string    `json:"photoUrl,omitempty"`
 	AnonymousRecordExpirationTime *ss.Time  `json:"anonymExpiration,omitempty"`
 }
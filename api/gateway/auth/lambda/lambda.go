@@ -117,6 +117,7 @@ type lambda struct {
 }
 
 func (lambda lambda) Execute(request rest.Request) error {
+	lambda.db = lambda.db.WithContext(request.GetContext())
 
 	if ok, err := lambda.CheckClientVersionActuality(request); err != nil {
 		return err
@@ -297,8 +298,8 @@ type updateRecord struct {
 	ssdb.UserRecord
 	OriginalName string `json:"origName"`
 	OwnName      string `json:"ownName,omitempty"`
-	Email        string `json:"email,omitempty"`
-	PhoneNumber  string `json:"phone,omitempty"`
+	Email        string `json:"email,omitempty" ddb:"encrypt"`
+	PhoneNumber  string `json:"phone,omitempty" ddb:"encrypt"`
 	PhotoURL     string `json:"photoUrl,omitempty"`
 }
 
@@ -317,7 +318,8 @@ func (lambda lambda) updateUser(
 	user *FirebaseIndex,
 	isAnonymous *bool,
 ) {
-	update := lambda.db.Update(ssdb.NewUserKey(user.ID))
+	key := ssdb.NewUserKey(user.ID)
+	update := lambda.db.Update(key)
 
 	if userRecord.DisplayName != "" {
 		update.Set("origName = :n").Value(":n", userRecord.DisplayName)
@@ -330,13 +332,17 @@ func (lambda lambda) updateUser(
 	}
 
 	if userRecord.Email != "" {
-		update.Set("email = :e").Value(":e", userRecord.Email)
+		update.
+			Set("email = :e").
+			Value(":e", ddb.Encrypt(key, "email", userRecord.Email))
 	} else {
 		update.Remove("email")
 	}
 
 	if userRecord.PhoneNumber != "" {
-		update.Set("phone = :p").Value(":p", userRecord.PhoneNumber)
+		update.
+			Set("phone = :p").
+			Value(":p", ddb.Encrypt(key, "phone", userRecord.PhoneNumber))
 	} else {
 		update.Remove("phone")
 	}
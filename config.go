@@ -36,7 +36,11 @@ type ServiceConfig struct {
 	PrivateKey   struct {
 		RSA RSAPrivateKey `json:"rsa"`
 	} `json:"privateKey"`
-	App struct {
+	DBEncryption DBEncryptionConfig `json:"dbEncryption"`
+	// DBCursorSecret is the secret encoded by Base64 to sign pagination cursors
+	// of database queries and scans, it's required only if cursors are used.
+	DBCursorSecret string `json:"dbCursorSecret"`
+	App            struct {
 		MinVersion [4]uint `json:"minVer"`
 		Domain     string  `json:"domain"`
 		Android    struct {
@@ -52,6 +56,18 @@ func (ServiceConfig) IsExtraLogEnabled() bool { return !S.Build().IsProd() }
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// DBEncryptionConfig is the keyring to encrypt database record attributes
+// with the tag ddb:"encrypt". Each encrypted value keeps the ID of its key,
+// so old keys have to be kept until all values are rewritten by a new key.
+type DBEncryptionConfig struct {
+	// CurrentKey is the ID of the key to encrypt new values.
+	CurrentKey string `json:"currentKey"`
+	// Keys has AES keys (256 bits) encoded by Base64 by key IDs.
+	Keys map[string]string `json:"keys"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 type AWSConfig struct {
 	AccountID string       `json:"accountId"`
 	Region    string       `json:"region"`
@@ -15,8 +15,6 @@ type ConnectionIDByUser struct {
 	ID ss.ConnectionID `json:"id"`
 }
 
-func (record *ConnectionIDByUser) Clear() { *record = ConnectionIDByUser{} }
-
 // IteratorMover describes intreface to move iterator.
 type FindUserConnectionsIterator interface {
 	Next() bool
@@ -27,23 +25,22 @@ func FindUserConnections(
 	user ss.UserID,
 	db ddb.Client,
 ) FindUserConnectionsIterator {
-	var result findUserConnectionsIterator
-	result.it = db.
-		Index(&result.buffer).
-		Query("user = :u", ddb.Values{":u": user}).
-		RequestPaged()
-	return &result
+	return findUserConnectionsIterator{
+		it: ddb.
+			NewTableIndex[ConnectionIDByUser](db).
+			Query("user = :u", ddb.Values{":u": user}).
+			RequestPaged(),
+	}
 }
 
 type findUserConnectionsIterator struct {
-	it     ddb.Iterator
-	buffer ConnectionIDByUser
+	it ddb.RecordIterator[ConnectionIDByUser]
 }
 
 func (it findUserConnectionsIterator) Next() bool { return it.it.Next() }
 
 func (it findUserConnectionsIterator) Get() ss.ConnectionID {
-	return it.buffer.ID
+	return it.it.Get().ID
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -87,4 +87,7 @@ func NewConnection(
 // GetData returns record's data.
 func (record Connection) GetData() interface{} { return record }
 
+// Clear resets the record to read the database response.
+func (record *Connection) Clear() { *record = Connection{} }
+
 ////////////////////////////////////////////////////////////////////////////////
@@ -48,7 +48,7 @@ type Device struct {
 	DeviceKeyValue
 	ID   ss.DeviceID     `json:"id"`
 	User ss.UserID       `json:"user"`
-	Key  DeviceCryptoKey `json:"key"`
+	Key  DeviceCryptoKey `json:"key" ddb:"encrypt"`
 }
 
 func NewDevice(
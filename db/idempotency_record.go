@@ -0,0 +1,94 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package db
+
+import (
+	"strings"
+
+	"github.com/palchukovsky/ss"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type IdempotencyRecord struct{}
+
+func (IdempotencyRecord) GetTable() string             { return "Idempotency" }
+func (IdempotencyRecord) GetKeyPartitionField() string { return "id" }
+func (IdempotencyRecord) GetKeySortField() string      { return "" }
+
+////////////////////////////////////////////////////////////////////////////////
+
+type IdempotencyKeyValue struct {
+	ID string `json:"id"`
+}
+
+func newIdempotencyKeyValue(
+	caller string,
+	command string,
+	key string,
+) IdempotencyKeyValue {
+	return IdempotencyKeyValue{
+		ID: strings.Join([]string{command, caller, key}, "/"),
+	}
+}
+
+type IdempotencyKey struct {
+	IdempotencyRecord
+	IdempotencyKeyValue
+}
+
+// NewIdempotencyKey creates key of the request by the caller, which sends
+// the request (the user ID or the hash of credentials), the command name and
+// the idempotency key from the client.
+func NewIdempotencyKey(caller, command, key string) IdempotencyKey {
+	return IdempotencyKey{
+		IdempotencyKeyValue: newIdempotencyKeyValue(caller, command, key),
+	}
+}
+
+func (key IdempotencyKey) GetKey() interface{} {
+	return key.IdempotencyKeyValue
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Idempotency describes the record of the request which is executed or is
+// being executed. The token is unique for each execution, so only the
+// execution which has set the record could complete or remove it. The request
+// is the hash of the request payload, so the key could not be reused by
+// another request. The response is set when the execution is completed.
+// The expiration is the table TTL attribute. The table is created by
+// the database installer if the project installer implements
+// dbinstall.IdempotencyInstaller.
+type Idempotency struct {
+	IdempotencyRecord
+	IdempotencyKeyValue
+	Token       string  `json:"token"`
+	Request     string  `json:"req"`
+	IsCompleted bool    `json:"done"`
+	Response    string  `json:"resp" ddb:"compress"`
+	Expiration  ss.Time `json:"expiration"`
+}
+
+func NewIdempotency(
+	key IdempotencyKey,
+	token string,
+	request string,
+	expiration ss.Time,
+) Idempotency {
+	return Idempotency{
+		IdempotencyKeyValue: key.IdempotencyKeyValue,
+		Token:               token,
+		Request:             request,
+		Expiration:          expiration,
+	}
+}
+
+func (record Idempotency) GetKey() interface{} {
+	return record.IdempotencyKeyValue
+}
+func (record Idempotency) GetData() interface{} { return record }
+func (record *Idempotency) Clear()              { *record = Idempotency{} }
+
+////////////////////////////////////////////////////////////////////////////////
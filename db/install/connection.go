@@ -20,7 +20,7 @@ func newConnectionTable(ddb ddbinstall.DB, log ss.Log) ddbinstall.Table {
 
 func (table connection) Create() error {
 	return table.TableAbstraction.Create(
-		[]ddb.IndexRecord{&db.ConnectionIDByUser{}})
+		[]ddb.IndexDescription{&db.ConnectionIDByUser{}})
 }
 
 func (table connection) Setup() error {
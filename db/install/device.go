@@ -20,7 +20,7 @@ func newDeviceTable(ddb ddbinstall.DB, log ss.Log) ddbinstall.Table {
 }
 
 func (table device) Create() error {
-	return table.TableAbstraction.Create([]ddb.IndexRecord{
+	return table.TableAbstraction.Create([]ddb.IndexDescription{
 		&push.DeviceUserIndex{},
 	})
 }
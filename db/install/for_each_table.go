@@ -24,6 +24,18 @@ func ForEachTable(
 		newConnectionTable(db, log),
 		newDeviceTable(db, log),
 		newUserTable(db, log, installer.HasUserUpdateLambda()))
+	if hasSequenceTable(installer) || hasLockTable(installer) {
+		tables = append(tables, newSequenceTable(db, log))
+	}
+	if hasLockTable(installer) {
+		tables = append(tables, newLockTable(db, log))
+	}
+	if hasOutboxTable(installer) {
+		tables = append(tables, newOutboxTable(db, log))
+	}
+	if hasIdempotencyTable(installer) {
+		tables = append(tables, newIdempotencyTable(db, log))
+	}
 
 	for _, table := range tables {
 		table.Log().Debug(ss.NewLogMsg("processing..."))
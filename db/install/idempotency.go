@@ -0,0 +1,37 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package dbinstall
+
+import (
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/db"
+	"github.com/palchukovsky/ss/ddb"
+	ddbinstall "github.com/palchukovsky/ss/ddb/install"
+)
+
+type idempotency struct{ ddbinstall.TableAbstraction }
+
+func newIdempotencyTable(ddb ddbinstall.DB, log ss.Log) ddbinstall.Table {
+	return idempotency{
+		TableAbstraction: ddbinstall.NewTableAbstraction(
+			ddb,
+			db.Idempotency{},
+			log),
+	}
+}
+
+func (table idempotency) Create() error {
+	return table.TableAbstraction.Create([]ddb.IndexDescription{})
+}
+
+func (table idempotency) Setup() error {
+	return table.EnableTimeToLive("expiration")
+}
+
+func (idempotency) InsertData() error { return nil }
+
+func hasIdempotencyTable(installer Installer) bool {
+	idempotency, isIdempotency := installer.(IdempotencyInstaller)
+	return isIdempotency && idempotency.HasIdempotencyTable()
+}
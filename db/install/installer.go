@@ -14,3 +14,32 @@ type Installer interface {
 
 	HasUserUpdateLambda() bool
 }
+
+// SequenceInstaller is implemented by the installer of the project which uses
+// ddb.Sequence, the sequence table is installed only if HasSequenceTable
+// returns true.
+type SequenceInstaller interface {
+	HasSequenceTable() bool
+}
+
+// LockInstaller is implemented by the installer of the project which uses
+// ddblock, the lock table and the sequence table for lock tokens are installed
+// only if HasLockTable returns true.
+type LockInstaller interface {
+	HasLockTable() bool
+}
+
+// IdempotencyInstaller is implemented by the installer of the project which
+// has idempotent gateway lambdas, the idempotency table is installed only if
+// HasIdempotencyTable returns true.
+type IdempotencyInstaller interface {
+	HasIdempotencyTable() bool
+}
+
+// OutboxInstaller is implemented by the installer of the project which uses
+// ddb.WriteTrans.Outbox, the outbox table with the stream is installed only if
+// HasOutboxLambda returns true, as messages are sent only by the outbox lambda
+// (see api/dbevent/lambda/outbox).
+type OutboxInstaller interface {
+	HasOutboxLambda() bool
+}
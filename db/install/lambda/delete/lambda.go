@@ -6,8 +6,7 @@ package deletetabaselambda
 import (
 	"errors"
 
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 	dbinstall "github.com/palchukovsky/ss/db/install"
 	ddbinstall "github.com/palchukovsky/ss/ddb/install"
@@ -29,11 +28,8 @@ func Run(installer dbinstall.Installer) {
 		db,
 		func(table ddbinstall.Table) error {
 			if err := table.Delete(); err != nil {
-				var awsErr awserr.Error
-				if !errors.As(err, &awsErr) {
-					return err
-				}
-				if awsErr.Code() != dynamodb.ErrCodeResourceNotFoundException {
+				var notFoundErr *types.ResourceNotFoundException
+				if !errors.As(err, &notFoundErr) {
 					return err
 				}
 				table.Log().Info(ss.NewLogMsg("table doesn't exist").AddErr(err))
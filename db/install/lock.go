@@ -0,0 +1,34 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package dbinstall
+
+import (
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbinstall "github.com/palchukovsky/ss/ddb/install"
+	ddblock "github.com/palchukovsky/ss/ddb/lock"
+)
+
+type lock struct{ ddbinstall.TableAbstraction }
+
+func newLockTable(db ddbinstall.DB, log ss.Log) ddbinstall.Table {
+	return lock{
+		TableAbstraction: ddbinstall.NewTableAbstraction(
+			db,
+			ddblock.Record{},
+			log),
+	}
+}
+
+func (table lock) Create() error {
+	return table.TableAbstraction.Create([]ddb.IndexDescription{})
+}
+
+func (table lock) Setup() error { return table.EnableTimeToLive("expiration") }
+func (lock) InsertData() error  { return nil }
+
+func hasLockTable(installer Installer) bool {
+	lock, isLock := installer.(LockInstaller)
+	return isLock && lock.HasLockTable()
+}
@@ -0,0 +1,44 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package dbinstall
+
+import (
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbinstall "github.com/palchukovsky/ss/ddb/install"
+)
+
+type outbox struct{ ddbinstall.TableAbstraction }
+
+func newOutboxTable(db ddbinstall.DB, log ss.Log) ddbinstall.Table {
+	return outbox{
+		TableAbstraction: ddbinstall.NewTableAbstraction(
+			db,
+			ddb.OutboxRecord{},
+			log),
+	}
+}
+
+func (table outbox) Create() error {
+	return table.TableAbstraction.Create([]ddb.IndexDescription{})
+}
+
+func (table outbox) Setup() error {
+	if err := table.EnableTimeToLive("expiration"); err != nil {
+		return err
+	}
+	return table.EnableStreams(
+		ddbinstall.NewStreams(
+			ddbinstall.StreamViewTypeNew,
+			ddbinstall.NewStream("Outbox"),
+		),
+	)
+}
+
+func (outbox) InsertData() error { return nil }
+
+func hasOutboxTable(installer Installer) bool {
+	outbox, isOutbox := installer.(OutboxInstaller)
+	return isOutbox && outbox.HasOutboxLambda()
+}
@@ -0,0 +1,33 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package dbinstall
+
+import (
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbinstall "github.com/palchukovsky/ss/ddb/install"
+)
+
+type sequence struct{ ddbinstall.TableAbstraction }
+
+func newSequenceTable(db ddbinstall.DB, log ss.Log) ddbinstall.Table {
+	return sequence{
+		TableAbstraction: ddbinstall.NewTableAbstraction(
+			db,
+			ddb.SequenceRecord{},
+			log),
+	}
+}
+
+func (table sequence) Create() error {
+	return table.TableAbstraction.Create([]ddb.IndexDescription{})
+}
+
+func (table sequence) Setup() error { return nil }
+func (sequence) InsertData() error  { return nil }
+
+func hasSequenceTable(installer Installer) bool {
+	sequence, isSequence := installer.(SequenceInstaller)
+	return isSequence && sequence.HasSequenceTable()
+}
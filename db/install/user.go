@@ -29,7 +29,7 @@ func newUserTable(
 
 func (table user) Create() error {
 	return table.TableAbstraction.Create(
-		[]ddb.IndexRecord{&lambda.FirebaseIndex{}})
+		[]ddb.IndexDescription{&lambda.FirebaseIndex{}})
 }
 
 func (table user) Setup() error {
@@ -62,8 +62,8 @@ type User struct {
 	OriginalName string `json:"origName"`
 	// OwnName is the name that user set by the app.
 	OwnName                       string   `json:"ownName,omitempty"`
-	Email                         string   `json:"email,omitempty"`
-	PhoneNumber                   string   `json:"phone,omitempty"`
+	Email                         string   `json:"email,omitempty" ddb:"encrypt"`
+	PhoneNumber                   string   `json:"phone,omitempty" ddb:"encrypt"`
 	PhotoURL                      string   `json:"photoUrl,omitempty"`
 	AnonymousRecordExpirationTime *ss.Time `json:"anonymExpiration,omitempty"`
 }
@@ -6,12 +6,12 @@ package ddb
 import (
 	"regexp"
 
-	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func aliasReservedInString(source string, aliases *map[string]*string) *string {
+func aliasReservedInString(source string, aliases *map[string]string) *string {
 	matches := getAliasReserverdInStringRegexp().FindAllStringIndex(source, -1)
 	if len(matches) == 0 {
 		return aws.String(source)
@@ -42,9 +42,9 @@ func aliasReservedInString(source string, aliases *map[string]*string) *string {
 		r := ranges[i]
 		w := source[r[0]:r[1]]
 		if *aliases == nil {
-			*aliases = make(map[string]*string)
+			*aliases = make(map[string]string)
 		}
-		(*aliases)["#"+w] = aws.String(w)
+		(*aliases)["#"+w] = w
 		source = source[:r[0]] + "#" + source[r[0]:]
 	}
 	return aws.String(source)
@@ -52,16 +52,16 @@ func aliasReservedInString(source string, aliases *map[string]*string) *string {
 
 func aliasReservedWord(
 	source string,
-	aliases *map[string]*string,
+	aliases *map[string]string,
 ) string {
 	if !isReservedWord(source) {
 		return source
 	}
 	if *aliases == nil {
-		*aliases = make(map[string]*string)
+		*aliases = make(map[string]string)
 	}
 	result := "#" + source
-	(*aliases)[result] = aws.String(source)
+	(*aliases)[result] = source
 	return result
 }
 
@@ -6,7 +6,6 @@ package ddb_test
 import (
 	"testing"
 
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/golang/mock/gomock"
 	"github.com/palchukovsky/ss"
 	"github.com/palchukovsky/ss/ddb"
@@ -52,13 +51,13 @@ func Test_DDB_Alias_AliasReservedInString(test *testing.T) {
 		*input.TransactItems[0].Update.UpdateExpression)
 	assert.NotNil(input.TransactItems[0].Update.ExpressionAttributeNames)
 	assert.Equal(
-		map[string]*string{
-			"#l":        aws.String("l"),
-			"#user":     aws.String("user"),
-			"#next":     aws.String("next"),
-			"#snapshot": aws.String("snapshot"),
-			"#owner":    aws.String("owner"),
-			"#share":    aws.String("share"),
+		map[string]string{
+			"#l":        "l",
+			"#user":     "user",
+			"#next":     "next",
+			"#snapshot": "snapshot",
+			"#owner":    "owner",
+			"#share":    "share",
 		},
 		input.TransactItems[0].Update.ExpressionAttributeNames)
 }
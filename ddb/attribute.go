@@ -0,0 +1,203 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Records are described by JSON tags, as the same structures are used for API
+// and for the database, so attribute values are serialized by JSON tags too.
+const attributeTagKey = "json"
+
+// optionsTagKey is the tag key with database options of record fields,
+// like ddb:"encrypt,compress".
+const optionsTagKey = "ddb"
+
+// MarshalMap serializes the record, key or values into DynamoDB item by JSON
+// tags.
+func MarshalMap(source interface{}) (map[string]types.AttributeValue, error) {
+	result, err := marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	if result, isMap := result.(*types.AttributeValueMemberM); isMap {
+		return result.Value, nil
+	}
+	return map[string]types.AttributeValue{}, nil
+}
+
+// UnmarshalMap deserializes DynamoDB item into the record by JSON tags.
+func UnmarshalMap(
+	source map[string]types.AttributeValue,
+	result interface{},
+) error {
+	return unmarshal(&types.AttributeValueMemberM{Value: source}, result)
+}
+
+func marshal(source interface{}) (types.AttributeValue, error) {
+	result, err := attributevalue.
+		NewEncoder(func(options *attributevalue.EncoderOptions) {
+			options.TagKey = attributeTagKey
+		}).
+		Encode(source)
+	if err != nil {
+		return nil, err
+	}
+	result = nullEmptyStrings(result)
+	if item, isMap := result.(*types.AttributeValueMemberM); isMap {
+		err := compressAttributes(reflect.TypeOf(source), item.Value)
+		if err != nil {
+			return nil, err
+		}
+		if err := encryptAttributes(source, item.Value); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func unmarshal(source types.AttributeValue, result interface{}) error {
+	if item, isMap := source.(*types.AttributeValueMemberM); isMap {
+		decrypted, err := decryptAttributes(result, item.Value)
+		if err != nil {
+			return err
+		}
+		decompressed, err := decompressAttributes(
+			reflect.TypeOf(result),
+			decrypted)
+		if err != nil {
+			return err
+		}
+		source = &types.AttributeValueMemberM{Value: decompressed}
+	}
+	return attributevalue.
+		NewDecoder(func(options *attributevalue.DecoderOptions) {
+			options.TagKey = attributeTagKey
+		}).
+		Decode(source, result)
+}
+
+// nullEmptyStrings replaces empty strings by NULL, including values of maps
+// and lists, as it did NullEmptyString option of the SDK v1 encoder, so items
+// are written in the same way as they were written before. NULL is read into
+// the empty string.
+func nullEmptyStrings(source types.AttributeValue) types.AttributeValue {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
+		if value.Value == "" {
+			return &types.AttributeValueMemberNULL{Value: true}
+		}
+	case *types.AttributeValueMemberM:
+		for k, v := range value.Value {
+			value.Value[k] = nullEmptyStrings(v)
+		}
+	case *types.AttributeValueMemberL:
+		for i, v := range value.Value {
+			value.Value[i] = nullEmptyStrings(v)
+		}
+	}
+	return source
+}
+
+// cloneItem returns item copy, attribute values are not copied.
+func cloneItem(
+	source map[string]types.AttributeValue,
+) map[string]types.AttributeValue {
+	result := make(map[string]types.AttributeValue, len(source))
+	for k, v := range source {
+		result[k] = v
+	}
+	return result
+}
+
+// formatKeyAttribute returns key attribute value as a string, key attribute
+// could be only string, number or binary.
+func formatKeyAttribute(source types.AttributeValue) string {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
+		return "S:" + value.Value
+	case *types.AttributeValueMemberN:
+		return "N:" + value.Value
+	case *types.AttributeValueMemberB:
+		return "B:" + base64.StdEncoding.EncodeToString(value.Value)
+	}
+	return fmt.Sprintf("%T", source)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type taggedFieldsKey struct {
+	source reflect.Type
+	option string
+}
+
+var taggedFields sync.Map
+
+// getTaggedFields returns attribute names of the record fields with the option
+// in the database options tag, including fields of embedded structures.
+func getTaggedFields(source reflect.Type, option string) []string {
+	if source == nil {
+		return nil
+	}
+	for source.Kind() == reflect.Ptr {
+		source = source.Elem()
+	}
+	if source.Kind() != reflect.Struct {
+		return nil
+	}
+	key := taggedFieldsKey{source: source, option: option}
+	if result, has := taggedFields.Load(key); has {
+		return result.([]string)
+	}
+	result := []string{}
+	collectTaggedFields(source, option, &result)
+	taggedFields.Store(key, result)
+	return result
+}
+
+func collectTaggedFields(source reflect.Type, option string, result *[]string) {
+	for i := 0; i < source.NumField(); i++ {
+		field := source.Field(i)
+		name := strings.Split(field.Tag.Get(attributeTagKey), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			fieldType := field.Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				collectTaggedFields(fieldType, option, result)
+			}
+			continue
+		}
+		if !field.IsExported() || !hasTagOption(field, option) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		*result = append(*result, name)
+	}
+}
+
+func hasTagOption(field reflect.StructField, option string) bool {
+	for _, value := range strings.Split(field.Tag.Get(optionsTagKey), ",") {
+		if value == option {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,52 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DDB_Attribute_NullEmptyString(test *testing.T) {
+	assert := assert.New(test)
+
+	type record struct {
+		Empty   string            `json:"e"`
+		Value   string            `json:"v"`
+		List    []string          `json:"l"`
+		Map     map[string]string `json:"m"`
+		Omitted string            `json:"o,omitempty"`
+	}
+	source := record{
+		Value: "a",
+		List:  []string{"", "b"},
+		Map:   map[string]string{"k": ""},
+	}
+
+	item, err := ddb.MarshalMap(source)
+	assert.NoError(err)
+	null := &types.AttributeValueMemberNULL{Value: true}
+	assert.Equal(
+		map[string]types.AttributeValue{
+			"e": null,
+			"v": &types.AttributeValueMemberS{Value: "a"},
+			"l": &types.AttributeValueMemberL{
+				Value: []types.AttributeValue{
+					null,
+					&types.AttributeValueMemberS{Value: "b"},
+				},
+			},
+			"m": &types.AttributeValueMemberM{
+				Value: map[string]types.AttributeValue{"k": null},
+			},
+		},
+		item)
+
+	var result record
+	assert.NoError(ddb.UnmarshalMap(item, &result))
+	assert.Equal(source, result)
+}
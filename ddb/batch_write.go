@@ -0,0 +1,298 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+// BatchWrite describes the interface to put and delete many records in many
+// tables without conditions and without transaction. Each item could be
+// written or not independently from other items.
+type BatchWrite interface {
+	ss.NoCopy
+
+	Put(DataRecord) BatchWrite
+	Delete(KeyRecord) BatchWrite
+
+	IsEmpty() bool
+	GetSize() int
+
+	Request()
+	// RequestE writes all items and returns the result with items which were
+	// not written. The returned error is the error of the first not written
+	// item.
+	RequestE() (BatchWriteResult, error)
+}
+
+// BatchWriteResult describes the result of the batch write.
+type BatchWriteResult interface {
+	IsSuccess() bool
+	// GetFails returns not written items sorted by index.
+	GetFails() []BatchWriteFail
+}
+
+// BatchWriteFail describes the item which was not written.
+type BatchWriteFail struct {
+	// Index is the item index in the order of adding into the batch.
+	Index int
+	Err   error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// batchWriteItemLimit is the maximum number of items in one BatchWriteItem
+// call.
+const batchWriteItemLimit = 25
+
+func (client *client) BatchWrite() BatchWrite {
+	return &batchWrite{
+		client:    client,
+		keyFields: map[string][]string{},
+		ids:       map[string]struct{}{},
+	}
+}
+
+type batchWriteItem struct {
+	index   int
+	table   string
+	id      string
+	request types.WriteRequest
+}
+
+type batchWrite struct {
+	ss.NoCopyImpl
+
+	client    *client
+	items     []batchWriteItem
+	keyFields map[string][]string
+	ids       map[string]struct{}
+	err       error
+}
+
+func (batch *batchWrite) Put(record DataRecord) BatchWrite {
+	item, err := MarshalMap(record.GetData())
+	if err != nil {
+		batch.setErr(newSerializationError(err, "failed to serialize item"))
+		return batch
+	}
+	if err := checkItemSize(item); err != nil {
+		batch.setErr(err)
+		return batch
+	}
+	batch.add(record, item, types.WriteRequest{
+		PutRequest: &types.PutRequest{Item: item},
+	})
+	return batch
+}
+
+func (batch *batchWrite) Delete(record KeyRecord) BatchWrite {
+	key, err := MarshalMap(record.GetKey())
+	if err != nil {
+		batch.setErr(newSerializationError(err, "failed to serialize key"))
+		return batch
+	}
+	batch.add(record, key, types.WriteRequest{
+		DeleteRequest: &types.DeleteRequest{Key: key},
+	})
+	return batch
+}
+
+func (batch *batchWrite) add(
+	record Record,
+	item map[string]types.AttributeValue,
+	request types.WriteRequest,
+) {
+	table := ss.S.NewBuildEntityName(record.GetTable())
+	keyFields := []string{record.GetKeyPartitionField()}
+	if sortField := record.GetKeySortField(); sortField != "" {
+		keyFields = append(keyFields, sortField)
+	}
+	batch.keyFields[table] = keyFields
+	id := getBatchWriteItemID(table, keyFields, item)
+	if _, has := batch.ids[id]; has {
+		// BatchWriteItem rejects the call if it has the same item twice.
+		batch.setErr(
+			Error{
+				kind: ErrValidation,
+				err:  fmt.Errorf("batch has item %s twice", id),
+			})
+		return
+	}
+	batch.ids[id] = struct{}{}
+	batch.items = append(
+		batch.items,
+		batchWriteItem{
+			index:   len(batch.items),
+			table:   table,
+			id:      id,
+			request: request,
+		})
+}
+
+func (batch *batchWrite) setErr(err error) {
+	if batch.err == nil {
+		batch.err = err
+	}
+}
+
+func (batch *batchWrite) IsEmpty() bool { return len(batch.items) == 0 }
+func (batch *batchWrite) GetSize() int  { return len(batch.items) }
+
+func (batch *batchWrite) Request() {
+	if _, err := batch.RequestE(); err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg(`failed to execute batch write item request`).
+				AddErr(err).
+				AddDump(batch.items))
+	}
+}
+
+func (batch *batchWrite) RequestE() (BatchWriteResult, error) {
+	if batch.err != nil {
+		return nil, batch.err
+	}
+
+	ctx, cancel := batch.client.newRequestContext()
+	defer cancel()
+
+	chunks := make([][]batchWriteItem, 0,
+		(len(batch.items)+batchWriteItemLimit-1)/batchWriteItemLimit)
+	for begin := 0; begin < len(batch.items); begin += batchWriteItemLimit {
+		end := begin + batchWriteItemLimit
+		if end > len(batch.items) {
+			end = len(batch.items)
+		}
+		chunks = append(chunks, batch.items[begin:end])
+	}
+
+	chunkFails := make([][]BatchWriteFail, len(chunks))
+	runInPool(len(chunks), func(i int) {
+		chunkFails[i] = batch.requestChunk(ctx, chunks[i])
+	})
+
+	result := batchWriteResult{}
+	for _, fails := range chunkFails {
+		result.fails = append(result.fails, fails...)
+	}
+	sort.Slice(result.fails, func(i, j int) bool {
+		return result.fails[i].Index < result.fails[j].Index
+	})
+	if !result.IsSuccess() {
+		return result, result.fails[0].Err
+	}
+	return result, nil
+}
+
+// requestChunk writes one chunk and retries unprocessed items by the retry
+// policy until all items are processed. Returns items which were not written.
+func (batch *batchWrite) requestChunk(
+	ctx context.Context,
+	chunk []batchWriteItem,
+) []BatchWriteFail {
+	pending := make(map[string]batchWriteItem, len(chunk))
+	input := dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]types.WriteRequest{},
+	}
+	for _, item := range chunk {
+		pending[item.id] = item
+		input.RequestItems[item.table] = append(
+			input.RequestItems[item.table],
+			item.request)
+	}
+
+	newFails := func(err error) []BatchWriteFail {
+		result := make([]BatchWriteFail, 0, len(pending))
+		for _, item := range pending {
+			result = append(result, BatchWriteFail{Index: item.index, Err: err})
+		}
+		return result
+	}
+
+	for attempt := 0; ; attempt++ {
+		var response *dynamodb.BatchWriteItemOutput
+		err := batch.client.retry(ctx, func() (err error) {
+			response, err = batch.client.db.BatchWriteItem(ctx, &input)
+			return err
+		})
+		if err != nil {
+			return newFails(newError(err))
+		}
+		if len(response.UnprocessedItems) == 0 {
+			return nil
+		}
+
+		unprocessed := make(map[string]batchWriteItem, len(pending))
+		for table, requests := range response.UnprocessedItems {
+			for _, request := range requests {
+				var attributes map[string]types.AttributeValue
+				if request.PutRequest != nil {
+					attributes = request.PutRequest.Item
+				} else if request.DeleteRequest != nil {
+					attributes = request.DeleteRequest.Key
+				}
+				id := getBatchWriteItemID(
+					table,
+					batch.keyFields[table],
+					attributes)
+				if item, has := pending[id]; has {
+					unprocessed[id] = item
+				}
+			}
+		}
+		pending = unprocessed
+
+		if !batch.client.retryPolicy.hasAttempt(attempt) {
+			return newFails(
+				Error{
+					kind: ErrThrottled,
+					err: fmt.Errorf(
+						"batch write item request still has unprocessed items after %d retries",
+						attempt),
+				})
+		}
+		if err := batch.client.retryPolicy.wait(ctx, attempt); err != nil {
+			return newFails(newError(err))
+		}
+		input.RequestItems = response.UnprocessedItems
+	}
+}
+
+// getBatchWriteItemID returns unique item ID in the batch by table
+// and key attributes.
+func getBatchWriteItemID(
+	table string,
+	keyFields []string,
+	attributes map[string]types.AttributeValue,
+) string {
+	result := make([]string, 0, len(keyFields)+1)
+	result = append(result, table)
+	for _, field := range keyFields {
+		var value string
+		if attribute, has := attributes[field]; has {
+			value = formatKeyAttribute(attribute)
+		}
+		result = append(result, fmt.Sprintf("%s=%s", field, value))
+	}
+	return strings.Join(result, ",")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type batchWriteResult struct{ fails []BatchWriteFail }
+
+func (result batchWriteResult) IsSuccess() bool { return len(result.fails) == 0 }
+func (result batchWriteResult) GetFails() []BatchWriteFail {
+	return result.fails
+}
+
+////////////////////////////////////////////////////////////////////////////////
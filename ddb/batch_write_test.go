@@ -0,0 +1,47 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_BatchWrite_Chunks(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	db.SetBatchWriteItemLimit(10)
+
+	batch := client.BatchWrite()
+	for i := 0; i < 60; i++ {
+		batch.Put(newTestData(strconv.Itoa(i), "a", i))
+	}
+	assert.Equal(60, batch.GetSize())
+	result, err := batch.RequestE()
+	assert.NoError(err)
+	assert.True(result.IsSuccess())
+	assert.Equal(60, db.GetSize(testRecord{}))
+
+	batch = client.BatchWrite()
+	for i := 0; i < 30; i++ {
+		batch.Delete(newTestKey(strconv.Itoa(i)))
+	}
+	batch.Request()
+	assert.Equal(30, db.GetSize(testRecord{}))
+
+	batch = client.BatchWrite()
+	batch.Put(newTestData("1", "a", 1))
+	batch.Delete(newTestKey("1"))
+	_, err = batch.RequestE()
+	assert.ErrorIs(err, ddb.ErrValidation)
+	assert.Equal(30, db.GetSize(testRecord{}))
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,459 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+// CacheConfig describes read cache, see NewCache.
+type CacheConfig struct {
+	// ImmutableTables has the time to keep records between lambda invocations
+	// for tables which records are never changed after creation, by table
+	// names as they are returned by the record GetTable.
+	ImmutableTables map[string]time.Duration
+}
+
+// Cache is a read-through cache of Get, Find and Query results, which is
+// used by the client created by Client.WithCache. Cached records live until
+// the lambda invocation is completed, records of immutable tables live until
+// the time from the config is expired. The record is removed from the cache
+// when it is written by the client with this cache, each write also removes
+// all cached queries of the table. Writes by other clients are not tracked,
+// so the cache has to be used only for records which are not changed
+// concurrently during the invocation.
+type Cache struct {
+	ss.NoCopyImpl
+
+	mutex           sync.Mutex
+	tables          map[string]*cacheTable
+	immutableTables map[string]time.Duration
+	removeHandler   func()
+}
+
+// NewCache creates new cache, which is reset each time when the lambda starts
+// and completes until it's closed.
+func NewCache(config CacheConfig) *Cache {
+	result := Cache{
+		tables: map[string]*cacheTable{},
+		immutableTables: make(
+			map[string]time.Duration,
+			len(config.ImmutableTables)),
+	}
+	for table, ttl := range config.ImmutableTables {
+		result.immutableTables[ss.S.NewBuildEntityName(table)] = ttl
+	}
+	result.removeHandler = ss.S.AddLambdaScopeHandler(result.Reset)
+	return &result
+}
+
+// Close stops resetting the cache by the lambda scope and removes all records.
+// The cache which is not needed anymore has to be closed, otherwise it's kept
+// by the service.
+func (cache *Cache) Close() {
+	cache.removeHandler()
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	cache.tables = map[string]*cacheTable{}
+}
+
+// Reset removes all records which have to live only while the lambda
+// invocation, and all expired records of immutable tables.
+func (cache *Cache) Reset() {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	now := time.Now()
+	tables := make(map[string]*cacheTable, len(cache.immutableTables))
+	for name, table := range cache.tables {
+		if _, isImmutable := cache.immutableTables[name]; isImmutable {
+			table.removeExpired(now)
+			tables[name] = table
+		}
+	}
+	cache.tables = tables
+}
+
+func (cache *Cache) get(
+	table *string,
+	itemKey *string,
+	key string,
+) (interface{}, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	tableCache, has := cache.tables[aws.ToString(table)]
+	if !has {
+		return nil, false
+	}
+	var entry cacheEntry
+	if itemKey == nil {
+		entry, has = tableCache.queries[key]
+	} else {
+		entry, has = tableCache.items[*itemKey][key]
+	}
+	if !has || entry.isExpired(time.Now()) {
+		return nil, false
+	}
+	return entry.output, true
+}
+
+func (cache *Cache) set(
+	table *string,
+	itemKey *string,
+	key string,
+	output interface{},
+) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	tableCache := cache.getTable(aws.ToString(table))
+	entry := cacheEntry{output: output}
+	if ttl, has := cache.immutableTables[aws.ToString(table)]; has {
+		entry.expiration = time.Now().Add(ttl)
+	}
+	if itemKey == nil {
+		tableCache.queries[key] = entry
+		return
+	}
+	if tableCache.items[*itemKey] == nil {
+		tableCache.items[*itemKey] = map[string]cacheEntry{}
+	}
+	tableCache.items[*itemKey][key] = entry
+}
+
+func (cache *Cache) getTable(name string) *cacheTable {
+	result, has := cache.tables[name]
+	if !has {
+		result = &cacheTable{
+			items:   map[string]map[string]cacheEntry{},
+			queries: map[string]cacheEntry{},
+		}
+		cache.tables[name] = result
+	}
+	return result
+}
+
+// setKeyAttributes remembers names of the table key attributes, so the item
+// could be found in the cache by the written item.
+func (cache *Cache) setKeyAttributes(
+	table *string,
+	key map[string]types.AttributeValue,
+) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	tableCache := cache.getTable(aws.ToString(table))
+	if tableCache.keyAttributes != nil {
+		return
+	}
+	tableCache.keyAttributes = make([]string, 0, len(key))
+	for name := range key {
+		tableCache.keyAttributes = append(tableCache.keyAttributes, name)
+	}
+}
+
+// invalidateKey removes all cached records with the key and all cached
+// queries of the table.
+func (cache *Cache) invalidateKey(
+	table *string,
+	key map[string]types.AttributeValue,
+) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	tableCache, has := cache.tables[aws.ToString(table)]
+	if !has {
+		return
+	}
+	tableCache.items[newCacheItemKey(key)] = nil
+	tableCache.queries = map[string]cacheEntry{}
+}
+
+// invalidateItem removes the written item from the cache, the item key
+// is taken by the known key attributes of the table.
+func (cache *Cache) invalidateItem(
+	table *string,
+	item map[string]types.AttributeValue,
+) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	tableCache, has := cache.tables[aws.ToString(table)]
+	if !has {
+		return
+	}
+	if tableCache.keyAttributes != nil {
+		key := make(
+			map[string]types.AttributeValue,
+			len(tableCache.keyAttributes))
+		for _, name := range tableCache.keyAttributes {
+			key[name] = item[name]
+		}
+		tableCache.items[newCacheItemKey(key)] = nil
+	}
+	tableCache.queries = map[string]cacheEntry{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cacheTable has cached records by record key and by request key, as
+// the record could be cached by different projections, and cached queries
+// by request key. Removed entries are replaced by new maps, as the builtin
+// delete is hidden by the delete request type.
+type cacheTable struct {
+	keyAttributes []string
+	items         map[string]map[string]cacheEntry
+	queries       map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	output     interface{}
+	expiration time.Time
+}
+
+func (entry cacheEntry) isExpired(now time.Time) bool {
+	return !entry.expiration.IsZero() && now.After(entry.expiration)
+}
+
+func (table *cacheTable) removeExpired(now time.Time) {
+	items := make(map[string]map[string]cacheEntry, len(table.items))
+	for itemKey, entries := range table.items {
+		for key, entry := range entries {
+			if entry.isExpired(now) {
+				continue
+			}
+			if items[itemKey] == nil {
+				items[itemKey] = map[string]cacheEntry{}
+			}
+			items[itemKey][key] = entry
+		}
+	}
+	table.items = items
+
+	queries := make(map[string]cacheEntry, len(table.queries))
+	for key, entry := range table.queries {
+		if !entry.isExpired(now) {
+			queries[key] = entry
+		}
+	}
+	table.queries = queries
+}
+
+// newCacheItemKey returns record key as a string, which doesn't depend on
+// attributes order.
+func newCacheItemKey(key map[string]types.AttributeValue) string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var result strings.Builder
+	for _, name := range names {
+		result.WriteString(name)
+		result.WriteByte('=')
+		result.WriteString(formatKeyAttribute(key[name]))
+		result.WriteByte(';')
+	}
+	return result.String()
+}
+
+// newCacheRequestKey returns request parameters as a string, attribute values
+// are serialized with its types.
+func newCacheRequestKey(request interface{}) (string, error) {
+	result, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+func newCacheAttributesJSON(
+	source map[string]types.AttributeValue,
+) map[string]attributeJSON {
+	if source == nil {
+		return nil
+	}
+	result := make(map[string]attributeJSON, len(source))
+	for name, value := range source {
+		result[name] = newAttributeJSON(value)
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cachedAPI returns cached results of GetItem and Query, and invalidates
+// the cache by each write.
+type cachedAPI struct {
+	API
+	cache *Cache
+}
+
+func newCachedAPI(api API, cache *Cache) API {
+	return cachedAPI{API: api, cache: cache}
+}
+
+func (api cachedAPI) GetItem(
+	ctx context.Context,
+	input *dynamodb.GetItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.GetItemOutput, error) {
+	itemKey := newCacheItemKey(input.Key)
+	key, err := newCacheRequestKey(struct {
+		Key                      string
+		ConsistentRead           *bool
+		ProjectionExpression     *string
+		ExpressionAttributeNames map[string]string
+	}{
+		Key:                      itemKey,
+		ConsistentRead:           input.ConsistentRead,
+		ProjectionExpression:     input.ProjectionExpression,
+		ExpressionAttributeNames: input.ExpressionAttributeNames,
+	})
+	if err != nil {
+		return api.API.GetItem(ctx, input, options...)
+	}
+	if output, has := api.cache.get(input.TableName, &itemKey, key); has {
+		result := *output.(*dynamodb.GetItemOutput)
+		return &result, nil
+	}
+
+	output, err := api.API.GetItem(ctx, input, options...)
+	if err != nil {
+		return nil, err
+	}
+	api.cache.setKeyAttributes(input.TableName, input.Key)
+	result := *output
+	api.cache.set(input.TableName, &itemKey, key, &result)
+	return output, nil
+}
+
+func (api cachedAPI) Query(
+	ctx context.Context,
+	input *dynamodb.QueryInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.QueryOutput, error) {
+	key, err := newCacheRequestKey(struct {
+		IndexName                 *string
+		KeyConditionExpression    *string
+		FilterExpression          *string
+		ProjectionExpression      *string
+		ExpressionAttributeNames  map[string]string
+		ExpressionAttributeValues map[string]attributeJSON
+		ExclusiveStartKey         map[string]attributeJSON
+		ConsistentRead            *bool
+		Limit                     *int32
+		ScanIndexForward          *bool
+		Select                    types.Select
+	}{
+		IndexName:                input.IndexName,
+		KeyConditionExpression:   input.KeyConditionExpression,
+		FilterExpression:         input.FilterExpression,
+		ProjectionExpression:     input.ProjectionExpression,
+		ExpressionAttributeNames: input.ExpressionAttributeNames,
+		ExpressionAttributeValues: newCacheAttributesJSON(
+			input.ExpressionAttributeValues),
+		ExclusiveStartKey: newCacheAttributesJSON(input.ExclusiveStartKey),
+		ConsistentRead:    input.ConsistentRead,
+		Limit:             input.Limit,
+		ScanIndexForward:  input.ScanIndexForward,
+		Select:            input.Select,
+	})
+	if err != nil {
+		return api.API.Query(ctx, input, options...)
+	}
+	if output, has := api.cache.get(input.TableName, nil, key); has {
+		result := *output.(*dynamodb.QueryOutput)
+		return &result, nil
+	}
+
+	output, err := api.API.Query(ctx, input, options...)
+	if err != nil {
+		return nil, err
+	}
+	result := *output
+	api.cache.set(input.TableName, nil, key, &result)
+	return output, nil
+}
+
+func (api cachedAPI) PutItem(
+	ctx context.Context,
+	input *dynamodb.PutItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.PutItemOutput, error) {
+	defer api.cache.invalidateItem(input.TableName, input.Item)
+	return api.API.PutItem(ctx, input, options...)
+}
+
+func (api cachedAPI) UpdateItem(
+	ctx context.Context,
+	input *dynamodb.UpdateItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.UpdateItemOutput, error) {
+	defer api.cache.invalidateKey(input.TableName, input.Key)
+	return api.API.UpdateItem(ctx, input, options...)
+}
+
+func (api cachedAPI) DeleteItem(
+	ctx context.Context,
+	input *dynamodb.DeleteItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.DeleteItemOutput, error) {
+	defer api.cache.invalidateKey(input.TableName, input.Key)
+	return api.API.DeleteItem(ctx, input, options...)
+}
+
+func (api cachedAPI) BatchWriteItem(
+	ctx context.Context,
+	input *dynamodb.BatchWriteItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.BatchWriteItemOutput, error) {
+	defer func() {
+		for table, requests := range input.RequestItems {
+			for _, request := range requests {
+				switch {
+				case request.PutRequest != nil:
+					api.cache.invalidateItem(&table, request.PutRequest.Item)
+				case request.DeleteRequest != nil:
+					api.cache.invalidateKey(&table, request.DeleteRequest.Key)
+				}
+			}
+		}
+	}()
+	return api.API.BatchWriteItem(ctx, input, options...)
+}
+
+func (api cachedAPI) TransactWriteItems(
+	ctx context.Context,
+	input *dynamodb.TransactWriteItemsInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	defer func() {
+		for _, item := range input.TransactItems {
+			switch {
+			case item.Put != nil:
+				api.cache.invalidateItem(item.Put.TableName, item.Put.Item)
+			case item.Update != nil:
+				api.cache.invalidateKey(item.Update.TableName, item.Update.Key)
+			case item.Delete != nil:
+				api.cache.invalidateKey(item.Delete.TableName, item.Delete.Key)
+			}
+		}
+	}()
+	return api.API.TransactWriteItems(ctx, input, options...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,120 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	mock_ss "github.com/palchukovsky/ss/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Cache_ReadThrough(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	cache := ddb.NewCache(ddb.CacheConfig{})
+	cached := client.WithCache(cache)
+	find := func() int {
+		record := testBuffer{testKey: newTestKey("1")}
+		assert.True(cached.Find(&record).Request())
+		return record.Time
+	}
+	query := func() int {
+		var index testUserIndex
+		return client.
+			WithCache(cache).
+			Index(&index).
+			Query("user = :u", ddb.Values{":u": "a"}).
+			RequestAll().
+			GetSize()
+	}
+
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+	assert.Equal(10, find())
+	assert.Equal(1, query())
+
+	// Writes by another client are not seen until the cache is reset.
+	client.CreateOrReplace(newTestData("1", "a", 20)).Request()
+	client.CreateOrReplace(newTestData("2", "a", 20)).Request()
+	assert.Equal(10, find())
+	assert.Equal(1, query())
+	cache.Reset()
+	assert.Equal(20, find())
+	assert.Equal(2, query())
+
+	// Writes by the client with the cache invalidate the cache.
+	cached.
+		Update(newTestKey("1")).
+		Set("#t = :t").Value(":t", 30).Alias("#t", "time").
+		Request()
+	assert.Equal(30, find())
+	cached.CreateOrReplace(newTestData("3", "a", 20)).Request()
+	assert.Equal(3, query())
+	cached.CreateOrReplace(newTestData("1", "a", 40)).Request()
+	assert.Equal(40, find())
+
+	// Records of immutable tables live between lambda invocations.
+	immutableCache := ddb.NewCache(ddb.CacheConfig{
+		ImmutableTables: map[string]time.Duration{"Test": time.Hour},
+	})
+	immutable := client.WithCache(immutableCache)
+	record := testBuffer{testKey: newTestKey("2")}
+	assert.True(immutable.Find(&record).Request())
+	client.Delete(newTestKey("2")).Request()
+	immutableCache.Reset()
+	assert.True(immutable.Find(&record).Request())
+	assert.Equal(20, record.Time)
+}
+
+func Test_DDB_Cache_Close(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	var handler func()
+	isRemoved := false
+	{
+		mock := gomock.NewController(test)
+		test.Cleanup(mock.Finish)
+		service := mock_ss.NewMockService(mock)
+		service.EXPECT().
+			NewBuildEntityName(gomock.Any()).
+			AnyTimes().
+			DoAndReturn(func(name string) string { return "test_" + name })
+		service.EXPECT().
+			AddLambdaScopeHandler(gomock.Any()).
+			DoAndReturn(func(source func()) func() {
+				handler = source
+				return func() { isRemoved = true }
+			})
+		ss.Set(service)
+	}
+	cache := ddb.NewCache(ddb.CacheConfig{})
+	ddbtest.SetService(test, newTestServiceConfig())
+	assert.NotNil(handler)
+
+	cached := client.WithCache(cache)
+	find := func() int {
+		record := testBuffer{testKey: newTestKey("1")}
+		assert.True(cached.Find(&record).Request())
+		return record.Time
+	}
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+	assert.Equal(10, find())
+	client.CreateOrReplace(newTestData("1", "a", 20)).Request()
+	assert.Equal(10, find())
+
+	cache.Close()
+	assert.True(isRemoved)
+	assert.Equal(20, find())
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -6,9 +6,8 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -20,21 +19,23 @@ type CheckTrans interface {
 	Value(name string, value interface{}) CheckTrans
 
 	Condition(string) CheckTrans
+	ConditionExpr(Condition) CheckTrans
+	ReturnOnConditionalCheckFail() CheckTrans
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 func (trans *writeTrans) Check(key KeyRecord) CheckTrans {
-	input := &dynamodb.ConditionCheck{
+	input := &types.ConditionCheck{
 		TableName: aws.String(ss.S.NewBuildEntityName(key.GetTable())),
 	}
 	result := checkTrans{
 		writeTransExpression: newWriteTransExpression(
-			trans, dynamodb.TransactWriteItem{ConditionCheck: input}),
+			trans, types.TransactWriteItem{ConditionCheck: input}),
 		input: input,
 	}
 	var err error
-	result.input.Key, err = dynamodbattribute.MarshalMap(key.GetKey())
+	result.input.Key, err = MarshalMap(key.GetKey())
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -53,7 +54,7 @@ func (trans *writeTrans) Check(key KeyRecord) CheckTrans {
 
 type checkTrans struct {
 	writeTransExpression
-	input *dynamodb.ConditionCheck
+	input *types.ConditionCheck
 }
 
 func (trans *checkTrans) Values(values Values) CheckTrans {
@@ -72,4 +73,19 @@ func (trans *checkTrans) Condition(condition string) CheckTrans {
 	return trans
 }
 
+func (trans *checkTrans) ConditionExpr(condition Condition) CheckTrans {
+	trans.addCondition(
+		condition,
+		&trans.input.ConditionExpression,
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues)
+	return trans
+}
+
+func (trans *checkTrans) ReturnOnConditionalCheckFail() CheckTrans {
+	trans.returnOnConditionalCheckFail(
+		&trans.input.ReturnValuesOnConditionCheckFailure)
+	return trans
+}
+
 ////////////////////////////////////////////////////////////////////////////////
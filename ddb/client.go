@@ -4,8 +4,10 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/palchukovsky/ss"
 )
 
@@ -13,61 +15,210 @@ import (
 type Client interface {
 	ss.NoCopy
 
+	// WithContext returns client copy which executes all requests with the
+	// given context. Requests are also canceled when the lambda is about
+	// to reach its timeout.
+	WithContext(ctx context.Context) Client
+	// WithRetryPolicy returns client copy which repeats requests failed
+	// by throttling or by transaction conflict with the given policy.
+	WithRetryPolicy(RetryPolicy) Client
+	// WithCache returns client copy which reads records and queries through
+	// the cache, and removes written records from the cache.
+	WithCache(*Cache) Client
+
 	Index(resultRecord IndexRecord) Index
 
 	Get(KeyRecordBuffer) Get
 	Find(KeyRecordBuffer) Find
 	FindMany() FindMany
 	Query(record RecordBuffer, keyCondition string, values Values) Query
+	QueryExpr(record RecordBuffer, keyCondition Condition) Query
+	// QueryByPartition queries records by the partition key value, the key
+	// field is taken from the record.
+	QueryByPartition(record RecordBuffer, partition interface{}) Query
+	// QueryByPartitionAndSort queries records by the partition key value and
+	// by the sort key condition, key fields are taken from the record.
+	QueryByPartitionAndSort(
+		record RecordBuffer,
+		partition interface{},
+		sort SortCondition,
+	) Query
+	Scan(record RecordBuffer) Scan
 
 	CreateIfNotExists(data DataRecord) CreateIfNotExists
 	CreateOrReplace(data DataRecord) Create
 	Update(key KeyRecord) Update
 	Delete(key KeyRecord) Delete
 	DeleteIfExisting(key KeyRecord) Delete
+	BatchWrite() BatchWrite
 
 	Write(WriteTrans) TransResult
+	WriteE(WriteTrans) (TransResult, error)
+	// Read reads all records of the transaction atomically, and fills each
+	// found record.
+	Read(ReadTrans) ReadTransResult
+	ReadE(ReadTrans) (ReadTransResult, error)
 }
 
 // GetClientInstance returns reference to client singleton.
 func GetClientInstance() Client {
 	if clientInstance == nil {
-		clientInstance = &client{db: dynamodb.New(ss.S.NewAWSSessionV1())}
+		// The SDK retryer is disabled as the client repeats requests by its own
+		// retry policy.
+		clientInstance = NewClient(dynamodb.NewFromConfig(
+			ss.S.NewAWSConfig(),
+			func(options *dynamodb.Options) { options.Retryer = aws.NopRetryer{} }))
 	}
 	return clientInstance
 }
 
+// SetClientInstance sets client singleton without any initialization and
+// checks, so tests could replace the database by in-memory implementation.
+func SetClientInstance(instance Client) { clientInstance = instance }
+
+// NewClient creates new client instance which works through the given API.
+// Interceptors are called for each request in the given order, the first
+// interceptor is the outermost.
+func NewClient(db API, interceptors ...Interceptor) Client {
+	return newClient(
+		newInterceptedAPI(db, interceptors),
+		context.Background(),
+		NewDefaultRetryPolicy())
+}
+
+// API describes the subset of DynamoDB service interface used by the client.
+// It is implemented by the AWS SDK and by the in-memory database for tests.
+type API interface {
+	GetItem(
+		context.Context,
+		*dynamodb.GetItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.GetItemOutput, error)
+	BatchGetItem(
+		context.Context,
+		*dynamodb.BatchGetItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.BatchGetItemOutput, error)
+	Query(
+		context.Context,
+		*dynamodb.QueryInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.QueryOutput, error)
+	Scan(
+		context.Context,
+		*dynamodb.ScanInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.ScanOutput, error)
+	PutItem(
+		context.Context,
+		*dynamodb.PutItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.PutItemOutput, error)
+	UpdateItem(
+		context.Context,
+		*dynamodb.UpdateItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(
+		context.Context,
+		*dynamodb.DeleteItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.DeleteItemOutput, error)
+	BatchWriteItem(
+		context.Context,
+		*dynamodb.BatchWriteItemInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(
+		context.Context,
+		*dynamodb.TransactWriteItemsInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.TransactWriteItemsOutput, error)
+	TransactGetItems(
+		context.Context,
+		*dynamodb.TransactGetItemsInput,
+		...func(*dynamodb.Options),
+	) (*dynamodb.TransactGetItemsOutput, error)
+}
+
 // Index describes db-command interface for the table index.
 type Index interface {
 	Query(keyCondition string, values Values) Query
+	QueryExpr(keyCondition Condition) Query
+	// QueryByPartition queries records by the index partition key value.
+	QueryByPartition(partition interface{}) Query
+	// QueryByPartitionAndSort queries records by the index partition key value
+	// and by the index sort key condition.
+	QueryByPartitionAndSort(partition interface{}, sort SortCondition) Query
+	Scan() Scan
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 var clientInstance Client
 
+func newClient(db API, ctx context.Context, retryPolicy RetryPolicy) *client {
+	return &client{db: db, ctx: ctx, retryPolicy: retryPolicy}
+}
+
 type client struct {
 	ss.NoCopyImpl
 
-	db *dynamodb.DynamoDB
+	db          API
+	ctx         context.Context
+	retryPolicy RetryPolicy
 }
 
-func (client *client) DynamoDB() *dynamodb.DynamoDB { return client.db }
+func (client *client) WithContext(ctx context.Context) Client {
+	return newClient(client.db, ctx, client.retryPolicy)
+}
+
+func (client *client) WithRetryPolicy(retryPolicy RetryPolicy) Client {
+	return newClient(client.db, client.ctx, retryPolicy)
+}
+
+func (client *client) WithCache(cache *Cache) Client {
+	return newClient(
+		newCachedAPI(client.db, cache),
+		client.ctx,
+		client.retryPolicy)
+}
+
+// newRequestContext creates context for one request, the context is canceled
+// by the client context or by the lambda timeout. The returned cancel
+// function has to be called when the request is completed.
+func (client *client) newRequestContext() (context.Context, context.CancelFunc) {
+	lambda := getLambdaContext()
+	if lambda == nil {
+		return context.WithCancel(client.ctx)
+	}
+	if client.ctx == context.Background() {
+		return context.WithCancel(lambda)
+	}
+	result, cancel := context.WithCancel(client.ctx)
+	go func() {
+		select {
+		case <-lambda.Done():
+			cancel()
+		case <-result.Done():
+		}
+	}()
+	return result, cancel
+}
 
 func (client *client) Index(record IndexRecord) Index {
 	return &index{client: client, record: record}
 }
 
 func (client *client) Find(key KeyRecordBuffer) Find {
-	return newFind(client.db, key)
+	return newFind(client, key)
 }
 func (client *client) Get(key KeyRecordBuffer) Get {
-	return newGet(client.db, key)
+	return newGet(client, key)
 }
 
 func (client *client) Write(trans WriteTrans) TransResult {
-	request, _ := client.db.TransactWriteItemsRequest(trans.GetResult())
-	result, err := newTransResult(request.Send(), trans)
+	result, err := client.WriteE(trans)
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -78,6 +229,24 @@ func (client *client) Write(trans WriteTrans) TransResult {
 	return result
 }
 
+func (client *client) WriteE(trans WriteTrans) (TransResult, error) {
+	for _, item := range trans.GetResult().TransactItems {
+		if item.Put == nil {
+			continue
+		}
+		if err := checkItemSize(item.Put.Item); err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := client.newRequestContext()
+	defer cancel()
+	err := client.retry(ctx, func() error {
+		_, err := client.db.TransactWriteItems(ctx, trans.GetResult())
+		return err
+	})
+	return newTransResult(err, trans)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type index struct {
@@ -93,4 +262,42 @@ func (index *index) Query(keyCondition string, values Values) Query {
 	return result
 }
 
+func (index *index) QueryExpr(keyCondition Condition) Query {
+	result := newQueryExpr(index.client, index.record, keyCondition)
+	result.Input.IndexName = aws.String(index.record.GetIndex())
+	return result
+}
+
+func (index *index) QueryByPartition(partition interface{}) Query {
+	return index.queryByKey(partition, nil)
+}
+
+func (index *index) QueryByPartitionAndSort(
+	partition interface{},
+	sort SortCondition,
+) Query {
+	return index.queryByKey(partition, &sort)
+}
+
+func (index *index) queryByKey(
+	partition interface{},
+	sort *SortCondition,
+) Query {
+	result := newQueryByKey(
+		index.client,
+		index.record,
+		index.record.GetIndexPartitionField(),
+		partition,
+		index.record.GetIndexSortField(),
+		sort)
+	result.Input.IndexName = aws.String(index.record.GetIndex())
+	return result
+}
+
+func (index *index) Scan() Scan {
+	result := newScan(index.client, index.record)
+	result.Input.IndexName = aws.String(index.record.GetIndex())
+	return result
+}
+
 ////////////////////////////////////////////////////////////////////////////////
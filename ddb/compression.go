@@ -0,0 +1,125 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Record fields with the tag ddb:"compress" are compressed by gzip before
+// writing, if it makes the attribute smaller, and decompressed after reading.
+// The compressed attribute is binary which starts with the marker. If the field
+// is encrypted too, the value is compressed before encryption.
+const (
+	compressTagOption = "compress"
+
+	// maxDecompressedAttributeSize limits the decompressed attribute size
+	// to not allow the attribute to take all the memory.
+	maxDecompressedAttributeSize = 64 * 1024 * 1024
+)
+
+var compressedAttributeMarker = []byte("ddbgz\x00")
+
+// compressAttributes compresses attributes of the record fields with
+// the compression tag.
+func compressAttributes(
+	source reflect.Type,
+	item map[string]types.AttributeValue,
+) error {
+	for _, field := range getTaggedFields(source, compressTagOption) {
+		value, has := item[field]
+		if !has {
+			continue
+		}
+		compressed, err := compressAttribute(value)
+		if err != nil {
+			return fmt.Errorf("failed to compress attribute %q: %w", field, err)
+		}
+		if getAttributeSize(compressed) < getAttributeSize(value) {
+			item[field] = compressed
+		}
+	}
+	return nil
+}
+
+// decompressAttributes returns item copy with decompressed attributes of
+// the record fields with the compression tag.
+func decompressAttributes(
+	result reflect.Type,
+	item map[string]types.AttributeValue,
+) (map[string]types.AttributeValue, error) {
+	var decompressed map[string]types.AttributeValue
+	for _, field := range getTaggedFields(result, compressTagOption) {
+		value, isBinary := item[field].(*types.AttributeValueMemberB)
+		if !isBinary ||
+			!bytes.HasPrefix(value.Value, compressedAttributeMarker) {
+			// The attribute was not compressed as compression doesn't make it
+			// smaller.
+			continue
+		}
+		source, err := decompressAttribute(
+			value.Value[len(compressedAttributeMarker):])
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to decompress attribute %q: %w",
+				field,
+				err)
+		}
+		if decompressed == nil {
+			decompressed = cloneItem(item)
+		}
+		decompressed[field] = source
+	}
+	if decompressed == nil {
+		return item, nil
+	}
+	return decompressed, nil
+}
+
+func compressAttribute(
+	source types.AttributeValue,
+) (types.AttributeValue, error) {
+	data, err := json.Marshal(newAttributeJSON(source))
+	if err != nil {
+		return nil, err
+	}
+	result := bytes.NewBuffer(append([]byte{}, compressedAttributeMarker...))
+	writer := gzip.NewWriter(result)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return &types.AttributeValueMemberB{Value: result.Bytes()}, nil
+}
+
+func decompressAttribute(source []byte) (types.AttributeValue, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(
+		io.LimitReader(reader, maxDecompressedAttributeSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDecompressedAttributeSize {
+		return nil, fmt.Errorf(
+			"decompressed attribute is larger than %d bytes",
+			maxDecompressedAttributeSize)
+	}
+	var result attributeJSON
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result.export()
+}
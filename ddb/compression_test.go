@@ -0,0 +1,98 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DDB_Compression_RoundTrip(test *testing.T) {
+	assert := assert.New(test)
+
+	for _, source := range []types.AttributeValue{
+		&types.AttributeValueMemberS{Value: strings.Repeat("abc", 1024)},
+		&types.AttributeValueMemberN{Value: "123.45"},
+		&types.AttributeValueMemberB{Value: []byte{0, 1, 2}},
+		&types.AttributeValueMemberL{
+			Value: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "a"},
+				&types.AttributeValueMemberBOOL{Value: true},
+			},
+		},
+		&types.AttributeValueMemberM{
+			Value: map[string]types.AttributeValue{
+				"a": &types.AttributeValueMemberSS{Value: []string{"x", "y"}},
+				"b": &types.AttributeValueMemberNULL{Value: true},
+			},
+		},
+	} {
+		compressed, err := compressAttribute(source)
+		assert.NoError(err)
+		binary, isBinary := compressed.(*types.AttributeValueMemberB)
+		if !assert.True(isBinary) {
+			continue
+		}
+		assert.True(bytes.HasPrefix(binary.Value, compressedAttributeMarker))
+
+		item := map[string]types.AttributeValue{"c": binary}
+		type record struct {
+			C interface{} `json:"c" ddb:"compress"`
+		}
+		result, err := decompressAttributes(reflect.TypeOf(record{}), item)
+		assert.NoError(err)
+		assert.Equal(source, result["c"])
+		// The source item is not changed.
+		assert.Equal(binary, item["c"])
+	}
+
+	{
+		// The attribute stays as is, if compression doesn't make it smaller.
+		type record struct {
+			C string `json:"c" ddb:"compress"`
+		}
+		source := &types.AttributeValueMemberS{Value: "a"}
+		item := map[string]types.AttributeValue{"c": source}
+		assert.NoError(compressAttributes(reflect.TypeOf(record{}), item))
+		assert.Equal(source, item["c"])
+	}
+}
+
+func Test_DDB_Compression_SizeLimit(test *testing.T) {
+	assert := assert.New(test)
+
+	compress := func(size int) []byte {
+		data, err := json.Marshal(
+			newAttributeJSON(
+				&types.AttributeValueMemberS{Value: strings.Repeat("a", size)}))
+		assert.NoError(err)
+		// JSON adds the type and the quotes, so the data is trimmed.
+		data = data[:size]
+		result := &bytes.Buffer{}
+		writer := gzip.NewWriter(result)
+		_, err = writer.Write(data)
+		assert.NoError(err)
+		assert.NoError(writer.Close())
+		return result.Bytes()
+	}
+
+	_, err := decompressAttribute(compress(maxDecompressedAttributeSize + 1))
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "decompressed attribute is larger than")
+	}
+
+	// The size of the limit passes the size check, but it's not valid JSON as
+	// it's trimmed.
+	_, err = decompressAttribute(compress(maxDecompressedAttributeSize))
+	if assert.Error(err) {
+		assert.NotContains(err.Error(), "decompressed attribute is larger than")
+	}
+}
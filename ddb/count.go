@@ -0,0 +1,109 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// countReader reads pages of the query or scan without records.
+type countReader interface {
+	// readCount reads the next page, and returns the number of records which
+	// pass the filter, and true if the page is the last.
+	readCount(ctx context.Context) (int, bool, error)
+}
+
+// readCount reads pages of all readers until the last page, or until
+// the enough number of records is read, and returns the number of records.
+func readCount(
+	client *client,
+	readers []countReader,
+	isEnough func(count int) bool,
+) (int, error) {
+	result := 0
+	for len(readers) != 0 && !isEnough(result) {
+		counts := make([]int, len(readers))
+		isLast := make([]bool, len(readers))
+		errs := make([]error, len(readers))
+		ctx, cancel := client.newRequestContext()
+		read := func(i int) {
+			counts[i], isLast[i], errs[i] = readers[i].readCount(ctx)
+		}
+		if len(readers) == 1 {
+			read(0)
+		} else {
+			runInPool(len(readers), read)
+		}
+		cancel()
+
+		next := make([]countReader, 0, len(readers))
+		for i, reader := range readers {
+			if errs[i] != nil {
+				return 0, newError(errs[i])
+			}
+			result += counts[i]
+			if !isLast[i] {
+				next = append(next, reader)
+			}
+		}
+		readers = next
+	}
+	return result, nil
+}
+
+func isCountEnough(int) bool { return false }
+
+func isExistsEnough(count int) bool { return count > 0 }
+
+// setCountProjection replaces the request projection by the fields, or
+// removes the projection if fields are not set, and removes attribute names
+// which are not used anymore. The names map is copied, so the source request
+// is not changed.
+func setCountProjection(
+	projection **string,
+	names *map[string]string,
+	fields []string,
+	expressions ...*string,
+) {
+	used := map[string]struct{}{}
+	for _, expression := range expressions {
+		if expression == nil {
+			continue
+		}
+		for _, name := range attributeNameRegexp.FindAllString(
+			*expression,
+			-1,
+		) {
+			used[name] = struct{}{}
+		}
+	}
+	source := *names
+	*names = nil
+	for placeholder, name := range source {
+		if _, isUsed := used[placeholder]; isUsed {
+			if *names == nil {
+				*names = map[string]string{}
+			}
+			(*names)[placeholder] = name
+		}
+	}
+
+	if len(fields) == 0 {
+		*projection = nil
+		return
+	}
+	result := make([]string, len(fields))
+	for i, field := range fields {
+		result[i] = aliasReservedWord(field, names)
+	}
+	*projection = aws.String(strings.Join(result, ","))
+}
+
+var attributeNameRegexp = regexp.MustCompile(`#[A-Za-z0-9_]+`)
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,82 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Count_QueryAndScan(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	var record testBuffer
+	assert.Equal(0, client.Scan(&record).Count())
+	assert.False(client.Scan(&record).Exists())
+
+	for i := 0; i < 20; i++ {
+		user := "a"
+		if i%4 == 0 {
+			user = "b"
+		}
+		client.CreateOrReplace(newTestData(strconv.Itoa(i), user, i)).Request()
+	}
+
+	var index testUserIndex
+	assert.Equal(
+		15,
+		client.Index(&index).QueryByPartition("a").Limit(4).Count())
+	assert.Equal(
+		2,
+		client.
+			Index(&index).
+			QueryByPartition("b").
+			FilterExpr(ddb.Name("time").Greater(ddb.Value(10))).
+			Count())
+	assert.True(client.Index(&index).QueryByPartition("b").Exists())
+	assert.False(client.Index(&index).QueryByPartition("c").Exists())
+	assert.True(
+		client.
+			Index(&index).
+			QueryByPartition("a").
+			FilterExpr(ddb.Name("time").Equal(ddb.Value(19))).
+			Limit(2).
+			Exists())
+	assert.False(
+		client.
+			Index(&index).
+			QueryByPartition("a").
+			FilterExpr(ddb.Name("time").Equal(ddb.Value(20))).
+			Exists())
+
+	assert.Equal(20, client.Scan(&record).Limit(3).Count())
+	assert.Equal(20, client.Scan(&record).Parallel(3).Count())
+	assert.Equal(
+		5,
+		client.Scan(&record).Filter("user = :u").Value(":u", "b").Count())
+	assert.True(
+		client.
+			Scan(&record).
+			FilterExpr(ddb.Name("time").Equal(ddb.Value(19))).
+			Limit(2).
+			Parallel(2).
+			Exists())
+	assert.False(
+		client.
+			Scan(&record).
+			FilterExpr(ddb.Name("user").Equal(ddb.Value("c"))).
+			Exists())
+
+	table := ddb.NewTable[testData](client)
+	assert.Equal(1, table.QueryByPartition("3").Count())
+	assert.False(table.QueryByPartition("30").Exists())
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -6,9 +6,9 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -16,28 +16,50 @@ type Create interface {
 	CheckedExpression
 
 	Condition(string) Create
+	ConditionExpr(Condition) Create
 	Values(Values) Create
 
 	Request() Result
+	// RequestAndReturnOld writes the record and reads the replaced record
+	// into the buffer, the buffer is not changed if there was no record.
+	RequestAndReturnOld(RecordBuffer) Result
+	RequestE() (Result, error)
+	RequestAndReturnOldE(RecordBuffer) (Result, error)
 }
 
 type CreateIfNotExists interface {
 	CheckedExpression
 
 	Request() Result
+	RequestE() (Result, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 func (client *client) CreateIfNotExists(record DataRecord) CreateIfNotExists {
-	result := newCreateIfNotExists(record, client.db)
+	result := newCreateIfNotExists(record, client)
 	result.Condition(
 		fmt.Sprintf("attribute_not_exists(%s)", record.GetKeyPartitionField()))
+	if check := newVersionCheck(record); check != nil && result.err == nil {
+		result.err = check.setItemVersion(result.input.Item, 1)
+	}
 	return result
 }
 
 func (client *client) CreateOrReplace(record DataRecord) Create {
-	return newCreate(record, client.db)
+	result := newCreate(record, client)
+	if check := newVersionCheck(record); check != nil {
+		result.ConditionExpr(
+			check.getReplaceCondition(record.GetKeyPartitionField()))
+		if result.err == nil {
+			result.err = check.setItemVersion(
+				result.input.Item,
+				check.expected+1)
+		}
+		result.version = check
+		result.key = newItemKey(result.input.Item, record)
+	}
+	return result
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -46,27 +68,25 @@ type create struct {
 	ss.NoCopyImpl
 	checkedExpression
 
-	db    *dynamodb.DynamoDB
-	input dynamodb.PutItemInput
+	client  *client
+	input   dynamodb.PutItemInput
+	version *versionCheck
+	key     map[string]types.AttributeValue
+	err     error
 }
 
-func newCreate(record DataRecord, db *dynamodb.DynamoDB) *create {
+func newCreate(record DataRecord, client *client) *create {
 	result := create{
 		checkedExpression: newCheckedExpression(),
-		db:                db,
+		client:            client,
 		input: dynamodb.PutItemInput{
 			TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
 		},
 	}
 	var err error
-	result.input.Item, err = dynamodbattribute.MarshalMap(record.GetData())
+	result.input.Item, err = MarshalMap(record.GetData())
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.NewLogMsg(
-				`failed to serialize item to put into table %q`,
-				record.GetTable()).
-				AddDump(result.input).
-				AddErr(err))
+		result.err = newSerializationError(err, "failed to serialize item")
 	}
 	return &result
 }
@@ -87,8 +107,22 @@ func (trans *create) Condition(condition string) Create {
 	return trans
 }
 
+func (trans *create) ConditionExpr(condition Condition) Create {
+	err := newExpressionBuilder(
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues,
+	).addCondition(&trans.input.ConditionExpression, condition)
+	if err != nil && trans.err == nil {
+		trans.err = err
+	}
+	return trans
+}
+
 func (trans *create) values(values Values) {
-	values.Marshal(&trans.input.ExpressionAttributeValues)
+	err := values.marshal(&trans.input.ExpressionAttributeValues)
+	if err != nil && trans.err == nil {
+		trans.err = err
+	}
 }
 
 func (trans *create) Values(values Values) Create {
@@ -97,14 +131,88 @@ func (trans *create) Values(values Values) Create {
 }
 
 func (trans *create) Request() Result {
-	request, _ := trans.db.PutItemRequest(&trans.input)
-	result, err := newResult(request.Send(), trans.isConditionalCheckFailAllowed)
+	result, err := trans.RequestE()
+	if err != nil {
+		trans.panic(err)
+	}
+	return result
+}
+
+func (trans *create) RequestAndReturnOld(resultRecord RecordBuffer) Result {
+	result, err := trans.RequestAndReturnOldE(resultRecord)
+	if err != nil {
+		trans.panic(err)
+	}
+	return result
+}
+
+func (trans *create) RequestE() (Result, error) {
+	result, _, err := trans.request()
+	return result, err
+}
+
+func (trans *create) RequestAndReturnOldE(
+	resultRecord RecordBuffer,
+) (Result, error) {
+	trans.input.ReturnValues = types.ReturnValueAllOld
+	result, output, err := trans.request()
+	if err != nil || !result.IsSuccess() || len(output.Attributes) == 0 {
+		return result, err
+	}
+	err = UnmarshalMap(output.Attributes, resultRecord)
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(`failed to put item into table %q`, *trans.input.TableName).
-				AddDump(trans.input).
-				AddErr(err))
+		return false, newSerializationError(err, "failed to read put response")
+	}
+	return result, nil
+}
+
+func (trans *create) request() (Result, *dynamodb.PutItemOutput, error) {
+	if trans.err != nil {
+		return false, nil, trans.err
+	}
+	if err := checkItemSize(trans.input.Item); err != nil {
+		return false, nil, err
+	}
+	ctx, cancel := trans.client.newRequestContext()
+	defer cancel()
+	var output *dynamodb.PutItemOutput
+	err := trans.client.retry(ctx, func() (err error) {
+		output, err = trans.client.db.PutItem(ctx, &trans.input)
+		return err
+	})
+	if err != nil {
+		err = trans.client.checkVersionConflict(
+			ctx,
+			err,
+			trans.version,
+			trans.input.TableName,
+			trans.key)
+	}
+	result, err := newResult(err, trans.isConditionalCheckFailAllowed)
+	return result, output, err
+}
+
+func (trans *create) panic(err error) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg(`failed to put item into table %q`, *trans.input.TableName).
+			AddDump(trans.input).
+			AddErr(err))
+}
+
+// newItemKey returns key attributes of the item.
+func newItemKey(
+	item map[string]types.AttributeValue,
+	record Record,
+) map[string]types.AttributeValue {
+	result := map[string]types.AttributeValue{}
+	for _, field := range []string{
+		record.GetKeyPartitionField(),
+		record.GetKeySortField(),
+	} {
+		if value, has := item[field]; has && field != "" {
+			result[field] = value
+		}
 	}
 	return result
 }
@@ -115,13 +223,17 @@ type createIfNotExists struct{ create }
 
 func newCreateIfNotExists(
 	record DataRecord,
-	db *dynamodb.DynamoDB,
+	client *client,
 ) *createIfNotExists {
-	return &createIfNotExists{create: *newCreate(record, db)}
+	return &createIfNotExists{create: *newCreate(record, client)}
 }
 
 func (trans *createIfNotExists) Request() Result {
 	return trans.create.Request()
 }
 
+func (trans *createIfNotExists) RequestE() (Result, error) {
+	return trans.create.RequestE()
+}
+
 ////////////////////////////////////////////////////////////////////////////////
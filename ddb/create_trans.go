@@ -6,9 +6,8 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -19,6 +18,7 @@ type CreateTrans interface {
 	Value(name string, value interface{}) CreateTrans
 	Alias(name, value string) CreateTrans
 	Condition(string) CreateTrans
+	ConditionExpr(Condition) CreateTrans
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -27,17 +27,36 @@ func (trans *writeTrans) CreateIfNotExists(record DataRecord) CreateTrans {
 	result := trans.newCreateTrans(record)
 	result.Condition(
 		fmt.Sprintf("attribute_not_exists(%s)", record.GetKeyPartitionField()))
+	if check := newVersionCheck(record); check != nil {
+		result.setItemVersion(*check, 1)
+	}
 	return result
 }
 
 func (trans *writeTrans) CreateOrReplace(record DataRecord) CreateTrans {
-	return trans.newCreateTrans(record)
+	result := trans.newCreateTrans(record)
+	if check := newVersionCheck(record); check != nil {
+		result.ConditionExpr(
+			check.getReplaceCondition(record.GetKeyPartitionField()))
+		result.setItemVersion(*check, check.expected+1)
+		result.setVersionCheck(
+			check,
+			&result.input.ReturnValuesOnConditionCheckFailure)
+	}
+	return result
 }
 
 func (trans *writeTrans) Replace(record DataRecord) CreateTrans {
 	result := trans.newCreateTrans(record)
 	result.Condition(
 		fmt.Sprintf("attribute_exists(%s)", record.GetKeyPartitionField()))
+	if check := newVersionCheck(record); check != nil {
+		result.ConditionExpr(check.getCondition())
+		result.setItemVersion(*check, check.expected+1)
+		result.setVersionCheck(
+			check,
+			&result.input.ReturnValuesOnConditionCheckFailure)
+	}
 	return result
 }
 
@@ -45,21 +64,21 @@ func (trans *writeTrans) Replace(record DataRecord) CreateTrans {
 
 type createTrans struct {
 	writeTransExpression
-	input *dynamodb.Put
+	input *types.Put
 }
 
 func (trans *writeTrans) newCreateTrans(record DataRecord) *createTrans {
-	input := &dynamodb.Put{
+	input := &types.Put{
 		TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
 	}
 	result := createTrans{
 		writeTransExpression: newWriteTransExpression(
 			trans,
-			dynamodb.TransactWriteItem{Put: input}),
+			types.TransactWriteItem{Put: input}),
 		input: input,
 	}
 	var err error
-	result.input.Item, err = dynamodbattribute.MarshalMap(record.GetData())
+	result.input.Item, err = MarshalMap(record.GetData())
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -73,6 +92,20 @@ func (trans *writeTrans) newCreateTrans(record DataRecord) *createTrans {
 	return &result
 }
 
+func (trans *createTrans) setItemVersion(
+	check versionCheck,
+	version RecordVersion,
+) {
+	if err := check.setItemVersion(trans.input.Item, version); err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg(
+					"failed to set item version to put into table %q",
+					*trans.input.TableName).
+				AddErr(err))
+	}
+}
+
 func (trans *createTrans) Values(values Values) CreateTrans {
 	trans.marshalValues(values, &trans.input.ExpressionAttributeValues)
 	return trans
@@ -99,4 +132,13 @@ func (trans *createTrans) Condition(condition string) CreateTrans {
 	return trans
 }
 
+func (trans *createTrans) ConditionExpr(condition Condition) CreateTrans {
+	trans.addCondition(
+		condition,
+		&trans.input.ConditionExpression,
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues)
+	return trans
+}
+
 ////////////////////////////////////////////////////////////////////////////////
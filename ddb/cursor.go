@@ -0,0 +1,332 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+// cursorSource describes the query or scan to build cursors.
+type cursorSource struct {
+	table     string
+	index     string
+	keyFields []string
+	startKey  map[string]types.AttributeValue
+	// scope is the key condition of the query with its names and values,
+	// it's signed with the cursor, so the cursor is valid only for the same
+	// key condition.
+	scope []byte
+}
+
+func newCursorSource(
+	record Record,
+	table *string,
+	index *string,
+	startKey map[string]types.AttributeValue,
+) *cursorSource {
+	result := cursorSource{
+		table:     aws.ToString(table),
+		index:     aws.ToString(index),
+		keyFields: []string{record.GetKeyPartitionField()},
+		startKey:  startKey,
+	}
+	if field := record.GetKeySortField(); field != "" {
+		result.keyFields = append(result.keyFields, field)
+	}
+	if index, isIndex := record.(IndexDescription); isIndex && result.index != "" {
+		for _, field := range []string{
+			index.GetIndexPartitionField(),
+			index.GetIndexSortField(),
+		} {
+			if field != "" && !result.hasKeyField(field) {
+				result.keyFields = append(result.keyFields, field)
+			}
+		}
+	}
+	return &result
+}
+
+// setKeyCondition binds cursors to the query key condition and to its names
+// and values, so the cursor of one partition can't be used to read another.
+func (source *cursorSource) setKeyCondition(
+	expression *string,
+	names map[string]string,
+	values map[string]types.AttributeValue,
+) error {
+	scope := cursorScope{Condition: aws.ToString(expression)}
+	for _, name := range attributeNameRegexp.FindAllString(
+		scope.Condition,
+		-1,
+	) {
+		if scope.Names == nil {
+			scope.Names = map[string]string{}
+		}
+		scope.Names[name] = names[name]
+	}
+	for _, name := range attributeValueRegexp.FindAllString(
+		scope.Condition,
+		-1,
+	) {
+		if scope.Values == nil {
+			scope.Values = map[string]cursorKeyValue{}
+		}
+		scope.Values[name] = newCursorKeyValue(values[name])
+	}
+	var err error
+	source.scope, err = json.Marshal(scope)
+	if err != nil {
+		return newSerializationError(err, "failed to serialize cursor scope")
+	}
+	return nil
+}
+
+func (source cursorSource) hasKeyField(field string) bool {
+	for _, keyField := range source.keyFields {
+		if keyField == field {
+			return true
+		}
+	}
+	return false
+}
+
+// addToProjection adds key fields into the projection if the projection is
+// set, so the cursor could be created by any record.
+func (source cursorSource) addToProjection(
+	projection **string,
+	names *map[string]string,
+) {
+	if *projection == nil {
+		return
+	}
+	result := **projection
+	fields := "," + result + ","
+	for _, field := range source.keyFields {
+		field = aliasReservedWord(field, names)
+		if !strings.Contains(fields, ","+field+",") {
+			result += "," + field
+		}
+	}
+	*projection = aws.String(result)
+}
+
+// newItemCursor creates cursor to continue reading after the item.
+func (source cursorSource) newItemCursor(
+	item map[string]types.AttributeValue,
+) (string, error) {
+	key := make(map[string]types.AttributeValue, len(source.keyFields))
+	for _, field := range source.keyFields {
+		value, has := item[field]
+		if !has {
+			return "", Error{
+				kind: ErrValidation,
+				err: fmt.Errorf(
+					"failed to create cursor as record doesn't have key field %q",
+					field),
+			}
+		}
+		key[field] = value
+	}
+	return source.newCursor(key)
+}
+
+// newCursor creates cursor to continue reading from the key.
+func (source cursorSource) newCursor(
+	key map[string]types.AttributeValue,
+) (string, error) {
+	data := cursorData{
+		Table: source.table,
+		Index: source.index,
+		Key:   make(map[string]cursorKeyValue, len(key)),
+	}
+	for name, value := range key {
+		data.Key[name] = newCursorKeyValue(value)
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return "", newSerializationError(err, "failed to serialize cursor")
+	}
+	signature, err := signCursor(payload, source.scope)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) +
+		"." +
+		base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseCursor validates the cursor and returns the key to continue reading.
+func (source cursorSource) parseCursor(
+	cursor string,
+) (map[string]types.AttributeValue, error) {
+	newCursorError := func(err error) error {
+		return Error{kind: ErrValidation, err: fmt.Errorf("invalid cursor: %w", err)}
+	}
+
+	separator := strings.IndexByte(cursor, '.')
+	if separator < 0 {
+		return nil, newCursorError(errors.New("wrong format"))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(cursor[:separator])
+	if err != nil {
+		return nil, newCursorError(err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(cursor[separator+1:])
+	if err != nil {
+		return nil, newCursorError(err)
+	}
+	expectedSignature, err := signCursor(payload, source.scope)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(signature, expectedSignature) {
+		return nil, newCursorError(errors.New("wrong signature"))
+	}
+
+	var data cursorData
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, newCursorError(err)
+	}
+	if data.Table != source.table || data.Index != source.index {
+		return nil, newCursorError(
+			fmt.Errorf(
+				"cursor is created for table %q and index %q",
+				data.Table,
+				data.Index))
+	}
+	if len(data.Key) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]types.AttributeValue, len(data.Key))
+	for name, value := range data.Key {
+		result[name] = value.export()
+	}
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cursorScope is the key condition which is signed with the cursor, JSON
+// object keys are sorted, so the same condition has the same serialization.
+type cursorScope struct {
+	Condition string                    `json:"c"`
+	Names     map[string]string         `json:"n,omitempty"`
+	Values    map[string]cursorKeyValue `json:"v,omitempty"`
+}
+
+var attributeValueRegexp = regexp.MustCompile(`:[A-Za-z0-9_]+`)
+
+type cursorData struct {
+	Table string                    `json:"t"`
+	Index string                    `json:"i,omitempty"`
+	Key   map[string]cursorKeyValue `json:"k,omitempty"`
+}
+
+// cursorKeyValue is a key attribute value, key attribute could be only
+// string, number or binary.
+type cursorKeyValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+func newCursorKeyValue(source types.AttributeValue) cursorKeyValue {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
+		return cursorKeyValue{S: aws.String(value.Value)}
+	case *types.AttributeValueMemberN:
+		return cursorKeyValue{N: aws.String(value.Value)}
+	case *types.AttributeValueMemberB:
+		return cursorKeyValue{B: value.Value}
+	}
+	return cursorKeyValue{}
+}
+
+func (value cursorKeyValue) export() types.AttributeValue {
+	switch {
+	case value.S != nil:
+		return &types.AttributeValueMemberS{Value: *value.S}
+	case value.N != nil:
+		return &types.AttributeValueMemberN{Value: *value.N}
+	}
+	return &types.AttributeValueMemberB{Value: value.B}
+}
+
+// signCursor signs the cursor and its scope by HMAC with the key derived
+// from the service cursor secret.
+func signCursor(payload []byte, scope []byte) ([]byte, error) {
+	key, err := getCursorKey()
+	if err != nil {
+		return nil, err
+	}
+	hasher := hmac.New(sha256.New, key)
+	for _, data := range [][]byte{payload, []byte("\n"), scope} {
+		if _, err := hasher.Write(data); err != nil {
+			return nil, fmt.Errorf(`failed to calc cursor signature: "%w"`, err)
+		}
+	}
+	return hasher.Sum(nil), nil
+}
+
+// cursorKeyMinSize is the minimal size of the cursor secret in bytes.
+const cursorKeyMinSize = 32
+
+// cursorKey is the HMAC key for cursors, it's derived once for the service
+// cursor secret.
+var cursorKey struct {
+	mutex  sync.Mutex
+	source string
+	value  []byte
+}
+
+func getCursorKey() ([]byte, error) {
+	source := ss.S.Config().DBCursorSecret
+
+	cursorKey.mutex.Lock()
+	defer cursorKey.mutex.Unlock()
+
+	if cursorKey.value != nil && cursorKey.source == source {
+		return cursorKey.value, nil
+	}
+
+	newError := func(err error) error {
+		return Error{
+			kind: ErrValidation,
+			err:  fmt.Errorf("cursor secret is invalid: %w", err),
+		}
+	}
+	if source == "" {
+		return nil, newError(errors.New("it's not configured"))
+	}
+	secret, err := base64.RawStdEncoding.DecodeString(
+		strings.TrimRight(source, "="))
+	if err != nil {
+		return nil, newError(err)
+	}
+	if len(secret) < cursorKeyMinSize {
+		return nil, newError(
+			fmt.Errorf(
+				"it has %d bytes, but at least %d is required",
+				len(secret),
+				cursorKeyMinSize))
+	}
+
+	key := sha256.New()
+	key.Write([]byte("ddb-cursor:"))
+	key.Write(secret)
+	cursorKey.value = key.Sum(nil)
+	cursorKey.source = source
+	return cursorKey.value, nil
+}
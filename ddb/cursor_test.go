@@ -0,0 +1,136 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Cursor_StartFrom(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	for i := 1; i <= 5; i++ {
+		client.CreateOrReplace(newTestData(strconv.Itoa(i), "a", i*10)).Request()
+	}
+	client.CreateOrReplace(newTestData("6", "b", 60)).Request()
+
+	newUserQuery := func(user string) ddb.Query {
+		var record testUserIndex
+		return client.
+			Index(&record).
+			Query("user = :u", ddb.Values{":u": user}).
+			Limit(2)
+	}
+	newQuery := func() ddb.Query { return newUserQuery("a") }
+	readAll := func(it ddb.Iterator) []string {
+		result := []string{}
+		for it.Next() {
+			result = append(result, it.Get().(*testUserIndex).ID)
+		}
+		return result
+	}
+
+	it := newQuery().RequestPaged()
+	start := it.Cursor()
+	assert.NotEmpty(start)
+	assert.Equal(
+		[]string{"1", "2", "3", "4", "5"},
+		readAll(newQuery().StartFrom(start).RequestPaged()))
+
+	// The cursor is in the middle of the page.
+	for i := 0; i < 3; i++ {
+		assert.True(it.Next())
+	}
+	cursor := it.Cursor()
+	assert.Equal(
+		[]string{"4", "5"},
+		readAll(newQuery().StartFrom(cursor).RequestPaged()))
+
+	// The cursor is at the end of the page.
+	assert.True(it.Next())
+	assert.Equal(
+		[]string{"5"},
+		readAll(newQuery().StartFrom(it.Cursor()).RequestPaged()))
+
+	assert.True(it.Next())
+	assert.False(it.Next())
+	assert.Empty(it.Cursor())
+
+	{
+		tampered := []byte(cursor)
+		tampered[len(tampered)/3] ^= 1
+		_, err := newQuery().StartFrom(string(tampered)).RequestPagedE().NextE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		// The cursor is valid only for the same key condition.
+		_, err := newUserQuery("b").StartFrom(cursor).RequestPagedE().NextE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		var record testBuffer
+		_, err := client.
+			Query(&record, "id = :id", ddb.Values{":id": "1"}).
+			StartFrom(cursor).
+			RequestPagedE().
+			NextE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+}
+
+func Test_DDB_Cursor_NoSecret(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+	client.CreateOrReplace(newTestData("2", "a", 20)).Request()
+
+	newQuery := func() ddb.Query {
+		var record testUserIndex
+		return client.
+			Index(&record).
+			Query("user = :u", ddb.Values{":u": "a"}).
+			Limit(1)
+	}
+
+	it := newQuery().RequestPagedE()
+	_, err := it.NextE()
+	assert.NoError(err)
+	cursor, err := it.CursorE()
+	assert.NoError(err)
+
+	// The service doesn't have the private key, as most of lambdas, and
+	// doesn't have the cursor secret.
+	config := newTestServiceConfig()
+	config.PrivateKey.RSA.IsUsed = false
+	config.DBCursorSecret = ""
+	ddbtest.SetService(test, config)
+
+	it = newQuery().RequestPagedE()
+	_, err = it.NextE()
+	assert.NoError(err)
+	_, err = it.CursorE()
+	assert.ErrorIs(err, ddb.ErrValidation)
+
+	_, err = newQuery().StartFrom(cursor).RequestPagedE().NextE()
+	assert.ErrorIs(err, ddb.ErrValidation)
+
+	// The cursor secret is enough without the private key.
+	config.DBCursorSecret = newTestServiceConfig().DBCursorSecret
+	ddbtest.SetService(test, config)
+	it = newQuery().StartFrom(cursor).RequestPagedE()
+	has, err := it.NextE()
+	assert.NoError(err)
+	assert.True(has)
+	assert.Equal("2", it.Get().(*testUserIndex).ID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -6,9 +6,9 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -17,10 +17,16 @@ type Delete interface {
 	CheckedExpression
 
 	Condition(string) Delete
+	ConditionExpr(Condition) Delete
 	Values(Values) Delete
 
 	Request() Result
+	// RequestAndReturn deletes the record and reads the deleted record into
+	// the buffer. The result is not success and the buffer is not changed if
+	// nothing was deleted.
 	RequestAndReturn(RecordBuffer) Result
+	RequestE() (Result, error)
+	RequestAndReturnE(RecordBuffer) (Result, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -43,34 +49,32 @@ func (client *client) DeleteIfExisting(key KeyRecord) Delete {
 type delete struct {
 	checkedExpression
 
-	db    *dynamodb.DynamoDB
-	input dynamodb.DeleteItemInput
+	client *client
+	input  dynamodb.DeleteItemInput
+	err    error
 }
 
 func (client *client) newDeleteTrans(key KeyRecord) *delete {
 	result := delete{
 		checkedExpression: newCheckedExpression(),
-		db:                client.db,
+		client:            client,
 		input: dynamodb.DeleteItemInput{
 			TableName: aws.String(ss.S.NewBuildEntityName(key.GetTable())),
 		},
 	}
 	var err error
-	result.input.Key, err = dynamodbattribute.MarshalMap(key.GetKey())
+	result.input.Key, err = MarshalMap(key.GetKey())
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					`failed to serialize key to delete from table %q`,
-					result.getTable()).
-				AddErr(err).
-				AddDump(key))
+		result.err = newSerializationError(err, "failed to serialize key")
 	}
 	return &result
 }
 
 func (trans *delete) Values(values Values) Delete {
-	values.Marshal(&trans.input.ExpressionAttributeValues)
+	err := values.marshal(&trans.input.ExpressionAttributeValues)
+	if err != nil && trans.err == nil {
+		trans.err = err
+	}
 	return trans
 }
 
@@ -81,41 +85,78 @@ func (trans *delete) Condition(condition string) Delete {
 	return trans
 }
 
+func (trans *delete) ConditionExpr(condition Condition) Delete {
+	err := newExpressionBuilder(
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues,
+	).addCondition(&trans.input.ConditionExpression, condition)
+	if err != nil && trans.err == nil {
+		trans.err = err
+	}
+	return trans
+}
+
 func (trans *delete) Request() Result {
-	result, _ := trans.request()
+	result, err := trans.RequestE()
+	if err != nil {
+		trans.panic(err)
+	}
 	return result
 }
 
 func (trans *delete) RequestAndReturn(resultRecord RecordBuffer) Result {
-	trans.input.ReturnValues = aws.String(dynamodb.ReturnValueAllOld)
-	result, output := trans.request()
-	if !result.IsSuccess() {
-		return result
-	}
-	err := dynamodbattribute.UnmarshalMap(output.Attributes, resultRecord)
+	result, err := trans.RequestAndReturnE(resultRecord)
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					`failed to read delete response from table %q`,
-					trans.getTable()).
-				AddErr(err).
-				AddDump(result))
+		trans.panic(err)
 	}
 	return result
 }
 
-func (trans *delete) request() (Result, *dynamodb.DeleteItemOutput) {
-	request, output := trans.db.DeleteItemRequest(&trans.input)
-	result, err := newResult(request.Send(), trans.isConditionalCheckFailAllowed)
+func (trans *delete) RequestE() (Result, error) {
+	result, _, err := trans.request()
+	return result, err
+}
+
+func (trans *delete) RequestAndReturnE(
+	resultRecord RecordBuffer,
+) (Result, error) {
+	trans.input.ReturnValues = types.ReturnValueAllOld
+	result, output, err := trans.request()
+	if err != nil || !result.IsSuccess() {
+		return result, err
+	}
+	if len(output.Attributes) == 0 {
+		// Nothing was deleted.
+		return false, nil
+	}
+	err = UnmarshalMap(output.Attributes, resultRecord)
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(`failed to delete item from table %q`, trans.getTable()).
-				AddErr(err).
-				AddDump(trans.input))
+		return false, newSerializationError(err, "failed to read delete response")
+	}
+	return result, nil
+}
+
+func (trans *delete) request() (Result, *dynamodb.DeleteItemOutput, error) {
+	if trans.err != nil {
+		return false, nil, trans.err
 	}
-	return result, output
+	ctx, cancel := trans.client.newRequestContext()
+	defer cancel()
+	var output *dynamodb.DeleteItemOutput
+	err := trans.client.retry(ctx, func() (err error) {
+		output, err = trans.client.db.DeleteItem(ctx, &trans.input)
+		return err
+	})
+	result, err := newResult(err, trans.isConditionalCheckFailAllowed)
+	return result, output, err
+}
+
+func (trans *delete) panic(err error) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg(`failed to delete item from table %q`, trans.getTable()).
+			AddErr(err).
+			AddDump(trans.input))
 }
 
 func (delete *delete) getTable() string { return *delete.input.TableName }
@@ -6,17 +6,20 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
 // DeleteTrans describes the part of WriteTrans witch builds delete-expression.
 type DeleteTrans interface {
 	WriteTransExpression
-	Values(Values) UpdateTrans
-	Condition(string) UpdateTrans
+	Values(Values) DeleteTrans
+	Value(name string, value interface{}) DeleteTrans
+	Alias(name, value string) DeleteTrans
+	Condition(string) DeleteTrans
+	ConditionExpr(Condition) DeleteTrans
+	ReturnOnConditionalCheckFail() DeleteTrans
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -40,20 +43,20 @@ func (trans *writeTrans) DeleteIfExisting(key KeyRecord) DeleteTrans {
 
 type deleteTrans struct {
 	writeTransExpression
-	input *dynamodb.Delete
+	input *types.Delete
 }
 
 func (trans *writeTrans) newDeleteTrans(key KeyRecord) *deleteTrans {
-	input := &dynamodb.Delete{
+	input := &types.Delete{
 		TableName: aws.String(ss.S.NewBuildEntityName(key.GetTable())),
 	}
 	result := &deleteTrans{
 		writeTransExpression: newWriteTransExpression(
-			trans, dynamodb.TransactWriteItem{Delete: input}),
+			trans, types.TransactWriteItem{Delete: input}),
 		input: input,
 	}
 	var err error
-	result.input.Key, err = dynamodbattribute.MarshalMap(key.GetKey())
+	result.input.Key, err = MarshalMap(key.GetKey())
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -67,25 +70,40 @@ func (trans *writeTrans) newDeleteTrans(key KeyRecord) *deleteTrans {
 	return result
 }
 
-func (trans *deleteTrans) Values(values Values) UpdateTrans {
+func (trans *deleteTrans) Values(values Values) DeleteTrans {
 	trans.marshalValues(values, &trans.input.ExpressionAttributeValues)
 	return trans
 }
 
-func (trans *deleteTrans) Value(name string, value interface{}) UpdateTrans {
+func (trans *deleteTrans) Value(name string, value interface{}) DeleteTrans {
 	return trans.Values(Values{name: value})
 }
 
-func (trans *deleteTrans) Alias(name, value string) UpdateTrans {
+func (trans *deleteTrans) Alias(name, value string) DeleteTrans {
 	trans.addAlias(name, value, &trans.input.ExpressionAttributeNames)
 	return trans
 }
 
-func (trans *deleteTrans) Condition(condition string) UpdateTrans {
+func (trans *deleteTrans) Condition(condition string) DeleteTrans {
 	*trans.input.ConditionExpression += " and (" +
 		*aliasReservedInString(condition, &trans.input.ExpressionAttributeNames) +
 		")"
 	return trans
 }
 
+func (trans *deleteTrans) ConditionExpr(condition Condition) DeleteTrans {
+	trans.addCondition(
+		condition,
+		&trans.input.ConditionExpression,
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues)
+	return trans
+}
+
+func (trans *deleteTrans) ReturnOnConditionalCheckFail() DeleteTrans {
+	trans.returnOnConditionalCheckFail(
+		&trans.input.ReturnValuesOnConditionCheckFailure)
+	return trans
+}
+
 ////////////////////////////////////////////////////////////////////////////////
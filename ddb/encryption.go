@@ -0,0 +1,497 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+// Record fields with the tag ddb:"encrypt" are encrypted by AEAD before
+// writing and decrypted after reading, keys are set by the keyring in
+// ss.ServiceConfig.DBEncryption. The encrypted attribute is a map with the key
+// ID and the encrypted value. The table name, the record key and
+// the attribute name are authenticated with the value, so the value could not
+// be moved into another attribute, record or table. So the record key can't be
+// encrypted, and it's required to read encrypted attributes, also by indexes.
+// Attributes which are not encrypted yet are read as is.
+//
+// The keyring is required to write records with tagged fields. Only records
+// are encrypted, to write the encrypted field by update expression, the value
+// has to be created by Encrypt.
+//
+// To encrypt a field of existing records:
+//  1. Add the keyring into the config and deploy it.
+//  2. Tag the field in all record, index and event types which read it,
+//     including types for DB stream images: apidbevent unmarshals images by
+//     UnmarshalMap, so a tagged field is decrypted, but a field without
+//     the tag gets the encrypted map. Deploy all readers before writers use
+//     Encrypt for the field in update expressions.
+//  3. Plaintext values are read as is, so records are encrypted when they
+//     are written. Rewrite records which have to be encrypted at once.
+const (
+	encryptTagOption = "encrypt"
+
+	encryptedAttributeKey  = "key"
+	encryptedAttributeData = "data"
+)
+
+// EncryptedValue is a value which is encrypted for the attribute when
+// it's written.
+type EncryptedValue struct {
+	record KeyRecord
+	field  string
+	value  interface{}
+}
+
+// Encrypt creates value which is encrypted for the attribute of the record
+// with the tag ddb:"encrypt", like
+// update.Set("email = :e").Value(":e", ddb.Encrypt(key, "email", email)).
+func Encrypt(record KeyRecord, field string, value interface{}) EncryptedValue {
+	return EncryptedValue{record: record, field: field, value: value}
+}
+
+// MarshalDynamoDBAttributeValue implements serialization for Dynamodb.
+func (value EncryptedValue) MarshalDynamoDBAttributeValue() (
+	types.AttributeValue,
+	error,
+) {
+	source, err := marshal(value.value)
+	if err != nil {
+		return nil, err
+	}
+	key, err := MarshalMap(value.record.GetKey())
+	if err != nil {
+		return nil, err
+	}
+	return getKeyring().encrypt(
+		newEncryptionContext(value.record, key),
+		value.field,
+		source)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// encryptAttributes encrypts attributes of the record fields with
+// the encryption tag.
+func encryptAttributes(
+	source interface{},
+	item map[string]types.AttributeValue,
+) error {
+	var keyring *keyring
+	var context encryptionContext
+	for _, field := range getTaggedFields(
+		reflect.TypeOf(source),
+		encryptTagOption,
+	) {
+		value, has := item[field]
+		if !has {
+			continue
+		}
+		if keyring == nil {
+			context = newRecordEncryptionContext(source, item)
+			keyring = getKeyring()
+		}
+		var err error
+		if item[field], err = keyring.encrypt(context, field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptAttributes returns item copy with decrypted attributes of the record
+// fields with the encryption tag.
+func decryptAttributes(
+	result interface{},
+	item map[string]types.AttributeValue,
+) (map[string]types.AttributeValue, error) {
+	var keyring *keyring
+	var context encryptionContext
+	var decrypted map[string]types.AttributeValue
+	for _, field := range getTaggedFields(
+		reflect.TypeOf(result),
+		encryptTagOption,
+	) {
+		source, has := item[field]
+		if !has {
+			continue
+		}
+		if keyring == nil {
+			context = newRecordEncryptionContext(result, item)
+			keyring = getKeyring()
+		}
+		value, err := keyring.decrypt(context, field, source)
+		if err != nil {
+			return nil, err
+		}
+		if value == source {
+			continue
+		}
+		if decrypted == nil {
+			decrypted = cloneItem(item)
+		}
+		decrypted[field] = value
+	}
+	if decrypted == nil {
+		return item, nil
+	}
+	return decrypted, nil
+}
+
+func parseEncryptedAttribute(
+	source types.AttributeValue,
+) (keyID string, data []byte, isEncrypted bool) {
+	attr, isMap := source.(*types.AttributeValueMemberM)
+	if !isMap || len(attr.Value) != 2 {
+		return "", nil, false
+	}
+	key, isKey := attr.
+		Value[encryptedAttributeKey].(*types.AttributeValueMemberS)
+	value, isData := attr.
+		Value[encryptedAttributeData].(*types.AttributeValueMemberB)
+	if !isKey || !isData {
+		return "", nil, false
+	}
+	return key.Value, value.Value, true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// encryptionContext is the table and the record key, which are authenticated
+// with the encrypted value, so the value is valid only for the same record.
+// The error is returned only when the context is used, so plaintext values
+// are read without the record key.
+type encryptionContext struct {
+	table     string
+	keyFields []string
+	key       []string
+	err       error
+}
+
+func newEncryptionContext(
+	record Record,
+	item map[string]types.AttributeValue,
+) encryptionContext {
+	result := encryptionContext{
+		table:     record.GetTable(),
+		keyFields: []string{record.GetKeyPartitionField()},
+	}
+	if field := record.GetKeySortField(); field != "" {
+		result.keyFields = append(result.keyFields, field)
+	}
+	result.key = make([]string, 0, len(result.keyFields))
+	for _, field := range result.keyFields {
+		value, has := item[field]
+		if !has {
+			result.err = fmt.Errorf(
+				"key attribute %q is required for encrypted attributes",
+				field)
+			break
+		}
+		result.key = append(result.key, formatKeyAttribute(value))
+	}
+	return result
+}
+
+func newRecordEncryptionContext(
+	source interface{},
+	item map[string]types.AttributeValue,
+) encryptionContext {
+	record, isRecord := source.(Record)
+	if !isRecord {
+		return encryptionContext{
+			err: fmt.Errorf("%T has encrypted fields, but it's not a record", source),
+		}
+	}
+	return newEncryptionContext(record, item)
+}
+
+// get returns associated data for the attribute.
+func (context encryptionContext) get(field string) ([]byte, error) {
+	if context.err != nil {
+		return nil, context.err
+	}
+	for _, keyField := range context.keyFields {
+		if keyField == field {
+			return nil, fmt.Errorf(
+				"key attribute %q could not be encrypted",
+				field)
+		}
+	}
+	result := strconv.Quote(context.table) + "," + strconv.Quote(field)
+	for _, value := range context.key {
+		result += "," + strconv.Quote(value)
+	}
+	return []byte(result), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// keyring encrypts attribute values by AES-GCM with keys from the service
+// config.
+type keyring struct {
+	currentKey string
+	aeads      map[string]cipher.AEAD
+	errs       map[string]error
+}
+
+// keyringCache is the keyring which is created once for the service config.
+var keyringCache struct {
+	mutex  sync.Mutex
+	source ss.DBEncryptionConfig
+	value  *keyring
+}
+
+func getKeyring() *keyring {
+	source := ss.S.Config().DBEncryption
+
+	keyringCache.mutex.Lock()
+	defer keyringCache.mutex.Unlock()
+
+	if keyringCache.value == nil ||
+		!reflect.DeepEqual(keyringCache.source, source) {
+		keyringCache.value = newKeyring(source)
+		keyringCache.source = source
+	}
+	return keyringCache.value
+}
+
+func newKeyring(config ss.DBEncryptionConfig) *keyring {
+	result := keyring{
+		currentKey: config.CurrentKey,
+		aeads:      make(map[string]cipher.AEAD, len(config.Keys)),
+		errs:       map[string]error{},
+	}
+	for keyID, source := range config.Keys {
+		aead, err := newAEAD(keyID, source)
+		if err != nil {
+			result.errs[keyID] = err
+			continue
+		}
+		result.aeads[keyID] = aead
+	}
+	return &result
+}
+
+func newAEAD(keyID string, source string) (cipher.AEAD, error) {
+	key, err := base64.RawStdEncoding.DecodeString(source)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to decode database encryption key %q: %w",
+			keyID,
+			err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to init cipher by database encryption key %q: %w",
+			keyID,
+			err)
+	}
+	result, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init AEAD: %w", err)
+	}
+	return result, nil
+}
+
+func (keyring *keyring) getAEAD(keyID string) (cipher.AEAD, error) {
+	if result, has := keyring.aeads[keyID]; has {
+		return result, nil
+	}
+	if err, has := keyring.errs[keyID]; has {
+		return nil, err
+	}
+	return nil, fmt.Errorf("database encryption key %q is unknown", keyID)
+}
+
+func (keyring *keyring) encrypt(
+	context encryptionContext,
+	field string,
+	source types.AttributeValue,
+) (types.AttributeValue, error) {
+	associatedData, err := context.get(field)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(newAttributeJSON(source))
+	if err != nil {
+		return nil, err
+	}
+	if keyring.currentKey == "" {
+		return nil, errors.New(
+			"database encryption current key is not configured")
+	}
+	aead, err := keyring.getAEAD(keyring.currentKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make(
+		[]byte,
+		aead.NonceSize(),
+		aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return &types.AttributeValueMemberM{
+		Value: map[string]types.AttributeValue{
+			encryptedAttributeKey: &types.AttributeValueMemberS{
+				Value: keyring.currentKey,
+			},
+			encryptedAttributeData: &types.AttributeValueMemberB{
+				Value: aead.Seal(nonce, nonce, plaintext, associatedData),
+			},
+		},
+	}, nil
+}
+
+// decrypt returns decrypted attribute value, or the source if the value is
+// not encrypted yet, as records are encrypted only when they are written.
+func (keyring *keyring) decrypt(
+	context encryptionContext,
+	field string,
+	source types.AttributeValue,
+) (types.AttributeValue, error) {
+	keyID, data, isEncrypted := parseEncryptedAttribute(source)
+	if !isEncrypted {
+		return source, nil
+	}
+	associatedData, err := context.get(field)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := keyring.getAEAD(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted attribute %q is too short", field)
+	}
+	plaintext, err := aead.Open(
+		nil,
+		data[:aead.NonceSize()],
+		data[aead.NonceSize():],
+		associatedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt attribute %q: %w", field, err)
+	}
+	var result attributeJSON
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil, fmt.Errorf(
+			"failed to read decrypted attribute %q: %w",
+			field,
+			err)
+	}
+	return result.export()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// attributeJSON is the attribute value in DynamoDB JSON format. Binary, list
+// and map are not omitted, as they could be empty, but not nil.
+type attributeJSON struct {
+	S    *string                  `json:"S,omitempty"`
+	N    *string                  `json:"N,omitempty"`
+	B    []byte                   `json:"B"`
+	BOOL *bool                    `json:"BOOL,omitempty"`
+	NULL bool                     `json:"NULL,omitempty"`
+	L    []attributeJSON          `json:"L"`
+	M    map[string]attributeJSON `json:"M"`
+	SS   []string                 `json:"SS,omitempty"`
+	NS   []string                 `json:"NS,omitempty"`
+	BS   [][]byte                 `json:"BS,omitempty"`
+}
+
+func newAttributeJSON(source types.AttributeValue) attributeJSON {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
+		return attributeJSON{S: &value.Value}
+	case *types.AttributeValueMemberN:
+		return attributeJSON{N: &value.Value}
+	case *types.AttributeValueMemberB:
+		return attributeJSON{B: append([]byte{}, value.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return attributeJSON{BOOL: &value.Value}
+	case *types.AttributeValueMemberL:
+		result := attributeJSON{L: make([]attributeJSON, len(value.Value))}
+		for i, member := range value.Value {
+			result.L[i] = newAttributeJSON(member)
+		}
+		return result
+	case *types.AttributeValueMemberM:
+		result := attributeJSON{
+			M: make(map[string]attributeJSON, len(value.Value)),
+		}
+		for k, member := range value.Value {
+			result.M[k] = newAttributeJSON(member)
+		}
+		return result
+	case *types.AttributeValueMemberSS:
+		return attributeJSON{SS: value.Value}
+	case *types.AttributeValueMemberNS:
+		return attributeJSON{NS: value.Value}
+	case *types.AttributeValueMemberBS:
+		return attributeJSON{BS: value.Value}
+	}
+	return attributeJSON{NULL: true}
+}
+
+func (source attributeJSON) export() (types.AttributeValue, error) {
+	switch {
+	case source.S != nil:
+		return &types.AttributeValueMemberS{Value: *source.S}, nil
+	case source.N != nil:
+		return &types.AttributeValueMemberN{Value: *source.N}, nil
+	case source.B != nil:
+		return &types.AttributeValueMemberB{Value: source.B}, nil
+	case source.BOOL != nil:
+		return &types.AttributeValueMemberBOOL{Value: *source.BOOL}, nil
+	case source.NULL:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case source.L != nil:
+		result := &types.AttributeValueMemberL{
+			Value: make([]types.AttributeValue, len(source.L)),
+		}
+		for i, member := range source.L {
+			var err error
+			if result.Value[i], err = member.export(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case source.M != nil:
+		result := &types.AttributeValueMemberM{
+			Value: make(map[string]types.AttributeValue, len(source.M)),
+		}
+		for k, member := range source.M {
+			var err error
+			if result.Value[k], err = member.export(); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case source.SS != nil:
+		return &types.AttributeValueMemberSS{Value: source.SS}, nil
+	case source.NS != nil:
+		return &types.AttributeValueMemberNS{Value: source.NS}, nil
+	case source.BS != nil:
+		return &types.AttributeValueMemberBS{Value: source.BS}, nil
+	}
+	return nil, errors.New("attribute value type is unknown")
+}
+
+////////////////////////////////////////////////////////////////////////////////
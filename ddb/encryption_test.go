@@ -0,0 +1,178 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testSecretData struct {
+	testData
+	Secret string `json:"secret" ddb:"encrypt"`
+}
+
+func (record testSecretData) GetData() interface{} { return record }
+
+type testSecretBuffer struct {
+	testBuffer
+	Secret string `json:"secret" ddb:"encrypt"`
+}
+
+func (record *testSecretBuffer) Clear() { *record = testSecretBuffer{} }
+
+type testOtherSecretBuffer struct{ testSecretBuffer }
+
+func (testOtherSecretBuffer) GetTable() string { return "Other" }
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Encryption_Attribute(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	getRaw := func(id string) map[string]types.AttributeValue {
+		key, err := ddb.MarshalMap(newTestKey(id).GetKey())
+		assert.NoError(err)
+		output, err := db.GetItem(
+			context.Background(),
+			&dynamodb.GetItemInput{TableName: aws.String("test_Test"), Key: key})
+		assert.NoError(err)
+		return output.Item
+	}
+	find := func(id string) string {
+		record := testSecretBuffer{testBuffer: testBuffer{testKey: newTestKey(id)}}
+		assert.True(client.Find(&record).Request())
+		assert.Equal("a", record.User)
+		return record.Secret
+	}
+
+	client.
+		CreateOrReplace(testSecretData{
+			testData: newTestData("1", "a", 10),
+			Secret:   "x",
+		}).
+		Request()
+	{
+		raw := getRaw("1")
+		assert.Equal(&types.AttributeValueMemberS{Value: "a"}, raw["user"])
+		secret, isMap := raw["secret"].(*types.AttributeValueMemberM)
+		if assert.True(isMap) {
+			assert.Equal(
+				&types.AttributeValueMemberS{Value: "2"},
+				secret.Value["key"])
+		}
+	}
+	assert.Equal("x", find("1"))
+
+	{
+		// Records encrypted by the old key are still readable.
+		config := newTestServiceConfig()
+		config.DBEncryption.CurrentKey = "1"
+		ddbtest.SetService(test, config)
+		client.
+			CreateOrReplace(testSecretData{
+				testData: newTestData("2", "a", 10),
+				Secret:   "y",
+			}).
+			Request()
+		ddbtest.SetService(test, newTestServiceConfig())
+	}
+	assert.Equal("y", find("2"))
+
+	{
+		// Not encrypted yet values are read as is.
+		item := getRaw("2")
+		item["secret"] = &types.AttributeValueMemberS{Value: "z"}
+		_, err := db.PutItem(
+			context.Background(),
+			&dynamodb.PutItemInput{TableName: aws.String("test_Test"), Item: item})
+		assert.NoError(err)
+	}
+	assert.Equal("z", find("2"))
+
+	{
+		update := client.
+			Update(newTestKey("2")).
+			Set("secret = :s").
+			Value(":s", ddb.Encrypt(newTestKey("2"), "secret", "w"))
+		assert.True(update.Request().IsSuccess())
+	}
+	assert.Equal("w", find("2"))
+
+	{
+		// The encrypted value can't be moved into another attribute.
+		value, err := ddb.
+			Encrypt(newTestKey("2"), "other", "v").
+			MarshalDynamoDBAttributeValue()
+		assert.NoError(err)
+		item := getRaw("2")
+		item["secret"] = value
+		assert.Error(ddb.UnmarshalMap(item, &testSecretBuffer{}))
+	}
+	{
+		// The encrypted value can't be moved into another record.
+		item := getRaw("1")
+		item["secret"] = getRaw("2")["secret"]
+		assert.Error(ddb.UnmarshalMap(item, &testSecretBuffer{}))
+	}
+	{
+		// The encrypted value can't be moved into another table.
+		item := getRaw("2")
+		assert.NoError(ddb.UnmarshalMap(item, &testSecretBuffer{}))
+		assert.Error(ddb.UnmarshalMap(item, &testOtherSecretBuffer{}))
+	}
+	{
+		// The record key is required to read the encrypted value.
+		item := getRaw("2")
+		delete(item, "id")
+		assert.Error(ddb.UnmarshalMap(item, &testSecretBuffer{}))
+	}
+}
+
+func Test_DDB_Encryption_NotConfigured(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	config := newTestServiceConfig()
+	config.DBEncryption = ss.DBEncryptionConfig{}
+	ddbtest.SetService(test, config)
+
+	// Records without encrypted fields don't need the keyring.
+	assert.True(
+		client.CreateOrReplace(newTestData("1", "a", 10)).Request().IsSuccess())
+
+	// Plaintext values of encrypted fields are read without the keyring.
+	item, err := ddb.MarshalMap(newTestData("2", "a", 10))
+	assert.NoError(err)
+	item["secret"] = &types.AttributeValueMemberS{Value: "x"}
+	_, err = db.PutItem(
+		context.Background(),
+		&dynamodb.PutItemInput{TableName: aws.String("test_Test"), Item: item})
+	assert.NoError(err)
+	record := testSecretBuffer{testBuffer: testBuffer{testKey: newTestKey("2")}}
+	assert.True(client.Find(&record).Request())
+	assert.Equal("x", record.Secret)
+
+	// Encrypted fields can't be written without the current key.
+	_, err = client.
+		CreateOrReplace(testSecretData{
+			testData: newTestData("3", "a", 10),
+			Secret:   "y",
+		}).
+		RequestE()
+	assert.Error(err)
+}
+
+////////////////////////////////////////////////////////////////////////////////
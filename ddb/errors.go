@@ -0,0 +1,181 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// Typed errors returned by the error-returning API (methods with "E" suffix).
+// The returned error keeps the original error, so errors.Is checks the type
+// and errors.As gets the original SDK error.
+var (
+	// ErrThrottled is returned when the request exceeds the provisioned
+	// throughput or the request rate limit.
+	ErrThrottled = errors.New("request throttled")
+	// ErrConditionFailed is returned when the condition of the request
+	// or of one of the transaction items is not passed, and the conditional
+	// check fail is not allowed for it.
+	ErrConditionFailed = errors.New("conditional check failed")
+	// ErrTransactionConflict is returned when the item is being modified
+	// by another transaction.
+	ErrTransactionConflict = errors.New("transaction conflict")
+	// ErrVersionConflict is returned when the versioned record is changed
+	// by another write, see Versioned.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrValidation is returned when the request is invalid, including
+	// serialization errors of keys, items and values.
+	ErrValidation = errors.New("validation error")
+	// ErrItemTooLarge is returned when the item exceeds DynamoDB item size
+	// limit, the error names the largest attributes.
+	ErrItemTooLarge = errors.New("item too large")
+	// ErrCanceled is returned when the request context is canceled,
+	// or the lambda is about to reach its timeout.
+	ErrCanceled = errors.New("request canceled")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Error is a typed error of a database request.
+type Error struct {
+	kind error
+	err  error
+}
+
+func (err Error) Error() string { return err.kind.Error() + ": " + err.err.Error() }
+func (err Error) Unwrap() error { return err.err }
+func (err Error) Is(target error) bool {
+	return target == err.kind
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// newError creates typed error by SDK error if the error type is known,
+// otherwise returns the source error as is.
+func newError(source error) error {
+	if source == nil {
+		return nil
+	}
+	if kind := getErrorKind(source); kind != nil {
+		return Error{kind: kind, err: source}
+	}
+	return source
+}
+
+// newSerializationError creates typed error for key, item or values
+// serialization fail.
+func newSerializationError(source error, format string, args ...interface{}) error {
+	return Error{
+		kind: ErrValidation,
+		err:  fmt.Errorf(format+": %w", append(args, source)...),
+	}
+}
+
+func getErrorKind(source error) error {
+	var typed Error
+	if errors.As(source, &typed) {
+		return typed.kind
+	}
+	if errors.Is(source, context.Canceled) ||
+		errors.Is(source, context.DeadlineExceeded) {
+		return ErrCanceled
+	}
+
+	var canceled *smithy.CanceledError
+	if errors.As(source, &canceled) {
+		return ErrCanceled
+	}
+
+	var apiErr smithy.APIError
+	if !errors.As(source, &apiErr) {
+		return nil
+	}
+	switch apiErr.ErrorCode() {
+	case (&types.ConditionalCheckFailedException{}).ErrorCode():
+		return ErrConditionFailed
+	case (&types.TransactionConflictException{}).ErrorCode():
+		return ErrTransactionConflict
+	case (&types.ProvisionedThroughputExceededException{}).ErrorCode(),
+		(&types.RequestLimitExceeded{}).ErrorCode(),
+		"ThrottlingException":
+		return ErrThrottled
+	case "ValidationException":
+		if strings.Contains(apiErr.ErrorMessage(), "Item size") {
+			// The size of the updated item could be checked only by DynamoDB.
+			return ErrItemTooLarge
+		}
+		return ErrValidation
+	case (&types.TransactionCanceledException{}).ErrorCode():
+		return getTransactionCancellationKind(apiErr)
+	}
+	return nil
+}
+
+// Transaction cancellation reason codes, one code for each transaction item.
+const (
+	cancellationReasonNone                            = "None"
+	cancellationReasonConditionalCheckFailed          = "ConditionalCheckFailed"
+	cancellationReasonItemCollectionSizeLimitExceeded = "ItemCollectionSizeLimitExceeded"
+	cancellationReasonTransactionConflict             = "TransactionConflict"
+	cancellationReasonProvisionedThroughputExceeded   = "ProvisionedThroughputExceeded"
+	cancellationReasonThrottlingError                 = "ThrottlingError"
+	cancellationReasonValidationError                 = "ValidationError"
+)
+
+// getTransactionCancellationKind returns error type by the transaction
+// cancellation reasons, conditional check fail has the lowest priority as
+// other reasons are not about the data.
+func getTransactionCancellationKind(source smithy.APIError) error {
+	var result error
+	for _, reason := range getTransactionCancellationReasons(source) {
+		switch reason {
+		case cancellationReasonTransactionConflict:
+			return ErrTransactionConflict
+		case cancellationReasonThrottlingError,
+			cancellationReasonProvisionedThroughputExceeded:
+			return ErrThrottled
+		case cancellationReasonValidationError,
+			cancellationReasonItemCollectionSizeLimitExceeded:
+			return ErrValidation
+		case cancellationReasonConditionalCheckFailed:
+			result = ErrConditionFailed
+		}
+	}
+	return result
+}
+
+// getTransactionCancellationReasons returns cancellation reason codes for
+// each transaction item, the codes are taken from the error fields if they
+// are set, or from the error message.
+func getTransactionCancellationReasons(source smithy.APIError) []string {
+	var canceled *types.TransactionCanceledException
+	if errors.As(source, &canceled) && len(canceled.CancellationReasons) != 0 {
+		result := make([]string, len(canceled.CancellationReasons))
+		for i, reason := range canceled.CancellationReasons {
+			result[i] = aws.ToString(reason.Code)
+		}
+		return result
+	}
+
+	message := source.ErrorMessage()
+	begin := strings.LastIndex(message, "[")
+	end := strings.LastIndex(message, "]")
+	if begin >= end {
+		return nil
+	}
+	result := strings.Split(message[begin+1:end], ",")
+	for i, reason := range result {
+		result[i] = strings.TrimSpace(reason)
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,61 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Errors_Kinds(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	client.CreateOrReplace(newTestData("1", "a", 30)).Request()
+
+	{
+		_, err := client.CreateIfNotExists(newTestData("1", "b", 20)).RequestE()
+		assert.ErrorIs(err, ddb.ErrConditionFailed)
+	}
+	{
+		result, err := client.
+			Update(newTestKey("1")).
+			Set("val = :v").
+			Value(":v", make(chan int)).
+			RequestE()
+		assert.False(result.IsSuccess())
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var record testBuffer
+		it := client.
+			WithContext(ctx).
+			Query(&record, "id = :i", ddb.Values{":i": "1"}).
+			RequestPagedE()
+		isFound, err := it.NextE()
+		assert.False(isFound)
+		assert.ErrorIs(err, ddb.ErrCanceled)
+	}
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.Update(newTestKey("2"), "set val = :v").Value(":v", 1)
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrConditionFailed)
+	}
+	{
+		record := testBuffer{testKey: newTestKey("1")}
+		isFound, err := client.Find(&record).RequestE()
+		assert.NoError(err)
+		assert.True(isFound)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -4,8 +4,13 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -15,15 +20,19 @@ type FindMany interface {
 
 	SetTable(RecordBuffer, []Key) CacheIterator
 	Request()
+	RequestE() error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// batchGetItemLimit is the maximum number of keys in one BatchGetItem call.
+const batchGetItemLimit = 100
+
 func (client *client) FindMany() FindMany {
 	return &findMany{
-		db: client.db,
+		client: client,
 		input: dynamodb.BatchGetItemInput{
-			RequestItems: map[string]*dynamodb.KeysAndAttributes{},
+			RequestItems: map[string]types.KeysAndAttributes{},
 		},
 		output: map[string]*cacheIterator{},
 	}
@@ -32,34 +41,30 @@ func (client *client) FindMany() FindMany {
 type findMany struct {
 	ss.NoCopyImpl
 
-	db     *dynamodb.DynamoDB
+	client *client
 	input  dynamodb.BatchGetItemInput
 	output map[string]*cacheIterator
+	err    error
 }
 
 func (find *findMany) SetTable(record RecordBuffer, keys []Key) CacheIterator {
 
-	request := dynamodb.KeysAndAttributes{}
+	request := types.KeysAndAttributes{}
 
 	request.ProjectionExpression = getRecordProjection(
 		record,
 		&request.ExpressionAttributeNames)
 
 	for _, keySource := range keys {
-		key, err := dynamodbattribute.MarshalMap(keySource)
-		if err != nil {
-			ss.S.Log().Panic(
-				ss.
-					NewLogMsg(`failed to serialize key`).
-					AddErr(err).
-					AddDump(record).
-					AddDump(key))
+		key, err := MarshalMap(keySource)
+		if err != nil && find.err == nil {
+			find.err = newSerializationError(err, "failed to serialize key")
 		}
 		request.Keys = append(request.Keys, key)
 	}
 
 	table := ss.S.NewBuildEntityName(record.GetTable())
-	find.input.RequestItems[table] = &request
+	find.input.RequestItems[table] = request
 	result := newCacheIterator(nil, record)
 	find.output[table] = result
 
@@ -67,27 +72,130 @@ func (find *findMany) SetTable(record RecordBuffer, keys []Key) CacheIterator {
 }
 
 func (find *findMany) Request() {
-
-	request, response := find.db.BatchGetItemRequest(&find.input)
-	if err := request.Send(); err != nil {
+	if err := find.RequestE(); err != nil {
 		ss.S.Log().Panic(
 			ss.
 				NewLogMsg(`failed to execute batch get item request`).
 				AddErr(err).
 				AddDump(find.input))
 	}
+}
 
-	if len(response.UnprocessedKeys) != 0 {
-		// In the 1st implementation UnprocessedKeys handling is not supported
-		// to speed up development.
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(`batch get item request respons has unprocessed keys`).
-				AddDump(response).
-				AddDump(find.input))
+func (find *findMany) RequestE() error {
+	if find.err != nil {
+		return find.err
 	}
 
-	for tableName, table := range response.Responses {
-		find.output[tableName].Set(table)
+	ctx, cancel := find.client.newRequestContext()
+	defer cancel()
+
+	chunks := find.splitIntoChunks()
+	responses := make([]map[string][]map[string]types.AttributeValue, len(chunks))
+	var err error
+	var errMutex sync.Mutex
+	runInPool(len(chunks), func(i int) {
+		var chunkErr error
+		responses[i], chunkErr = find.requestChunk(ctx, chunks[i])
+		if chunkErr == nil {
+			return
+		}
+		errMutex.Lock()
+		defer errMutex.Unlock()
+		if err == nil {
+			// Other chunks are canceled as the result is already failed,
+			// so only the first error is important.
+			err = chunkErr
+			cancel()
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for table, output := range find.output {
+		items := []map[string]types.AttributeValue{}
+		for _, response := range responses {
+			items = append(items, response[table]...)
+		}
+		output.Set(items)
 	}
+	return nil
 }
+
+// splitIntoChunks splits keys of all tables into BatchGetItem requests
+// with no more than batchGetItemLimit keys.
+func (find *findMany) splitIntoChunks() []*dynamodb.BatchGetItemInput {
+	tables := make([]string, 0, len(find.input.RequestItems))
+	for table := range find.input.RequestItems {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	result := []*dynamodb.BatchGetItemInput{}
+	var chunk *dynamodb.BatchGetItemInput
+	chunkSize := 0
+	for _, table := range tables {
+		request := find.input.RequestItems[table]
+		for _, key := range request.Keys {
+			if chunk == nil || chunkSize == batchGetItemLimit {
+				chunk = &dynamodb.BatchGetItemInput{
+					RequestItems: map[string]types.KeysAndAttributes{},
+				}
+				result = append(result, chunk)
+				chunkSize = 0
+			}
+			chunkRequest, has := chunk.RequestItems[table]
+			if !has {
+				chunkRequest = types.KeysAndAttributes{
+					ConsistentRead:           request.ConsistentRead,
+					ProjectionExpression:     request.ProjectionExpression,
+					ExpressionAttributeNames: request.ExpressionAttributeNames,
+				}
+			}
+			chunkRequest.Keys = append(chunkRequest.Keys, key)
+			chunk.RequestItems[table] = chunkRequest
+			chunkSize++
+		}
+	}
+	return result
+}
+
+// requestChunk requests one chunk and retries unprocessed keys by the retry
+// policy until all keys are processed.
+func (find *findMany) requestChunk(
+	ctx context.Context,
+	input *dynamodb.BatchGetItemInput,
+) (map[string][]map[string]types.AttributeValue, error) {
+	result := map[string][]map[string]types.AttributeValue{}
+	for attempt := 0; ; attempt++ {
+		var response *dynamodb.BatchGetItemOutput
+		err := find.client.retry(ctx, func() (err error) {
+			response, err = find.client.db.BatchGetItem(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, newError(err)
+		}
+		for table, items := range response.Responses {
+			result[table] = append(result[table], items...)
+		}
+
+		if len(response.UnprocessedKeys) == 0 {
+			return result, nil
+		}
+		if !find.client.retryPolicy.hasAttempt(attempt) {
+			return nil, Error{
+				kind: ErrThrottled,
+				err: fmt.Errorf(
+					"batch get item request still has unprocessed keys after %d retries",
+					attempt),
+			}
+		}
+		if err := find.client.retryPolicy.wait(ctx, attempt); err != nil {
+			return nil, newError(err)
+		}
+		input = &dynamodb.BatchGetItemInput{RequestItems: response.UnprocessedKeys}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
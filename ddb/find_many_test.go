@@ -0,0 +1,52 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_FindMany_Chunks(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	client.CreateOrReplace(newTestData("1", "a", 30)).Request()
+	client.CreateOrReplace(newTestData("2", "b", 20)).Request()
+
+	var record testBuffer
+	find := client.FindMany()
+	it := find.SetTable(
+		&record,
+		[]ddb.Key{newTestKey("1"), newTestKey("3")})
+	find.Request()
+	assert.Equal(1, it.GetSize())
+	assert.True(it.Next())
+	assert.Equal("a", record.User)
+	assert.False(it.Next())
+
+	db.SetBatchGetItemLimit(30)
+	keys := []ddb.Key{}
+	for i := 0; i < 250; i++ {
+		id := strconv.Itoa(i)
+		client.CreateOrReplace(newTestData(id, "c", i)).Request()
+		keys = append(keys, newTestKey(id), newTestKey("missing "+id))
+	}
+	find = client.FindMany()
+	it = find.SetTable(&record, keys)
+	assert.NoError(find.RequestE())
+	assert.Equal(250, it.GetSize())
+	ids := map[string]struct{}{}
+	for it.Next() {
+		ids[record.ID] = struct{}{}
+	}
+	assert.Equal(250, len(ids))
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,96 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testRecord struct{}
+
+func (testRecord) GetTable() string             { return "Test" }
+func (testRecord) GetKeyPartitionField() string { return "id" }
+func (testRecord) GetKeySortField() string      { return "" }
+
+type testKeyValue struct {
+	ID string `json:"id"`
+}
+
+type testKey struct {
+	testRecord
+	testKeyValue
+}
+
+func newTestKey(id string) testKey {
+	return testKey{testKeyValue: testKeyValue{ID: id}}
+}
+
+func (key testKey) GetKey() interface{} { return key.testKeyValue }
+
+type testData struct {
+	testRecord
+	testKeyValue
+	User  string `json:"user"`
+	Time  int    `json:"time"`
+	Value int    `json:"val"`
+}
+
+func newTestData(id, user string, time int) testData {
+	return testData{
+		testKeyValue: testKeyValue{ID: id},
+		User:         user,
+		Time:         time,
+	}
+}
+
+func (record testData) GetData() interface{} { return record }
+
+type testBuffer struct {
+	testKey
+	User  string `json:"user"`
+	Time  int    `json:"time"`
+	Value int    `json:"val"`
+}
+
+func (record *testBuffer) Clear() { *record = testBuffer{} }
+
+type testUserIndex struct {
+	testRecord
+	testKeyValue
+	Time int `json:"time"`
+}
+
+func (testUserIndex) GetIndex() string               { return "User" }
+func (testUserIndex) GetIndexPartitionField() string { return "user" }
+func (testUserIndex) GetIndexSortField() string      { return "time" }
+func (testUserIndex) GetProjection() []string        { return []string{} }
+func (record *testUserIndex) Clear()                 { *record = testUserIndex{} }
+
+func newTestServiceConfig() ss.ServiceConfig {
+	var result ss.ServiceConfig
+	result.DBEncryption = ss.DBEncryptionConfig{
+		CurrentKey: "2",
+		Keys: map[string]string{
+			"1": "A9eotsz5PEWpMTj9znCmiCt82tZnAXh0yZelf+Jqz8k",
+			"2": "jAyvYp2Q/dHb8XKRVubb3jtw8SqU40EOj3w5hTfrwA8",
+		},
+	}
+	result.DBCursorSecret = "Q2oQb0Zl0y0kJ3HqDk8rB0hW2C1m4Yt9sVx6uNp7aLc"
+	return result
+}
+
+func newTestClient(test *testing.T) (ddb.Client, *ddbtest.DB) {
+	ddbtest.SetService(test, newTestServiceConfig())
+	db := ddbtest.NewDB()
+	db.CreateTable(testRecord{}, &testUserIndex{})
+	return ddbtest.NewClient(db), db
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -4,9 +4,8 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/palchukovsky/ss"
 )
 
@@ -15,6 +14,9 @@ type Find interface {
 	ss.NoCopy
 
 	Request() bool
+	// RequestE returns false if the record is not found,
+	// or typed error instead of panic.
+	RequestE() (bool, error)
 }
 
 // Get describes the interface to query one record by key from a database.
@@ -22,24 +24,18 @@ type Get interface{ Request() }
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func newFind(db *dynamodb.DynamoDB, record KeyRecordBuffer) *find {
+func newFind(client *client, record KeyRecordBuffer) *find {
 	result := find{
-		db:     db,
+		client: client,
 		record: record,
 		input: dynamodb.GetItemInput{
 			TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
 		},
 	}
 	var err error
-	result.input.Key, err = dynamodbattribute.MarshalMap(record.GetKey())
+	result.input.Key, err = MarshalMap(record.GetKey())
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					`failed to serialize key to get item from %q`,
-					record.GetTable()).
-				AddErr(err).
-				AddDump(record))
+		result.err = newSerializationError(err, "failed to serialize key")
 		return &result
 	}
 	result.input.ProjectionExpression = getRecordProjection(
@@ -51,14 +47,15 @@ func newFind(db *dynamodb.DynamoDB, record KeyRecordBuffer) *find {
 type find struct {
 	ss.NoCopyImpl
 
-	db     *dynamodb.DynamoDB
+	client *client
 	record RecordBuffer
 	input  dynamodb.GetItemInput
+	err    error
 }
 
 func (find *find) Request() bool {
-	request, response := find.db.GetItemRequest(&find.input)
-	if err := request.Send(); err != nil {
+	result, err := find.RequestE()
+	if err != nil {
 		ss.S.Log().Panic(
 			ss.
 				NewLogMsg(`failed to get item from table %q`, find.record.GetTable()).
@@ -66,27 +63,36 @@ func (find *find) Request() bool {
 				AddDump(find.record).
 				AddDump(find.input))
 	}
-	if len(response.Item) == 0 {
-		return false
+	return result
+}
+
+func (find *find) RequestE() (bool, error) {
+	if find.err != nil {
+		return false, find.err
 	}
-	err := dynamodbattribute.UnmarshalMap(response.Item, find.record)
+	ctx, cancel := find.client.newRequestContext()
+	defer cancel()
+	var response *dynamodb.GetItemOutput
+	err := find.client.retry(ctx, func() (err error) {
+		response, err = find.client.db.GetItem(ctx, &find.input)
+		return err
+	})
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					`failed to read get-response from table %q`,
-					find.record.GetTable()).
-				AddErr(err).
-				AddDump(find.record).
-				AddDump(find.input))
+		return false, newError(err)
+	}
+	if len(response.Item) == 0 {
+		return false, nil
+	}
+	if err := UnmarshalMap(response.Item, find.record); err != nil {
+		return false, newSerializationError(err, "failed to read get-response")
 	}
-	return true
+	return true, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func newGet(db *dynamodb.DynamoDB, record KeyRecordBuffer) *get {
-	return &get{find: newFind(db, record)}
+func newGet(client *client, record KeyRecordBuffer) *get {
+	return &get{find: newFind(client, record)}
 }
 
 type get struct{ *find }
@@ -4,7 +4,10 @@
 package ddbinstall
 
 import (
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/palchukovsky/ss"
 )
 
@@ -22,44 +25,54 @@ type DB interface {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func NewDB() DB { return dbClient{db: dynamodb.New(ss.S.NewAWSSessionV1())} }
+// tableWaitTimeout is the maximum time to wait for the table state,
+// it's the same as the deprecated SDK waiters had by default.
+const tableWaitTimeout = 25 * 20 * time.Second
+
+func NewDB() DB {
+	return dbClient{db: dynamodb.NewFromConfig(ss.S.NewAWSConfig())}
+}
 
-type dbClient struct{ db *dynamodb.DynamoDB }
+type dbClient struct{ db *dynamodb.Client }
 
 func (db dbClient) CreateTable(input dynamodb.CreateTableInput) error {
-	request, _ := db.db.CreateTableRequest(&input)
-	return request.Send()
+	_, err := db.db.CreateTable(context.Background(), &input)
+	return err
 }
 
 func (db dbClient) DescribeTable(input dynamodb.DescribeTableInput,
 ) (dynamodb.DescribeTableOutput, error) {
-	request, result := db.db.DescribeTableRequest(&input)
-	if err := request.Send(); err != nil {
+	result, err := db.db.DescribeTable(context.Background(), &input)
+	if err != nil {
 		return dynamodb.DescribeTableOutput{}, err
 	}
 	return *result, nil
 }
 
 func (db dbClient) WaitTable(input dynamodb.DescribeTableInput) error {
-	return db.db.WaitUntilTableExists(&input)
+	return dynamodb.
+		NewTableExistsWaiter(db.db).
+		Wait(context.Background(), &input, tableWaitTimeout)
 }
 
 func (db dbClient) WaitUntilTableNotExists(input dynamodb.DescribeTableInput,
 ) error {
-	return db.db.WaitUntilTableNotExists(&input)
+	return dynamodb.
+		NewTableNotExistsWaiter(db.db).
+		Wait(context.Background(), &input, tableWaitTimeout)
 }
 
 func (db dbClient) UpdateTable(input dynamodb.UpdateTableInput) error {
-	request, _ := db.db.UpdateTableRequest(&input)
-	return request.Send()
+	_, err := db.db.UpdateTable(context.Background(), &input)
+	return err
 }
 
 func (db dbClient) UpdateTimeToLive(input dynamodb.UpdateTimeToLiveInput) error {
-	request, _ := db.db.UpdateTimeToLiveRequest(&input)
-	return request.Send()
+	_, err := db.db.UpdateTimeToLive(context.Background(), &input)
+	return err
 }
 
 func (db dbClient) DeleteTable(input dynamodb.DeleteTableInput) error {
-	request, _ := db.db.DeleteTableRequest(&input)
-	return request.Send()
+	_, err := db.db.DeleteTable(context.Background(), &input)
+	return err
 }
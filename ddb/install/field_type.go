@@ -7,13 +7,13 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 	"github.com/palchukovsky/ss/ddb"
 )
 
 func getTypeFields(
-	record ddb.IndexRecord,
+	record ddb.IndexDescription,
 	source reflect.Type,
 	names map[string]struct{},
 ) {
@@ -63,7 +63,10 @@ func getFieldName(field reflect.StructField) (string, bool) {
 	return tag, true
 }
 
-func getFiledType(record ddb.DataRecord, fieldName string) string {
+func getFiledType(
+	record ddb.DataRecord,
+	fieldName string,
+) types.ScalarAttributeType {
 	result := getTypeType(reflect.ValueOf(record.GetData()).Type(), fieldName)
 	if result == "" {
 		ss.S.Log().Panic(
@@ -75,7 +78,10 @@ func getFiledType(record ddb.DataRecord, fieldName string) string {
 	return result
 }
 
-func getTypeType(source reflect.Type, fieldName string) string {
+func getTypeType(
+	source reflect.Type,
+	fieldName string,
+) types.ScalarAttributeType {
 	if source.Kind() == reflect.Ptr {
 		source = source.Elem()
 	}
@@ -101,7 +107,7 @@ func getTypeType(source reflect.Type, fieldName string) string {
 	return ""
 }
 
-func getTypeByType(source reflect.Type) string {
+func getTypeByType(source reflect.Type) types.ScalarAttributeType {
 	if source.Kind() == reflect.Ptr {
 		source = source.Elem()
 	}
@@ -111,24 +117,24 @@ func getTypeByType(source reflect.Type) string {
 		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64:
 		{
-			return dynamodb.ScalarAttributeTypeN
+			return types.ScalarAttributeTypeN
 		}
 	case reflect.String:
-		return dynamodb.ScalarAttributeTypeS
+		return types.ScalarAttributeTypeS
 	case reflect.Array:
-		return dynamodb.ScalarAttributeTypeB
+		return types.ScalarAttributeTypeB
 	}
 
 	switch source.PkgPath() {
 	case "github.com/palchukovsky/ss":
 		switch source.Name() {
 		case "EntityID":
-			return dynamodb.ScalarAttributeTypeB
+			return types.ScalarAttributeTypeB
 		}
 	case "github.com/palchukovsky/ss/ddb":
 		switch source.Name() {
 		case "Time", "DateOrTime":
-			return dynamodb.ScalarAttributeTypeN
+			return types.ScalarAttributeTypeN
 		}
 	}
 
@@ -144,7 +150,7 @@ func getTypeByType(source reflect.Type) string {
 	return ""
 }
 
-func getTypeBySubtype(source reflect.Type) string {
+func getTypeBySubtype(source reflect.Type) types.ScalarAttributeType {
 	if source.Kind() == reflect.Ptr {
 		source = source.Elem()
 	}
@@ -6,13 +6,12 @@ package ddbinstall
 import (
 	"reflect"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 	"github.com/palchukovsky/ss/ddb"
 )
 
-func getIndexProjection(records ...ddb.IndexRecord) *dynamodb.Projection {
+func getIndexProjection(records ...ddb.IndexDescription) *types.Projection {
 	var table string
 	var index string
 
@@ -49,16 +48,14 @@ func getIndexProjection(records ...ddb.IndexRecord) *dynamodb.Projection {
 	}
 
 	if len(names) == 0 {
-		return &dynamodb.Projection{
-			ProjectionType: aws.String(dynamodb.ProjectionTypeKeysOnly),
-		}
+		return &types.Projection{ProjectionType: types.ProjectionTypeKeysOnly}
 	}
-	result := dynamodb.Projection{
-		ProjectionType:   aws.String(dynamodb.ProjectionTypeInclude),
-		NonKeyAttributes: make([]*string, 0, len(names)),
+	result := types.Projection{
+		ProjectionType:   types.ProjectionTypeInclude,
+		NonKeyAttributes: make([]string, 0, len(names)),
 	}
 	for name := range names {
-		result.NonKeyAttributes = append(result.NonKeyAttributes, aws.String(name))
+		result.NonKeyAttributes = append(result.NonKeyAttributes, name)
 	}
 	return &result
 }
@@ -4,11 +4,14 @@
 package ddbinstall
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	ddb "github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ddb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/palchukovsky/ss"
 	ssddb "github.com/palchukovsky/ss/ddb"
 )
@@ -68,10 +71,10 @@ func (table TableAbstraction) Delete() error {
 }
 
 func (table TableAbstraction) Create(
-	indexRecords []ssddb.IndexRecord,
+	indexRecords []ssddb.IndexDescription,
 ) error {
 
-	attributeNames := map[string]string{}
+	attributeNames := map[string]types.ScalarAttributeType{}
 	addAttribute := func(name string) {
 		if _, has := attributeNames[name]; has {
 			return
@@ -81,22 +84,22 @@ func (table TableAbstraction) Create(
 
 	addAttribute(table.record.GetKeyPartitionField())
 
-	primaryKey := []*ddb.KeySchemaElement{
+	primaryKey := []types.KeySchemaElement{
 		{
 			AttributeName: aws.String(table.record.GetKeyPartitionField()),
-			KeyType:       aws.String(ddb.KeyTypeHash),
+			KeyType:       types.KeyTypeHash,
 		},
 	}
 	if table.record.GetKeySortField() != "" {
-		primaryKey = append(primaryKey, &ddb.KeySchemaElement{
+		primaryKey = append(primaryKey, types.KeySchemaElement{
 			AttributeName: aws.String(table.record.GetKeySortField()),
-			KeyType:       aws.String(ddb.KeyTypeRange),
+			KeyType:       types.KeyTypeRange,
 		})
 		addAttribute(table.record.GetKeySortField())
 	}
 
-	indexMap := map[string]*ddb.GlobalSecondaryIndex{}
-	recordByIndex := map[string][]ssddb.IndexRecord{}
+	indexMap := map[string]*types.GlobalSecondaryIndex{}
+	recordByIndex := map[string][]ssddb.IndexDescription{}
 	for i, record := range indexRecords {
 		if record.GetTable() != table.record.GetTable() {
 			return fmt.Errorf(`index #%d from table %q, but expected from table %q`,
@@ -110,38 +113,38 @@ func (table TableAbstraction) Create(
 			recordByIndex[record.GetIndex()] = append(val, record)
 			continue
 		}
-		recordByIndex[record.GetIndex()] = []ssddb.IndexRecord{record}
-		index := ddb.GlobalSecondaryIndex{
+		recordByIndex[record.GetIndex()] = []ssddb.IndexDescription{record}
+		index := types.GlobalSecondaryIndex{
 			IndexName: aws.String(record.GetIndex()),
-			KeySchema: []*ddb.KeySchemaElement{
+			KeySchema: []types.KeySchemaElement{
 				{
 					AttributeName: aws.String(record.GetIndexPartitionField()),
-					KeyType:       aws.String(ddb.KeyTypeHash),
+					KeyType:       types.KeyTypeHash,
 				},
 			},
 		}
 		if record.GetIndexSortField() != "" {
-			index.KeySchema = append(index.KeySchema, &ddb.KeySchemaElement{
+			index.KeySchema = append(index.KeySchema, types.KeySchemaElement{
 				AttributeName: aws.String(record.GetIndexSortField()),
-				KeyType:       aws.String(ddb.KeyTypeRange),
+				KeyType:       types.KeyTypeRange,
 			})
 		}
 		indexMap[record.GetIndex()] = &index
 	}
-	var indexes []*ddb.GlobalSecondaryIndex
+	var indexes []types.GlobalSecondaryIndex
 	if len(indexMap) > 0 {
-		indexes = make([]*ddb.GlobalSecondaryIndex, 0, len(indexMap))
+		indexes = make([]types.GlobalSecondaryIndex, 0, len(indexMap))
 		for key, index := range indexMap {
 			index.Projection = getIndexProjection(recordByIndex[key]...)
-			indexes = append(indexes, index)
+			indexes = append(indexes, *index)
 		}
 	}
 
-	attributes := make([]*ddb.AttributeDefinition, 0, len(attributeNames))
+	attributes := make([]types.AttributeDefinition, 0, len(attributeNames))
 	for name, fieldType := range attributeNames {
-		attributes = append(attributes, &ddb.AttributeDefinition{
+		attributes = append(attributes, types.AttributeDefinition{
 			AttributeName: aws.String(name),
-			AttributeType: aws.String(string(fieldType)),
+			AttributeType: fieldType,
 		})
 	}
 
@@ -151,7 +154,7 @@ func (table TableAbstraction) Create(
 		AttributeDefinitions:   attributes,
 		KeySchema:              primaryKey,
 		GlobalSecondaryIndexes: indexes,
-		Tags: []*ddb.Tag{
+		Tags: []types.Tag{
 			{Key: aws.String("product"), Value: aws.String(ss.S.Product())},
 			{Key: aws.String("project"), Value: aws.String("backend")},
 			{Key: aws.String("package"), Value: aws.String("database")},
@@ -159,7 +162,7 @@ func (table TableAbstraction) Create(
 			{Key: aws.String("version"), Value: aws.String(build.Version)},
 			{Key: aws.String("builder"), Value: aws.String(build.Builder)},
 		},
-		BillingMode: aws.String(ddb.BillingModePayPerRequest),
+		BillingMode: types.BillingModePayPerRequest,
 		TableName:   table.getAWSName(),
 	}
 
@@ -199,7 +202,7 @@ func (table TableAbstraction) EnableTimeToLive(fieldName string) error {
 
 	return table.db.UpdateTimeToLive(ddb.UpdateTimeToLiveInput{
 		TableName: table.getAWSName(),
-		TimeToLiveSpecification: &ddb.TimeToLiveSpecification{
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
 			AttributeName: aws.String(fieldName),
 			Enabled:       ss.BoolPtr(true),
 		},
@@ -211,9 +214,9 @@ func (table TableAbstraction) EnableStreams(streams Streams) error {
 		return err
 	}
 
-	streamSpecification := ddb.StreamSpecification{
+	streamSpecification := types.StreamSpecification{
 		StreamEnabled:  ss.BoolPtr(true),
-		StreamViewType: aws.String(string(streams.ViewType)),
+		StreamViewType: types.StreamViewType(streams.ViewType),
 	}
 	err := table.db.UpdateTable(ddb.UpdateTableInput{
 		TableName:           table.getAWSName(),
@@ -230,19 +233,18 @@ func (table TableAbstraction) EnableStreams(streams Streams) error {
 		return err
 	}
 
+	client := lambda.NewFromConfig(ss.S.NewAWSConfig())
 	for _, stream := range streams.Streams {
 		input := lambda.CreateEventSourceMappingInput{
 			Enabled:        ss.BoolPtr(true),
 			EventSourceArn: description.Table.LatestStreamArn,
 			FunctionName: aws.String(
 				ss.S.NewBuildEntityName("api_dbevent_" + stream.lambda)),
-			StartingPosition:           aws.String(lambda.EventSourcePositionLatest),
+			StartingPosition:           lambdatypes.EventSourcePositionLatest,
 			BisectBatchOnFunctionError: ss.BoolPtr(true),
 		}
-		request, _ := lambda.
-			New(ss.S.NewAWSSessionV1()).
-			CreateEventSourceMappingRequest(&input)
-		if err := request.Send(); err != nil {
+		_, err := client.CreateEventSourceMapping(context.Background(), &input)
+		if err != nil {
 			return fmt.Errorf(
 				`failed to create event source mapping for %q (%q -> %q): "%w"`,
 				stream.lambda,
@@ -266,10 +268,10 @@ func NewStream(lambda string) Stream { return Stream{lambda: lambda} }
 type StreamViewType string
 
 const (
-	StreamViewTypeNone StreamViewType = ddb.StreamViewTypeKeysOnly
-	StreamViewTypePrev StreamViewType = ddb.StreamViewTypeOldImage
-	StreamViewTypeNew  StreamViewType = ddb.StreamViewTypeNewImage
-	StreamViewTypeFull StreamViewType = ddb.StreamViewTypeNewAndOldImages
+	StreamViewTypeNone StreamViewType = StreamViewType(types.StreamViewTypeKeysOnly)
+	StreamViewTypePrev StreamViewType = StreamViewType(types.StreamViewTypeOldImage)
+	StreamViewTypeNew  StreamViewType = StreamViewType(types.StreamViewTypeNewImage)
+	StreamViewTypeFull StreamViewType = StreamViewType(types.StreamViewTypeNewAndOldImages)
 )
 
 type Streams struct {
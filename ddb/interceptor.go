@@ -0,0 +1,383 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Interceptor hooks each database request, including each retry attempt.
+// The interceptor has to call next to execute the request (or the next
+// interceptor), and returns the request error, or another error to fail
+// the request. If the interceptor doesn't call next, it has to return
+// an error, so it could be used for fault injection. Reads which are served
+// by Cache don't send requests, so they are not intercepted.
+type Interceptor func(
+	ctx context.Context,
+	operation *Operation,
+	next func(context.Context) error,
+) error
+
+// OperationKind is DynamoDB operation name.
+type OperationKind string
+
+const (
+	OperationGetItem            OperationKind = "GetItem"
+	OperationBatchGetItem       OperationKind = "BatchGetItem"
+	OperationQuery              OperationKind = "Query"
+	OperationScan               OperationKind = "Scan"
+	OperationPutItem            OperationKind = "PutItem"
+	OperationUpdateItem         OperationKind = "UpdateItem"
+	OperationDeleteItem         OperationKind = "DeleteItem"
+	OperationBatchWriteItem     OperationKind = "BatchWriteItem"
+	OperationTransactWriteItems OperationKind = "TransactWriteItems"
+	OperationTransactGetItems   OperationKind = "TransactGetItems"
+)
+
+// Operation describes the database request for interceptors. Output,
+// consumed capacity and duration are set when next returns.
+type Operation struct {
+	Kind OperationKind
+	// Tables has names of all tables of the request, sorted.
+	Tables []string
+	// Input is the SDK request input, like *dynamodb.GetItemInput.
+	Input interface{}
+	// Output is the SDK request output, like *dynamodb.GetItemOutput,
+	// or nil if the request is failed.
+	Output           interface{}
+	ConsumedCapacity []types.ConsumedCapacity
+	Duration         time.Duration
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// interceptedAPI calls interceptors for each request of the API. The total
+// consumed capacity is requested for each request, so interceptors could
+// see it.
+type interceptedAPI struct {
+	api          API
+	interceptors []Interceptor
+}
+
+func newInterceptedAPI(api API, interceptors []Interceptor) API {
+	if len(interceptors) == 0 {
+		return api
+	}
+	return interceptedAPI{api: api, interceptors: interceptors}
+}
+
+func (api interceptedAPI) intercept(
+	ctx context.Context,
+	operation *Operation,
+	request func(context.Context) error,
+) error {
+	next := request
+	for i := len(api.interceptors) - 1; i >= 0; i-- {
+		interceptor := api.interceptors[i]
+		inner := next
+		next = func(ctx context.Context) error {
+			return interceptor(ctx, operation, inner)
+		}
+	}
+	return next(ctx)
+}
+
+func intercept[Input any, Output any](
+	api interceptedAPI,
+	ctx context.Context,
+	operation Operation,
+	input *Input,
+	request func(
+		context.Context,
+		*Input,
+		...func(*dynamodb.Options),
+	) (*Output, error),
+	getConsumedCapacity func(*Output) []types.ConsumedCapacity,
+	options []func(*dynamodb.Options),
+) (*Output, error) {
+	operation.Input = input
+	var output *Output
+	err := api.intercept(
+		ctx,
+		&operation,
+		func(ctx context.Context) error {
+			start := time.Now()
+			var err error
+			output, err = request(ctx, input, options...)
+			operation.Duration = time.Since(start)
+			if err != nil {
+				return err
+			}
+			operation.Output = output
+			operation.ConsumedCapacity = getConsumedCapacity(output)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+func newOperation(kind OperationKind, tables ...*string) Operation {
+	result := Operation{Kind: kind, Tables: make([]string, 0, len(tables))}
+	unique := make(map[string]struct{}, len(tables))
+	for _, table := range tables {
+		if _, has := unique[aws.ToString(table)]; has {
+			continue
+		}
+		unique[aws.ToString(table)] = struct{}{}
+		result.Tables = append(result.Tables, aws.ToString(table))
+	}
+	sort.Strings(result.Tables)
+	return result
+}
+
+func getMapTables[Value any](source map[string]Value) []*string {
+	result := make([]*string, 0, len(source))
+	for table := range source {
+		result = append(result, aws.String(table))
+	}
+	return result
+}
+
+// copyInput returns the shallow copy of the request input, so the caller input
+// is not changed by the interceptor.
+func copyInput[Input any](source *Input) *Input {
+	result := *source
+	return &result
+}
+
+func requestConsumedCapacity(destination *types.ReturnConsumedCapacity) {
+	if *destination == "" {
+		*destination = types.ReturnConsumedCapacityTotal
+	}
+}
+
+func newConsumedCapacity(
+	source *types.ConsumedCapacity,
+) []types.ConsumedCapacity {
+	if source == nil {
+		return nil
+	}
+	return []types.ConsumedCapacity{*source}
+}
+
+func (api interceptedAPI) GetItem(
+	ctx context.Context,
+	input *dynamodb.GetItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.GetItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationGetItem, input.TableName),
+		input,
+		api.api.GetItem,
+		func(output *dynamodb.GetItemOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) BatchGetItem(
+	ctx context.Context,
+	input *dynamodb.BatchGetItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.BatchGetItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationBatchGetItem, getMapTables(input.RequestItems)...),
+		input,
+		api.api.BatchGetItem,
+		func(output *dynamodb.BatchGetItemOutput) []types.ConsumedCapacity {
+			return output.ConsumedCapacity
+		},
+		options)
+}
+
+func (api interceptedAPI) Query(
+	ctx context.Context,
+	input *dynamodb.QueryInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.QueryOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationQuery, input.TableName),
+		input,
+		api.api.Query,
+		func(output *dynamodb.QueryOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) Scan(
+	ctx context.Context,
+	input *dynamodb.ScanInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.ScanOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationScan, input.TableName),
+		input,
+		api.api.Scan,
+		func(output *dynamodb.ScanOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) PutItem(
+	ctx context.Context,
+	input *dynamodb.PutItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.PutItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationPutItem, input.TableName),
+		input,
+		api.api.PutItem,
+		func(output *dynamodb.PutItemOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) UpdateItem(
+	ctx context.Context,
+	input *dynamodb.UpdateItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.UpdateItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationUpdateItem, input.TableName),
+		input,
+		api.api.UpdateItem,
+		func(output *dynamodb.UpdateItemOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) DeleteItem(
+	ctx context.Context,
+	input *dynamodb.DeleteItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.DeleteItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationDeleteItem, input.TableName),
+		input,
+		api.api.DeleteItem,
+		func(output *dynamodb.DeleteItemOutput) []types.ConsumedCapacity {
+			return newConsumedCapacity(output.ConsumedCapacity)
+		},
+		options)
+}
+
+func (api interceptedAPI) BatchWriteItem(
+	ctx context.Context,
+	input *dynamodb.BatchWriteItemInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.BatchWriteItemOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	return intercept(
+		api,
+		ctx,
+		newOperation(
+			OperationBatchWriteItem,
+			getMapTables(input.RequestItems)...),
+		input,
+		api.api.BatchWriteItem,
+		func(output *dynamodb.BatchWriteItemOutput) []types.ConsumedCapacity {
+			return output.ConsumedCapacity
+		},
+		options)
+}
+
+func (api interceptedAPI) TransactWriteItems(
+	ctx context.Context,
+	input *dynamodb.TransactWriteItemsInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	tables := make([]*string, 0, len(input.TransactItems))
+	for _, item := range input.TransactItems {
+		switch {
+		case item.Put != nil:
+			tables = append(tables, item.Put.TableName)
+		case item.Update != nil:
+			tables = append(tables, item.Update.TableName)
+		case item.Delete != nil:
+			tables = append(tables, item.Delete.TableName)
+		case item.ConditionCheck != nil:
+			tables = append(tables, item.ConditionCheck.TableName)
+		}
+	}
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationTransactWriteItems, tables...),
+		input,
+		api.api.TransactWriteItems,
+		func(output *dynamodb.TransactWriteItemsOutput) []types.ConsumedCapacity {
+			return output.ConsumedCapacity
+		},
+		options)
+}
+
+func (api interceptedAPI) TransactGetItems(
+	ctx context.Context,
+	input *dynamodb.TransactGetItemsInput,
+	options ...func(*dynamodb.Options),
+) (*dynamodb.TransactGetItemsOutput, error) {
+	input = copyInput(input)
+	requestConsumedCapacity(&input.ReturnConsumedCapacity)
+	tables := make([]*string, 0, len(input.TransactItems))
+	for _, item := range input.TransactItems {
+		if item.Get != nil {
+			tables = append(tables, item.Get.TableName)
+		}
+	}
+	return intercept(
+		api,
+		ctx,
+		newOperation(OperationTransactGetItems, tables...),
+		input,
+		api.api.TransactGetItems,
+		func(output *dynamodb.TransactGetItemsOutput) []types.ConsumedCapacity {
+			return output.ConsumedCapacity
+		},
+		options)
+}
+
+////////////////////////////////////////////////////////////////////////////////
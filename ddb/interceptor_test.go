@@ -0,0 +1,76 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Interceptor_Chain(test *testing.T) {
+	assert := assert.New(test)
+	_, db := newTestClient(test)
+
+	var operations []ddb.Operation
+	var errs []error
+	fault := errors.New("fault")
+	isFaultInjected := false
+	client := ddbtest.NewClient(
+		db,
+		func(
+			ctx context.Context,
+			operation *ddb.Operation,
+			next func(context.Context) error,
+		) error {
+			err := next(ctx)
+			operations = append(operations, *operation)
+			errs = append(errs, err)
+			return err
+		},
+		func(
+			ctx context.Context,
+			operation *ddb.Operation,
+			next func(context.Context) error,
+		) error {
+			if isFaultInjected {
+				return fault
+			}
+			return next(ctx)
+		})
+
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+	assert.True(client.Find(&testBuffer{testKey: newTestKey("1")}).Request())
+	trans := ddb.NewWriteTrans(false)
+	trans.Update(newTestKey("1"), "set val = :v").Value(":v", 1)
+	assert.True(client.Write(trans).IsSuccess())
+
+	isFaultInjected = true
+	_, err := client.Find(&testBuffer{testKey: newTestKey("1")}).RequestE()
+	assert.ErrorIs(err, fault)
+
+	assert.Len(operations, 4)
+	for i, kind := range []ddb.OperationKind{
+		ddb.OperationPutItem,
+		ddb.OperationGetItem,
+		ddb.OperationTransactWriteItems,
+		ddb.OperationGetItem,
+	} {
+		assert.Equal(kind, operations[i].Kind)
+		assert.Equal([]string{"test_Test"}, operations[i].Tables)
+	}
+	assert.NotNil(operations[0].Input)
+	assert.NotNil(operations[0].Output)
+	assert.NoError(errs[0])
+	assert.Nil(operations[3].Output)
+	assert.ErrorIs(errs[3], fault)
+}
+
+////////////////////////////////////////////////////////////////////////////////
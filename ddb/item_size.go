@@ -0,0 +1,120 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxItemSize is DynamoDB item size limit, including attribute names.
+const maxItemSize = 400 * 1024
+
+// itemSizeReportAttributes is the number of the largest attributes which are
+// named in the error if the item is too large.
+const itemSizeReportAttributes = 3
+
+// checkItemSize returns ErrItemTooLarge if the item is larger than DynamoDB
+// allows to write. The item is checked as it's written, after MarshalMap
+// compresses and encrypts tagged attributes.
+func checkItemSize(item map[string]types.AttributeValue) error {
+	type attribute struct {
+		name string
+		size int
+	}
+	attributes := make([]attribute, 0, len(item))
+	size := 0
+	for name, value := range item {
+		attributes = append(
+			attributes,
+			attribute{name: name, size: len(name) + getAttributeSize(value)})
+		size += attributes[len(attributes)-1].size
+	}
+	if size <= maxItemSize {
+		return nil
+	}
+
+	sort.Slice(attributes, func(i, j int) bool {
+		return attributes[i].size > attributes[j].size
+	})
+	if len(attributes) > itemSizeReportAttributes {
+		attributes = attributes[:itemSizeReportAttributes]
+	}
+	largest := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		largest[i] = fmt.Sprintf("%q (%d bytes)", attribute.name, attribute.size)
+	}
+	return Error{
+		kind: ErrItemTooLarge,
+		err: fmt.Errorf(
+			"item size %d bytes exceeds the limit %d bytes, "+
+				"the largest attributes: %s",
+			size,
+			maxItemSize,
+			strings.Join(largest, ", ")),
+	}
+}
+
+// getAttributeSize returns the attribute value size as DynamoDB calculates
+// it for the item size limit.
+func getAttributeSize(source types.AttributeValue) int {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
+		return len(value.Value)
+	case *types.AttributeValueMemberN:
+		return getNumberAttributeSize(value.Value)
+	case *types.AttributeValueMemberB:
+		return len(value.Value)
+	case *types.AttributeValueMemberBOOL, *types.AttributeValueMemberNULL:
+		return 1
+	case *types.AttributeValueMemberL:
+		result := 3
+		for _, member := range value.Value {
+			result += 1 + getAttributeSize(member)
+		}
+		return result
+	case *types.AttributeValueMemberM:
+		result := 3
+		for name, member := range value.Value {
+			result += 1 + len(name) + getAttributeSize(member)
+		}
+		return result
+	case *types.AttributeValueMemberSS:
+		result := 0
+		for _, member := range value.Value {
+			result += len(member)
+		}
+		return result
+	case *types.AttributeValueMemberNS:
+		result := 0
+		for _, member := range value.Value {
+			result += getNumberAttributeSize(member)
+		}
+		return result
+	case *types.AttributeValueMemberBS:
+		result := 0
+		for _, member := range value.Value {
+			result += len(member)
+		}
+		return result
+	}
+	return 0
+}
+
+// getNumberAttributeSize returns the number size, DynamoDB stores numbers
+// with up to 38 significant digits, two digits in one byte, plus one byte.
+func getNumberAttributeSize(source string) int {
+	digits := 0
+	for _, char := range strings.TrimLeft(source, "-+0.") {
+		if char >= '0' && char <= '9' {
+			digits++
+		} else if char == 'e' || char == 'E' {
+			break
+		}
+	}
+	return (digits+1)/2 + 1
+}
@@ -0,0 +1,67 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DDB_ItemSize_NumberAttributeSize(test *testing.T) {
+	assert := assert.New(test)
+	for source, size := range map[string]int{
+		"0":          1,
+		"1":          2,
+		"12":         2,
+		"123":        3,
+		"-123":       3,
+		"+123":       3,
+		"0.001":      2,
+		"-0.0012":    2,
+		"12.5":       3,
+		"1.5e10":     2,
+		"1.5E-10":    2,
+		"1234567890": 6,
+	} {
+		assert.Equal(size, getNumberAttributeSize(source), source)
+	}
+}
+
+func Test_DDB_ItemSize_AttributeSize(test *testing.T) {
+	assert := assert.New(test)
+	for i, testCase := range []struct {
+		source types.AttributeValue
+		size   int
+	}{
+		{&types.AttributeValueMemberS{Value: "abc"}, 3},
+		{&types.AttributeValueMemberN{Value: "123"}, 3},
+		{&types.AttributeValueMemberB{Value: []byte{1, 2}}, 2},
+		{&types.AttributeValueMemberBOOL{}, 1},
+		{&types.AttributeValueMemberNULL{}, 1},
+		{
+			&types.AttributeValueMemberL{
+				Value: []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "a"},
+					&types.AttributeValueMemberNULL{},
+				},
+			},
+			3 + 1 + 1 + 1 + 1,
+		},
+		{
+			&types.AttributeValueMemberM{
+				Value: map[string]types.AttributeValue{
+					"ab": &types.AttributeValueMemberN{Value: "5"},
+				},
+			},
+			3 + 1 + 2 + 2,
+		},
+		{&types.AttributeValueMemberSS{Value: []string{"a", "bc"}}, 3},
+		{&types.AttributeValueMemberNS{Value: []string{"1", "123"}}, 5},
+		{&types.AttributeValueMemberBS{Value: [][]byte{{1}, {2, 3}}}, 3},
+	} {
+		assert.Equal(testCase.size, getAttributeSize(testCase.source), i)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testTextData struct {
+	testData
+	Text       string `json:"text"`
+	Compressed string `json:"compressed" ddb:"compress,encrypt"`
+}
+
+func (record testTextData) GetData() interface{} { return record }
+
+type testTextBuffer struct {
+	testBuffer
+	Text       string `json:"text"`
+	Compressed string `json:"compressed" ddb:"compress,encrypt"`
+}
+
+func (record *testTextBuffer) Clear() { *record = testTextBuffer{} }
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_ItemSize_Limit(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	text := strings.Repeat("abcdefgh", 64*1024)
+
+	{
+		_, err := client.
+			CreateOrReplace(testTextData{
+				testData: newTestData("1", "a", 10),
+				Text:     text,
+			}).
+			RequestE()
+		assert.ErrorIs(err, ddb.ErrItemTooLarge)
+		assert.Contains(err.Error(), `"text" (524292 bytes)`)
+	}
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.CreateOrReplace(testTextData{
+			testData: newTestData("1", "a", 10),
+			Text:     text,
+		})
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrItemTooLarge)
+	}
+	assert.Equal(0, db.GetSize(testRecord{}))
+
+	// The size is checked after compression and encryption, so the same text
+	// fits into the compressed and encrypted attribute.
+	assert.True(
+		client.
+			CreateOrReplace(testTextData{
+				testData:   newTestData("1", "a", 10),
+				Compressed: text,
+			}).
+			Request().
+			IsSuccess())
+	record := testTextBuffer{testBuffer: testBuffer{testKey: newTestKey("1")}}
+	assert.True(client.Find(&record).Request())
+	assert.Equal(text, record.Compressed)
+	assert.Equal("a", record.User)
+}
+
+////////////////////////////////////////////////////////////////////////////////
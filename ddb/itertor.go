@@ -4,10 +4,10 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	awsrequest "github.com/aws/aws-sdk-go/aws/request"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -22,11 +22,26 @@ type IteratorMover interface {
 type Iterator interface {
 	IteratorMover
 	Get() RecordBuffer
+	// Cursor returns an opaque pagination token to continue reading after
+	// the current record by another request, see Query.StartFrom. The cursor
+	// is signed by the service key, so it could be given to clients. Empty
+	// cursor means that there are no more records.
+	Cursor() string
+}
+
+// IteratorE describes intreface to read paged data from the database,
+// which returns typed error instead of panic.
+type IteratorE interface {
+	NextE() (bool, error)
+	Get() RecordBuffer
+	// CursorE returns the same as Iterator.Cursor, or typed error.
+	CursorE() (string, error)
 }
 
 // CacheIterator describes intreface to read query or scan cached result.
 type CacheIterator interface {
-	Iterator
+	IteratorMover
+	Get() RecordBuffer
 	GetSize() int
 	GetAt(index int) RecordBuffer
 }
@@ -34,19 +49,19 @@ type CacheIterator interface {
 ////////////////////////////////////////////////////////////////////////////////
 
 func newCacheIterator(
-	data []map[string]*dynamodb.AttributeValue,
+	data []map[string]types.AttributeValue,
 	record RecordBuffer,
 ) *cacheIterator {
 	return &cacheIterator{data: data, record: record}
 }
 
 type cacheIterator struct {
-	data   []map[string]*dynamodb.AttributeValue
+	data   []map[string]types.AttributeValue
 	record RecordBuffer
 	pos    int
 }
 
-func (it *cacheIterator) Set(data []map[string]*dynamodb.AttributeValue) {
+func (it *cacheIterator) Set(data []map[string]types.AttributeValue) {
 	it.data = data
 }
 
@@ -72,7 +87,7 @@ func (it cacheIterator) GetAt(index int) RecordBuffer {
 
 func (it *cacheIterator) readAt(index int) {
 	it.record.Clear()
-	err := dynamodbattribute.UnmarshalMap(it.data[index], it.record)
+	err := UnmarshalMap(it.data[index], it.record)
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -88,45 +103,188 @@ func (it *cacheIterator) readAt(index int) {
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// pageReader reads pages of one query or one scan segment.
+type pageReader interface {
+	// readPage reads the next page and returns the key to read the next page,
+	// or nil if the page is the last.
+	readPage(ctx context.Context) (
+		[]map[string]types.AttributeValue,
+		map[string]types.AttributeValue,
+		error,
+	)
+}
+
+type page struct {
+	items   []map[string]types.AttributeValue
+	lastKey map[string]types.AttributeValue
+}
+
+// newPagedIterator creates paged iterator, cursor source is nil if cursors are
+// not supported.
 func newPagedIterator(
-	request *awsrequest.Request,
+	client *client,
+	readers []pageReader,
 	record RecordBuffer,
-) Iterator {
-	return &pagedIterator{
-		// example has been taken from func (DynamoDB) QueryPagesWithContext:
-		paginator: awsrequest.Pagination{
-			NewRequest: func() (*awsrequest.Request, error) {
-				request.SetContext(aws.BackgroundContext())
-				return request, nil
-			},
-		},
-		cache: newCacheIterator([]map[string]*dynamodb.AttributeValue{}, record),
+	cursor *cursorSource,
+	err error,
+) *pagedIterator {
+	result := &pagedIterator{
+		client:  client,
+		readers: readers,
+		cache:   newCacheIterator([]map[string]types.AttributeValue{}, record),
+		cursor:  cursor,
+		err:     err,
+	}
+	if cursor != nil {
+		result.cacheLastKey = cursor.startKey
 	}
+	return result
 }
 
+// pagedIterator reads pages by many readers, if there are several readers,
+// the next page of each reader is read concurrently.
 type pagedIterator struct {
-	paginator awsrequest.Pagination
-	cache     CacheIterator
+	client  *client
+	readers []pageReader
+	pages   []page
+	cache   *cacheIterator
+	// cacheLastKey is the key to read the page after the cached page.
+	cacheLastKey map[string]types.AttributeValue
+	cursor       *cursorSource
+	isEnd        bool
+	err          error
 }
 
 func (it pagedIterator) Get() RecordBuffer { return it.cache.Get() }
 
+func (it *pagedIterator) Cursor() string {
+	result, err := it.CursorE()
+	if err != nil {
+		ss.S.Log().Panic(ss.NewLogMsg(`failed to create cursor`).AddErr(err))
+	}
+	return result
+}
+
+func (it *pagedIterator) CursorE() (string, error) {
+	if it.err != nil {
+		return "", it.err
+	}
+	if it.cursor == nil {
+		return "", Error{
+			kind: ErrValidation,
+			err:  errors.New("cursor is not supported for parallel reading"),
+		}
+	}
+	if it.isEnd {
+		return "", nil
+	}
+	if it.cache.pos < len(it.cache.data) {
+		return it.cursor.newItemCursor(it.cache.data[it.cache.pos-1])
+	}
+	// The current record is the last record of the page, or nothing is read
+	// yet, so the next page starts by the page key.
+	if len(it.cacheLastKey) == 0 && len(it.readers) == 0 && len(it.pages) == 0 {
+		return "", nil
+	}
+	return it.cursor.newCursor(it.cacheLastKey)
+}
+
 func (it *pagedIterator) Next() bool {
-	if it.cache.Next() {
-		return true
-	}
-	// example has been taken from func (DynamoDB) QueryPagesWithContext:
-	if !it.paginator.Next() {
-		if err := it.paginator.Err(); err != nil {
-			ss.S.Log().Panic(
-				ss.NewLogMsg(`failed to request next page`).AddErr(err))
+	result, err := it.NextE()
+	if err != nil {
+		ss.S.Log().Panic(
+			ss.NewLogMsg(`failed to request next page`).AddErr(err))
+	}
+	return result
+}
+
+func (it *pagedIterator) NextE() (bool, error) {
+	if it.err != nil {
+		return false, it.err
+	}
+	for !it.cache.Next() {
+		if len(it.pages) == 0 {
+			if len(it.readers) == 0 {
+				it.isEnd = true
+				return false, nil
+			}
+			if err := it.readPages(); err != nil {
+				// The next call returns the same error as the iterator
+				// could not continue.
+				it.err = err
+				return false, it.err
+			}
+			// The page could be empty, but with the key to continue, so the loop
+			// continues until the last page.
+			continue
+		}
+		it.cache = newCacheIterator(it.pages[0].items, it.cache.Get())
+		it.cacheLastKey = it.pages[0].lastKey
+		it.pages = it.pages[1:]
+	}
+	return true, nil
+}
+
+// readAll reads all not yet read pages.
+func (it *pagedIterator) readAll() (
+	[]map[string]types.AttributeValue,
+	error,
+) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	result := []map[string]types.AttributeValue{}
+	for len(it.pages) != 0 || len(it.readers) != 0 {
+		for _, page := range it.pages {
+			result = append(result, page.items...)
+		}
+		it.pages = nil
+		if len(it.readers) == 0 {
+			break
+		}
+		if err := it.readPages(); err != nil {
+			it.err = err
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// readPages reads the next page of each reader, and removes readers which
+// have read the last page.
+func (it *pagedIterator) readPages() error {
+	ctx, cancel := it.client.newRequestContext()
+	defer cancel()
+
+	pages := make([]page, len(it.readers))
+	errs := make([]error, len(it.readers))
+	read := func(i int) {
+		pages[i].items, pages[i].lastKey, errs[i] = it.readers[i].readPage(ctx)
+	}
+	if len(it.readers) == 1 {
+		read(0)
+	} else {
+		runInPool(len(it.readers), read)
+	}
+
+	readers := make([]pageReader, 0, len(it.readers))
+	for i, reader := range it.readers {
+		if errs[i] != nil {
+			return newError(errs[i])
+		}
+		if len(pages[i].items) != 0 {
+			it.pages = append(it.pages, pages[i])
+		} else if len(it.pages) == 0 {
+			// The empty page is skipped, so the page key has to be kept
+			// for the cursor.
+			it.cacheLastKey = pages[i].lastKey
+		}
+		if len(pages[i].lastKey) != 0 {
+			readers = append(readers, reader)
 		}
-		return false
 	}
-	it.cache = newCacheIterator(
-		it.paginator.Page().(*dynamodb.QueryOutput).Items,
-		it.cache.Get())
-	return it.cache.Next()
+	it.readers = readers
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
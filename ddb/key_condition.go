@@ -0,0 +1,176 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"errors"
+	"time"
+
+	"github.com/palchukovsky/ss"
+)
+
+// SortCondition is a condition for the sort key of the query, the sort field
+// name is taken from the record or index description.
+type SortCondition struct {
+	build func(field string) Condition
+}
+
+func newSortCondition(build func(Operand) Condition) SortCondition {
+	return SortCondition{build: func(field string) Condition {
+		return build(Name(field))
+	}}
+}
+
+// SortEqual creates condition for the sort key equal to the value.
+func SortEqual(value interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.Equal(Value(value))
+	})
+}
+
+// SortLessThan creates condition for the sort key less than the value.
+func SortLessThan(value interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.Less(Value(value))
+	})
+}
+
+// SortLessOrEqual creates condition for the sort key less than or equal
+// to the value.
+func SortLessOrEqual(value interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.LessOrEqual(Value(value))
+	})
+}
+
+// SortGreaterThan creates condition for the sort key greater than
+// the value.
+func SortGreaterThan(value interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.Greater(Value(value))
+	})
+}
+
+// SortGreaterOrEqual creates condition for the sort key greater than or
+// equal to the value.
+func SortGreaterOrEqual(value interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.GreaterOrEqual(Value(value))
+	})
+}
+
+// SortBetween creates condition for the sort key between low and high
+// including both.
+func SortBetween(low, high interface{}) SortCondition {
+	return newSortCondition(func(field Operand) Condition {
+		return field.Between(Value(low), Value(high))
+	})
+}
+
+// SortBeginsWith creates condition for the string sort key with the prefix.
+func SortBeginsWith(prefix string) SortCondition {
+	return SortCondition{build: func(field string) Condition {
+		return BeginsWith(field, Value(prefix))
+	}}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SortDays creates condition for the ss.DateOrTime sort key in the days
+// from the first to the last including both, dates only and times are
+// selected. ss.DateOrTime stores the date only before all times of the same
+// day, so the range is from the date only of the first day to the last
+// second of the last day. Values of ss.DateOrTime could be used by other
+// Sort* conditions as is.
+func SortDays(first, last ss.Date) SortCondition {
+	return SortBetween(
+		ss.NewDateOrTime(ss.NewTimeFromDate(first), true),
+		ss.NewDateOrTime(
+			ss.NewTimeFromDate(last).AddDate(0, 0, 1).Add(-time.Second),
+			false))
+}
+
+// SortDay creates condition for the ss.DateOrTime sort key in the day, date
+// only and times are selected.
+func SortDay(day ss.Date) SortCondition { return SortDays(day, day) }
+
+// SortDateOnly creates condition for the ss.DateOrTime sort key with
+// the date only, times of the day are not selected.
+func SortDateOnly(day ss.Date) SortCondition {
+	return SortEqual(ss.NewDateOrTime(ss.NewTimeFromDate(day), true))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// newKeyCondition creates key condition by the partition key value and by
+// the sort key condition if it's set.
+func newKeyCondition(
+	partitionField string,
+	partition interface{},
+	sortField string,
+	sort *SortCondition,
+) (Condition, error) {
+	result := Name(partitionField).Equal(Value(partition))
+	if sort == nil {
+		return result, nil
+	}
+	if sortField == "" {
+		return Condition{}, Error{
+			kind: ErrValidation,
+			err: errors.New(
+				"sort key condition is set, but there is no sort key"),
+		}
+	}
+	return And(result, sort.build(sortField)), nil
+}
+
+func newQueryByKey(
+	client *client,
+	record RecordBuffer,
+	partitionField string,
+	partition interface{},
+	sortField string,
+	sort *SortCondition,
+) *query {
+	keyCondition, err := newKeyCondition(
+		partitionField,
+		partition,
+		sortField,
+		sort)
+	if err != nil {
+		result := newQueryTemplate(client, record)
+		result.err = err
+		return result
+	}
+	return newQueryExpr(client, record, keyCondition)
+}
+
+func (client *client) QueryByPartition(
+	record RecordBuffer,
+	partition interface{},
+) Query {
+	return newQueryByKey(
+		client,
+		record,
+		record.GetKeyPartitionField(),
+		partition,
+		record.GetKeySortField(),
+		nil)
+}
+
+func (client *client) QueryByPartitionAndSort(
+	record RecordBuffer,
+	partition interface{},
+	sort SortCondition,
+) Query {
+	return newQueryByKey(
+		client,
+		record,
+		record.GetKeyPartitionField(),
+		partition,
+		record.GetKeySortField(),
+		&sort)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,116 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testEventRecord struct{}
+
+func (testEventRecord) GetTable() string             { return "Event" }
+func (testEventRecord) GetKeyPartitionField() string { return "user" }
+func (testEventRecord) GetKeySortField() string      { return "at" }
+
+type testEvent struct {
+	testEventRecord
+	User string        `json:"user"`
+	At   ss.DateOrTime `json:"at"`
+}
+
+func (record testEvent) GetData() interface{} { return record }
+func (record *testEvent) Clear()              { *record = testEvent{} }
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_KeyCondition_Query(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+	db.CreateTable(testEventRecord{})
+
+	for _, record := range []testData{
+		newTestData("1", "a", 30),
+		newTestData("2", "b", 20),
+		newTestData("3", "a", 10),
+		newTestData("4", "a", 20),
+	} {
+		client.CreateOrReplace(record).Request()
+	}
+
+	var record testBuffer
+	assert.True(client.QueryByPartition(&record, "2").RequestOne())
+	assert.Equal("b", record.User)
+	{
+		_, err := client.
+			QueryByPartitionAndSort(&record, "2", ddb.SortEqual(20)).
+			RequestAllE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+
+	var index testUserIndex
+	assert.Equal(
+		3,
+		client.Index(&index).QueryByPartition("a").RequestAll().GetSize())
+	all := client.
+		Index(&index).
+		QueryByPartitionAndSort("a", ddb.SortBetween(10, 25)).
+		RequestAll()
+	assert.Equal(2, all.GetSize())
+	all = client.
+		Index(&index).
+		QueryByPartitionAndSort("a", ddb.SortGreaterThan(20)).
+		RequestAll()
+	assert.Equal(1, all.GetSize())
+	assert.Equal("1", all.GetAt(0).(*testUserIndex).ID)
+
+	day := ss.NewDate(2022, time.March, 10)
+	dayStart := ss.NewTimeFromDate(day)
+	for _, at := range []ss.DateOrTime{
+		ss.NewDateOrTime(dayStart.AddDate(0, 0, -1), true),
+		ss.NewDateOrTime(dayStart.Add(-time.Second), false),
+		ss.NewDateOrTime(dayStart, true),
+		ss.NewDateOrTime(dayStart, false),
+		ss.NewDateOrTime(dayStart.Add(23*time.Hour+59*time.Minute), false),
+		ss.NewDateOrTime(dayStart.AddDate(0, 0, 1), true),
+		ss.NewDateOrTime(dayStart.AddDate(0, 0, 1), false),
+	} {
+		client.CreateOrReplace(testEvent{User: "a", At: at}).Request()
+	}
+
+	var event testEvent
+	events := ddb.NewTable[testEvent](client)
+	assert.Equal(
+		3,
+		len(events.QueryByPartitionAndSort("a", ddb.SortDay(day)).RequestAll()))
+	assert.Equal(
+		5,
+		len(
+			events.
+				QueryByPartitionAndSort(
+					"a",
+					ddb.SortDays(day, ss.NewDate(2022, time.March, 11))).
+				RequestAll()))
+	assert.True(
+		client.
+			QueryByPartitionAndSort(&event, "a", ddb.SortDateOnly(day)).
+			RequestOne())
+	assert.True(event.At.IsDateOnly)
+	assert.True(event.At.Value.Equal(dayStart))
+	all = client.
+		QueryByPartitionAndSort(
+			&event,
+			"a",
+			ddb.SortGreaterOrEqual(ss.NewDateOrTime(dayStart, false))).
+		RequestAll()
+	assert.Equal(4, all.GetSize())
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,58 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"sync"
+
+	"github.com/palchukovsky/ss"
+)
+
+// lambdaScope has the context of the current lambda invocation, which is
+// canceled when the lambda is about to reach its timeout or is completed.
+// Request contexts are derived from it, so requests don't subscribe for
+// the lambda timeout by themselves.
+var lambdaScope struct {
+	once  sync.Once
+	mutex sync.RWMutex
+	// ctx is nil out of the lambda invocation, so requests before the first
+	// invocation, and deferred or background requests after the invocation,
+	// are not limited by the lambda timeout.
+	ctx context.Context
+}
+
+// getLambdaContext returns the context of the current lambda invocation, or
+// nil if there is no invocation.
+func getLambdaContext() context.Context {
+	lambdaScope.once.Do(func() {
+		ss.S.AddLambdaScopeHandler(resetLambdaScope)
+	})
+	lambdaScope.mutex.RLock()
+	defer lambdaScope.mutex.RUnlock()
+	return lambdaScope.ctx
+}
+
+// resetLambdaScope is called when the lambda starts and when it completes.
+func resetLambdaScope() {
+	var ctx context.Context
+	timeout := ss.S.SubscribeForLambdaTimeout()
+	select {
+	case <-timeout:
+		// The subscription is already signaled, so the lambda is completed.
+	default:
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(context.Background())
+		// The subscription is signaled by the timeout or by the lambda
+		// completion, so the goroutine lives only while the invocation.
+		go func() {
+			<-timeout
+			cancel()
+		}()
+	}
+
+	lambdaScope.mutex.Lock()
+	defer lambdaScope.mutex.Unlock()
+	lambdaScope.ctx = ctx
+}
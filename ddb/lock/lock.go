@@ -0,0 +1,275 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+)
+
+var (
+	// ErrLocked is returned when the lock is held by another owner until
+	// the acquiring context is done.
+	ErrLocked = errors.New("locked")
+	// ErrLost is returned when the lease is expired and could be taken by
+	// another owner.
+	ErrLost = errors.New("lease lost")
+)
+
+// Locker acquires leases of named locks by the lock table. The lease lives
+// the given time, and is renewed while the lease is not released, so the lock
+// of the crashed owner is released when the lease time is expired. Expired
+// records are deleted by the table TTL.
+type Locker struct{ client ddb.Client }
+
+// NewLocker creates locker which works through the given client.
+func NewLocker(client ddb.Client) Locker { return Locker{client: client} }
+
+// Acquire waits until the lock is free and takes it for the given time,
+// returns ErrLocked if the lock is not taken until the context is done.
+// The lease time is at least one second, and the lease expiration is rounded
+// up to seconds, as the expiration is stored in seconds.
+func (locker Locker) Acquire(
+	ctx context.Context,
+	name string,
+	ttl time.Duration,
+) (*Lease, error) {
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	client := locker.client.WithContext(ctx)
+	for {
+		lease, retryAfter, err := locker.try(client, name, ttl)
+		if err != nil || lease != nil {
+			return lease, err
+		}
+		timer := time.NewTimer(retryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("%w: %q: %v", ErrLocked, name, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// pollInterval is the max time to wait before the next try to take the lock.
+const pollInterval = 250 * time.Millisecond
+
+// newRetryDelay returns the time to wait before the next try after the lock
+// is taken by another owner, with jitter, so owners don't try at once.
+func newRetryDelay() time.Duration {
+	return pollInterval/2 + time.Duration(rand.Int63n(int64(pollInterval/2)))
+}
+
+// newExpiration returns the lease expiration rounded up to seconds, so
+// the stored expiration is not earlier than the lease time.
+func newExpiration(now ss.Time, ttl time.Duration) ss.Time {
+	result := now.Get().Add(ttl)
+	if truncated := result.Truncate(time.Second); truncated.Before(result) {
+		result = truncated.Add(time.Second)
+	}
+	return ss.NewTime(result)
+}
+
+// try takes the lock, or returns the time to wait before the next try.
+func (locker Locker) try(
+	client ddb.Client,
+	name string,
+	ttl time.Duration,
+) (*Lease, time.Duration, error) {
+	current := Record{KeyValue: newKeyValue(name)}
+	isFound, err := client.Find(&current).RequestE()
+	if err != nil {
+		return nil, 0, err
+	}
+	// The time is compared in seconds, as it's stored in seconds.
+	now := ss.NewTime(ss.Now().Get().Truncate(time.Second))
+	if isFound && !current.Expiration.Before(now) {
+		retryAfter := current.Expiration.Get().Sub(now.Get()) + time.Second
+		if retryAfter > pollInterval {
+			retryAfter = pollInterval
+		}
+		return nil, retryAfter, nil
+	}
+
+	// The fencing token is taken from the sequence, as the lock record is
+	// deleted by release and TTL.
+	trans := ddb.NewWriteTrans(true)
+	token, err := ddb.NewSequence("lock."+name, 1).NextInTransE(client, trans)
+	if err != nil {
+		return nil, 0, err
+	}
+	record := newRecord(name, token, newExpiration(ss.Now(), ttl))
+	if isFound {
+		trans.
+			CreateOrReplace(record).
+			ConditionExpr(
+				ddb.Name(expirationField).Less(ddb.Value(now)))
+	} else {
+		trans.CreateIfNotExists(record)
+	}
+	result, err := client.WriteE(trans)
+	if err != nil {
+		if errors.Is(err, ddb.ErrTransactionConflict) {
+			return nil, newRetryDelay(), nil
+		}
+		return nil, 0, err
+	}
+	if !result.IsSuccess() {
+		// The lock or the token is taken by another owner, the lock has
+		// to be checked again.
+		return nil, newRetryDelay(), nil
+	}
+	return newLease(locker.client, record, ttl), 0, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Lease is the taken lock, which is renewed until it's released, lost,
+// or until the lambda is about to reach its timeout.
+type Lease struct {
+	ss.NoCopyImpl
+
+	client ddb.Client
+	key    key
+	token  uint64
+	ttl    time.Duration
+
+	mutex      sync.Mutex
+	expiration ss.Time
+
+	stop    chan struct{}
+	stopped chan struct{}
+	lost    chan struct{}
+
+	release    sync.Once
+	releaseErr error
+}
+
+func newLease(client ddb.Client, record Record, ttl time.Duration) *Lease {
+	result := Lease{
+		client:     client,
+		key:        newKey(record.Name),
+		token:      record.Token,
+		ttl:        ttl,
+		expiration: record.Expiration,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+		lost:       make(chan struct{}),
+	}
+	go result.renew(ss.S.SubscribeForLambdaTimeout())
+	return &result
+}
+
+// Token returns fencing token, which is greater for each next lease of
+// the lock, so the resource could reject writes of the stale owner.
+func (lease *Lease) Token() uint64 { return lease.token }
+
+// Lost returns the channel which is closed when the lease is not renewed,
+// so the lock could be taken by another owner.
+func (lease *Lease) Lost() <-chan struct{} { return lease.lost }
+
+// Release stops the lease renewal and frees the lock.
+func (lease *Lease) Release() {
+	if err := lease.ReleaseE(); err != nil && !errors.Is(err, ErrLost) {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg("failed to release lock %q", lease.key.Name).
+				AddErr(err))
+	}
+}
+
+// ReleaseE stops the lease renewal and frees the lock, returns ErrLost if
+// the lock is already taken by another owner, or typed error instead of
+// panic. The lease is released only once, next calls return the result of
+// the first call.
+func (lease *Lease) ReleaseE() error {
+	lease.release.Do(func() { lease.releaseErr = lease.releaseOnce() })
+	return lease.releaseErr
+}
+
+func (lease *Lease) releaseOnce() error {
+	close(lease.stop)
+	<-lease.stopped
+
+	request := lease.client.
+		DeleteIfExisting(lease.key).
+		ConditionExpr(ddb.Name(tokenField).Equal(ddb.Value(lease.token)))
+	request.AllowConditionalCheckFail()
+	result, err := request.RequestE()
+	if err != nil {
+		return err
+	}
+	if !result.IsSuccess() {
+		return fmt.Errorf("%w: %q", ErrLost, lease.key.Name)
+	}
+	return nil
+}
+
+func (lease *Lease) renew(timeout <-chan struct{}) {
+	defer close(lease.stopped)
+
+	ticker := time.NewTicker(lease.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lease.stop:
+			return
+		case <-timeout:
+			// The lambda will be stopped, so the lease is not renewed anymore
+			// and will be expired.
+			close(lease.lost)
+			return
+		case <-ticker.C:
+			if err := lease.extend(); err != nil {
+				ss.S.Log().Warn(
+					ss.
+						NewLogMsg("failed to renew lock %q", lease.key.Name).
+						AddErr(err))
+				if errors.Is(err, ErrLost) || lease.isExpired() {
+					close(lease.lost)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (lease *Lease) extend() error {
+	expiration := newExpiration(ss.Now(), lease.ttl)
+	update := lease.client.
+		Update(lease.key).
+		Apply(ddb.Set(expirationField, expiration)).
+		ConditionExpr(ddb.Name(tokenField).Equal(ddb.Value(lease.token)))
+	update.AllowConditionalCheckFail()
+	result, err := update.RequestE()
+	if err != nil {
+		return err
+	}
+	if !result.IsSuccess() {
+		return fmt.Errorf("%w: %q", ErrLost, lease.key.Name)
+	}
+
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	lease.expiration = expiration
+	return nil
+}
+
+func (lease *Lease) isExpired() bool {
+	lease.mutex.Lock()
+	defer lease.mutex.Unlock()
+	return !ss.Now().Before(lease.expiration)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,247 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddblock_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddblock "github.com/palchukovsky/ss/ddb/lock"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testLog accepts warnings of the lease renewal, other log methods are not
+// expected.
+type testLog struct{ ss.Log }
+
+func (testLog) Warn(*ss.LogMsg) {}
+
+func newTestClient(test *testing.T) (ddb.Client, *ddbtest.DB) {
+	ddbtest.
+		SetService(test, ss.ServiceConfig{}).
+		EXPECT().
+		Log().
+		AnyTimes().
+		Return(testLog{})
+
+	db := ddbtest.NewDB()
+	db.CreateTable(ddb.SequenceRecord{})
+	db.CreateTable(ddblock.Record{})
+	return ddbtest.NewClient(db), db
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDBLock_Lock_Acquire(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	locker := ddblock.NewLocker(client)
+	lease, err := locker.Acquire(context.Background(), "user", time.Hour)
+	assert.NoError(err)
+	assert.Equal(uint64(1), lease.Token())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(ctx, "user", time.Hour)
+	assert.ErrorIs(err, ddblock.ErrLocked)
+
+	other, err := locker.Acquire(context.Background(), "device", time.Hour)
+	assert.NoError(err)
+	assert.Equal(uint64(1), other.Token())
+	other.Release()
+
+	lease.Release()
+	lease, err = locker.Acquire(context.Background(), "user", time.Hour)
+	assert.NoError(err)
+	assert.Equal(uint64(2), lease.Token())
+
+	// Abandoned lock is taken when its lease is expired.
+	client.CreateOrReplace(ddblock.Record{
+		KeyValue:   ddblock.KeyValue{Name: "user"},
+		Token:      lease.Token(),
+		Expiration: ss.Now().Add(-time.Minute),
+	}).Request()
+	next, err := locker.Acquire(context.Background(), "user", time.Hour)
+	assert.NoError(err)
+	assert.Equal(uint64(3), next.Token())
+	assert.ErrorIs(lease.ReleaseE(), ddblock.ErrLost)
+	next.Release()
+}
+
+func Test_DDBLock_Lock_Contention(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	locker := ddblock.NewLocker(client)
+
+	const owners = 4
+	var holders int32
+	tokens := make(chan uint64, owners)
+	var wait sync.WaitGroup
+	for i := 0; i < owners; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			lease, err := locker.Acquire(context.Background(), "user", time.Hour)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(int32(1), atomic.AddInt32(&holders, 1))
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+			tokens <- lease.Token()
+			lease.Release()
+		}()
+	}
+	wait.Wait()
+	close(tokens)
+
+	result := []uint64{}
+	for token := range tokens {
+		result = append(result, token)
+	}
+	assert.ElementsMatch([]uint64{1, 2, 3, 4}, result)
+}
+
+func Test_DDBLock_Lock_ConditionFailDelay(test *testing.T) {
+	assert := assert.New(test)
+	_, db := newTestClient(test)
+
+	// Another owner takes the lock between the check and the write.
+	var transactions int32
+	client := ddbtest.NewClient(
+		db,
+		func(
+			ctx context.Context,
+			operation *ddb.Operation,
+			next func(context.Context) error,
+		) error {
+			if operation.Kind != ddb.OperationTransactWriteItems {
+				return next(ctx)
+			}
+			atomic.AddInt32(&transactions, 1)
+			return &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String("ConditionalCheckFailed")},
+				},
+			}
+		})
+
+	// Each failed try waits before the next one, so the lock table is not
+	// requested in a tight loop.
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		500*time.Millisecond)
+	defer cancel()
+	_, err := ddblock.NewLocker(client).Acquire(ctx, "user", time.Hour)
+	assert.ErrorIs(err, ddblock.ErrLocked)
+	assert.LessOrEqual(atomic.LoadInt32(&transactions), int32(5))
+}
+
+func Test_DDBLock_Lock_Expiration(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	// The expiration is stored in seconds, but it's not earlier than
+	// the lease time.
+	start := ss.Now()
+	lease, err := ddblock.
+		NewLocker(client).
+		Acquire(context.Background(), "user", time.Second)
+	assert.NoError(err)
+	defer lease.Release()
+
+	record := ddblock.Record{KeyValue: ddblock.KeyValue{Name: "user"}}
+	assert.True(client.Find(&record).Request())
+	assert.False(record.Expiration.Before(start.Add(time.Second)))
+}
+
+func Test_DDBLock_Lock_Renewal(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	locker := ddblock.NewLocker(client)
+
+	lease, err := locker.Acquire(context.Background(), "user", time.Second)
+	assert.NoError(err)
+	defer lease.Release()
+
+	// The lease lives longer than its time as it's renewed.
+	time.Sleep(2 * time.Second)
+	select {
+	case <-lease.Lost():
+		assert.Fail("lease is lost")
+	default:
+	}
+	record := ddblock.Record{KeyValue: ddblock.KeyValue{Name: "user"}}
+	assert.True(client.Find(&record).Request())
+	assert.Equal(lease.Token(), record.Token)
+	assert.False(record.Expiration.Before(ss.Now()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(ctx, "user", time.Second)
+	assert.ErrorIs(err, ddblock.ErrLocked)
+}
+
+func Test_DDBLock_Lock_Lost(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	locker := ddblock.NewLocker(client)
+
+	lease, err := locker.Acquire(context.Background(), "user", time.Second)
+	assert.NoError(err)
+
+	// Another owner takes the lock, so the lease renewal fails.
+	client.CreateOrReplace(ddblock.Record{
+		KeyValue:   ddblock.KeyValue{Name: "user"},
+		Token:      lease.Token() + 1,
+		Expiration: ss.Now().Add(time.Hour),
+	}).Request()
+	select {
+	case <-lease.Lost():
+	case <-time.After(3 * time.Second):
+		assert.Fail("lease is not lost")
+	}
+
+	assert.ErrorIs(lease.ReleaseE(), ddblock.ErrLost)
+	// The lock of another owner is not released.
+	record := ddblock.Record{KeyValue: ddblock.KeyValue{Name: "user"}}
+	assert.True(client.Find(&record).Request())
+	assert.Equal(lease.Token()+1, record.Token)
+}
+
+func Test_DDBLock_Lock_DoubleRelease(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	locker := ddblock.NewLocker(client)
+
+	lease, err := locker.Acquire(context.Background(), "user", time.Hour)
+	assert.NoError(err)
+	assert.NoError(lease.ReleaseE())
+	assert.NoError(lease.ReleaseE())
+	lease.Release()
+
+	// The second release doesn't free the lock of the next owner.
+	next, err := locker.Acquire(context.Background(), "user", time.Hour)
+	assert.NoError(err)
+	assert.NoError(lease.ReleaseE())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = locker.Acquire(ctx, "user", time.Hour)
+	assert.ErrorIs(err, ddblock.ErrLocked)
+	next.Release()
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,72 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddblock
+
+import "github.com/palchukovsky/ss"
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	tokenField      = "token"
+	expirationField = "expiration"
+)
+
+type record struct{}
+
+// GetTable returns table name.
+func (record) GetTable() string { return "Lock" }
+
+// GetKeyPartitionField returns partition field name.
+func (record) GetKeyPartitionField() string { return "id" }
+
+// GetKeySortField returns sort field name.
+func (record) GetKeySortField() string { return "" }
+
+////////////////////////////////////////////////////////////////////////////////
+
+type KeyValue struct {
+	Name string `json:"id"`
+}
+
+func newKeyValue(name string) KeyValue { return KeyValue{Name: name} }
+
+type key struct {
+	record
+	KeyValue
+}
+
+func newKey(name string) key { return key{KeyValue: newKeyValue(name)} }
+
+func (key key) GetKey() interface{} { return key.KeyValue }
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Record describes the record of the lock table, the table is created by
+// the database installer if the project installer implements
+// dbinstall.LockInstaller. The expiration is the table TTL attribute.
+type Record struct {
+	record
+	KeyValue
+	Token      uint64  `json:"token"`
+	Expiration ss.Time `json:"expiration"`
+}
+
+func newRecord(name string, token uint64, expiration ss.Time) Record {
+	return Record{
+		KeyValue:   newKeyValue(name),
+		Token:      token,
+		Expiration: expiration,
+	}
+}
+
+// GetKey returns record key.
+func (record Record) GetKey() interface{} { return record.KeyValue }
+
+// GetData returns record's data.
+func (record Record) GetData() interface{} { return record }
+
+// Clear resets the record to read the database response.
+func (record *Record) Clear() { *record = Record{} }
+
+////////////////////////////////////////////////////////////////////////////////
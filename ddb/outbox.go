@@ -0,0 +1,102 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"time"
+
+	"github.com/palchukovsky/ss"
+)
+
+// OutboxMessageKind is the way to deliver the outbox message.
+type OutboxMessageKind string
+
+const (
+	// OutboxMessageKindPush is a push message for all user devices.
+	OutboxMessageKindPush OutboxMessageKind = "push"
+	// OutboxMessageKindGateway is a message for the gateway connection.
+	OutboxMessageKindGateway OutboxMessageKind = "gateway"
+)
+
+// OutboxMessage is a message which is written in the outbox table by
+// the transaction, and which is sent by the outbox lambda after the
+// transaction is completed. Messages are created by push.NewOutboxMessage
+// and lambda.NewGatewayOutboxMessage.
+type OutboxMessage struct {
+	Kind OutboxMessageKind
+	// Recipient is the user ID for push, and the connection ID for gateway.
+	Recipient string
+	// Type is the push message type, it's not used by gateway.
+	Type string
+	// Data is the serialized message.
+	Data []byte
+}
+
+// outboxMessageTTL is the time to keep the message which could not be sent.
+const outboxMessageTTL = 24 * time.Hour
+
+func (trans *writeTrans) Outbox(message OutboxMessage) CreateTrans {
+	return trans.CreateOrReplace(NewOutboxRecord(message))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type outboxRecord struct{}
+
+// GetTable returns table name.
+func (outboxRecord) GetTable() string { return "Outbox" }
+
+// GetKeyPartitionField returns partition field name.
+func (outboxRecord) GetKeyPartitionField() string { return "id" }
+
+// GetKeySortField returns sort field name.
+func (outboxRecord) GetKeySortField() string { return "" }
+
+type OutboxKeyValue struct {
+	ID ss.EntityID `json:"id"`
+}
+
+// OutboxRecord describes the record of the outbox table, the table is
+// created by the database installer if the project installer implements
+// dbinstall.OutboxInstaller. The expiration is the table TTL attribute.
+// NextAttempt is the Unix time in milliseconds of the next sending attempt
+// after the failed one. Sent has FCM tokens of devices which already got
+// the push message, so the next attempt doesn't send it again.
+type OutboxRecord struct {
+	outboxRecord
+	OutboxKeyValue
+	Kind        OutboxMessageKind `json:"kind"`
+	Recipient   string            `json:"to"`
+	Type        string            `json:"type"`
+	Data        []byte            `json:"data"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt int64             `json:"next,omitempty"`
+	Sent        []string          `json:"sent,omitempty"`
+	Expiration  ss.Time           `json:"expiration"`
+}
+
+// NewOutboxRecord creates new outbox record for the message.
+func NewOutboxRecord(message OutboxMessage) OutboxRecord {
+	return OutboxRecord{
+		OutboxKeyValue: OutboxKeyValue{ID: ss.NewEntityID()},
+		Kind:           message.Kind,
+		Recipient:      message.Recipient,
+		Type:           message.Type,
+		Data:           message.Data,
+		Expiration:     ss.Now().Add(outboxMessageTTL),
+	}
+}
+
+// GetKey returns record key.
+func (record OutboxRecord) GetKey() interface{} {
+	return record.OutboxKeyValue
+}
+
+// GetData returns record's data.
+func (record OutboxRecord) GetData() interface{} { return record }
+
+// Clear resets the record to read the database response.
+func (record *OutboxRecord) Clear() { *record = OutboxRecord{} }
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,51 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Outbox_WriteTrans(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+	db.CreateTable(ddb.OutboxRecord{})
+
+	message := ddb.OutboxMessage{
+		Kind:      ddb.OutboxMessageKindGateway,
+		Recipient: "connection",
+		Data:      []byte("data"),
+	}
+
+	client.CreateOrReplace(newTestData("1", "a", 30)).Request()
+
+	trans := ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("1", "b", 20)).
+		AllowConditionalCheckFail()
+	trans.Outbox(message)
+	assert.False(client.Write(trans).IsSuccess())
+	assert.Equal(0, db.GetSize(ddb.OutboxRecord{}))
+
+	trans = ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("2", "b", 20))
+	trans.Outbox(message)
+	assert.True(client.Write(trans).IsSuccess())
+	assert.Equal(1, db.GetSize(ddb.OutboxRecord{}))
+
+	var record ddb.OutboxRecord
+	it := client.Scan(&record).RequestPaged()
+	assert.True(it.Next())
+	assert.Equal(ddb.OutboxMessageKindGateway, record.Kind)
+	assert.Equal("connection", record.Recipient)
+	assert.Equal([]byte("data"), record.Data)
+	assert.Equal(0, record.Attempts)
+	assert.False(it.Next())
+}
+
+////////////////////////////////////////////////////////////////////////////////
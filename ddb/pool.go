@@ -0,0 +1,29 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import "sync"
+
+// batchConcurrency is the maximum number of concurrent calls for one batch
+// request.
+const batchConcurrency = 4
+
+// runInPool calls the function for each index from 0 to size with no more
+// than batchConcurrency concurrent calls, and waits for all calls.
+func runInPool(size int, run func(i int)) {
+	var barrier sync.WaitGroup
+	pool := make(chan struct{}, batchConcurrency)
+	for i := 0; i < size; i++ {
+		pool <- struct{}{}
+		barrier.Add(1)
+		go func(i int) {
+			defer func() {
+				<-pool
+				barrier.Done()
+			}()
+			run(i)
+		}(i)
+	}
+	barrier.Wait()
+}
@@ -7,12 +7,12 @@ import (
 	"reflect"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 func getRecordProjection(
 	record RecordBuffer,
-	aliases *map[string]*string,
+	aliases *map[string]string,
 ) *string {
 	var result string
 	getTypeProjection(reflect.ValueOf(record).Type(), &result, aliases)
@@ -25,7 +25,7 @@ func getRecordProjection(
 func getTypeProjection(
 	source reflect.Type,
 	projection *string,
-	aliases *map[string]*string,
+	aliases *map[string]string,
 ) {
 	// It has to provide nested fields only, if root-struct is not from standard
 	// project types. See task https://buzzplace.atlassian.net/browse/BUZZ-200
@@ -55,9 +55,9 @@ func getTypeProjection(
 		if isReservedWord(tag) {
 			alias := "#" + tag
 			if *aliases == nil {
-				*aliases = map[string]*string{alias: aws.String(tag)}
+				*aliases = map[string]string{alias: tag}
 			} else {
-				(*aliases)[alias] = aws.String(tag)
+				(*aliases)[alias] = tag
 			}
 			tag = alias
 		}
@@ -4,8 +4,12 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -14,11 +18,27 @@ type Query interface {
 	ss.NoCopy
 
 	Filter(string) Query
+	FilterExpr(Condition) Query
 	Limit(int64) Query
 	Descending() Query
+	// StartFrom continues reading after the position of the cursor, which is
+	// returned by Iterator.Cursor for the same table or index.
+	StartFrom(cursor string) Query
 	RequestOne() bool
 	RequestPaged() Iterator
 	RequestAll() CacheIterator
+	// Count returns the number of records which pass the filter, it reads
+	// all pages, but doesn't read records.
+	Count() int
+	// Exists returns true if any record passes the filter, it reads only
+	// record keys.
+	Exists() bool
+
+	RequestOneE() (bool, error)
+	RequestPagedE() IteratorE
+	RequestAllE() (CacheIterator, error)
+	CountE() (int, error)
+	ExistsE() (bool, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -31,12 +51,40 @@ func (client *client) Query(
 	return newQuery(client, record, keyCondition, values)
 }
 
+func (client *client) QueryExpr(
+	record RecordBuffer,
+	keyCondition Condition,
+) Query {
+	return newQueryExpr(client, record, keyCondition)
+}
+
 func newQuery(
 	client *client,
 	record RecordBuffer,
 	keyCondition string,
 	values Values,
 ) *query {
+	result := newQueryTemplate(client, record)
+	result.err = values.marshal(&result.Input.ExpressionAttributeValues)
+	result.Input.KeyConditionExpression = aliasReservedInString(
+		keyCondition,
+		&result.Input.ExpressionAttributeNames)
+	return result
+}
+
+func newQueryExpr(
+	client *client,
+	record RecordBuffer,
+	keyCondition Condition,
+) *query {
+	result := newQueryTemplate(client, record)
+	result.Input.KeyConditionExpression, result.err = result.
+		newExpressionBuilder().
+		buildCondition(keyCondition)
+	return result
+}
+
+func newQueryTemplate(client *client, record RecordBuffer) *query {
 	result := &query{
 		client: client,
 		Record: record,
@@ -44,15 +92,9 @@ func newQuery(
 			TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
 		},
 	}
-	values.Marshal(&result.Input.ExpressionAttributeValues)
-
-	result.Input.KeyConditionExpression = aliasReservedInString(
-		keyCondition,
-		&result.Input.ExpressionAttributeNames)
 	result.Input.ProjectionExpression = getRecordProjection(
 		record,
 		&result.Input.ExpressionAttributeNames)
-
 	return result
 }
 
@@ -62,6 +104,7 @@ type query struct {
 	client *client             `json:"-"`
 	Record RecordBuffer        `json:"record"`
 	Input  dynamodb.QueryInput `json:"input"`
+	err    error
 }
 
 func (query *query) Filter(filter string) Query {
@@ -71,8 +114,25 @@ func (query *query) Filter(filter string) Query {
 	return query
 }
 
+func (query *query) FilterExpr(filter Condition) Query {
+	var err error
+	query.Input.FilterExpression, err = query.
+		newExpressionBuilder().
+		buildCondition(filter)
+	if err != nil && query.err == nil {
+		query.err = err
+	}
+	return query
+}
+
+func (query *query) newExpressionBuilder() *expressionBuilder {
+	return newExpressionBuilder(
+		&query.Input.ExpressionAttributeNames,
+		&query.Input.ExpressionAttributeValues)
+}
+
 func (query *query) Limit(limit int64) Query {
-	query.Input.Limit = &limit
+	query.Input.Limit = aws.Int32(int32(limit))
 	return query
 }
 
@@ -82,27 +142,199 @@ func (query *query) Descending() Query {
 }
 
 func (query *query) RequestPaged() Iterator {
-	request, _ := query.client.db.QueryRequest(&query.Input)
-	return newPagedIterator(request, query.Record)
+	return query.requestPaged()
+}
+
+func (query *query) RequestPagedE() IteratorE {
+	return query.requestPaged()
+}
+
+func (query *query) requestPaged() *pagedIterator {
+	reader := &queryPageReader{client: query.client, input: query.Input}
+	cursor := query.newCursorSource()
+	cursor.addToProjection(
+		&reader.input.ProjectionExpression,
+		&reader.input.ExpressionAttributeNames)
+	return newPagedIterator(
+		query.client,
+		[]pageReader{reader},
+		query.Record,
+		cursor,
+		query.err)
+}
+
+func (query *query) StartFrom(cursor string) Query {
+	key, err := query.newCursorSource().parseCursor(cursor)
+	if err != nil {
+		if query.err == nil {
+			query.err = err
+		}
+		return query
+	}
+	query.Input.ExclusiveStartKey = key
+	return query
+}
+
+func (query *query) newCursorSource() *cursorSource {
+	result := newCursorSource(
+		query.Record,
+		query.Input.TableName,
+		query.Input.IndexName,
+		query.Input.ExclusiveStartKey)
+	err := result.setKeyCondition(
+		query.Input.KeyConditionExpression,
+		query.Input.ExpressionAttributeNames,
+		query.Input.ExpressionAttributeValues)
+	if err != nil && query.err == nil {
+		query.err = err
+	}
+	return result
 }
 
 func (query *query) RequestOne() bool {
-	it := query.RequestAll()
+	result, err := query.RequestOneE()
+	if err != nil {
+		query.panic(err)
+	}
+	return result
+}
+
+func (query *query) RequestOneE() (bool, error) {
+	it, err := query.RequestAllE()
+	if err != nil {
+		return false, err
+	}
 	if it.GetSize() > 1 {
-		ss.S.Log().Panic(
-			ss.NewLogMsg(`expected one record, but returned %d`, it.GetSize()))
+		return false, fmt.Errorf(`expected one record, but returned %d`, it.GetSize())
 	}
-	return it.Next()
+	return it.Next(), nil
 }
 
 func (query *query) RequestAll() CacheIterator {
-	request, output := query.client.db.QueryRequest(&query.Input)
-	if err := request.Send(); err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(`failed to query from table %q`, query.Record.GetTable()).
-				AddErr(err).
-				AddDump(query.Input))
+	result, err := query.RequestAllE()
+	if err != nil {
+		query.panic(err)
 	}
-	return newCacheIterator(output.Items, query.Record)
+	return result
 }
+
+func (query *query) RequestAllE() (CacheIterator, error) {
+	if query.err != nil {
+		return nil, query.err
+	}
+	ctx, cancel := query.client.newRequestContext()
+	defer cancel()
+	var output *dynamodb.QueryOutput
+	err := query.client.retry(ctx, func() (err error) {
+		output, err = query.client.db.Query(ctx, &query.Input)
+		return err
+	})
+	if err != nil {
+		return nil, newError(err)
+	}
+	return newCacheIterator(output.Items, query.Record), nil
+}
+
+func (query *query) Count() int {
+	result, err := query.CountE()
+	if err != nil {
+		query.panic(err)
+	}
+	return result
+}
+
+func (query *query) CountE() (int, error) {
+	if query.err != nil {
+		return 0, query.err
+	}
+	reader := query.newCountReader(nil)
+	reader.input.Select = types.SelectCount
+	return readCount(query.client, []countReader{reader}, isCountEnough)
+}
+
+func (query *query) Exists() bool {
+	result, err := query.ExistsE()
+	if err != nil {
+		query.panic(err)
+	}
+	return result
+}
+
+func (query *query) ExistsE() (bool, error) {
+	if query.err != nil {
+		return false, query.err
+	}
+	reader := query.newCountReader(query.newCursorSource().keyFields)
+	if reader.input.FilterExpression == nil {
+		// Limit is applied before the filter, so it could be set only if
+		// there is no filter.
+		reader.input.Limit = aws.Int32(1)
+	}
+	count, err := readCount(
+		query.client,
+		[]countReader{reader},
+		isExistsEnough)
+	return count > 0, err
+}
+
+func (query *query) newCountReader(projection []string) *queryPageReader {
+	result := &queryPageReader{client: query.client, input: query.Input}
+	setCountProjection(
+		&result.input.ProjectionExpression,
+		&result.input.ExpressionAttributeNames,
+		projection,
+		result.input.KeyConditionExpression,
+		result.input.FilterExpression)
+	return result
+}
+
+func (query *query) panic(err error) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg(`failed to query from table %q`, query.Record.GetTable()).
+			AddErr(err).
+			AddDump(query.Input))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type queryPageReader struct {
+	client *client
+	input  dynamodb.QueryInput
+}
+
+func (reader *queryPageReader) readPage(ctx context.Context) (
+	[]map[string]types.AttributeValue,
+	map[string]types.AttributeValue,
+	error,
+) {
+	var page *dynamodb.QueryOutput
+	err := reader.client.retry(ctx, func() (err error) {
+		page, err = reader.client.db.Query(ctx, &reader.input)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.input.ExclusiveStartKey = page.LastEvaluatedKey
+	return page.Items, page.LastEvaluatedKey, nil
+}
+
+func (reader *queryPageReader) readCount(ctx context.Context) (
+	int,
+	bool,
+	error,
+) {
+	var page *dynamodb.QueryOutput
+	err := reader.client.retry(ctx, func() (err error) {
+		page, err = reader.client.db.Query(ctx, &reader.input)
+		return err
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	reader.input.ExclusiveStartKey = page.LastEvaluatedKey
+	return int(page.Count), len(page.LastEvaluatedKey) == 0, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
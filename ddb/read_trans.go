@@ -0,0 +1,169 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ReadTrans helps to build read-transaction, which reads many records from
+// many tables atomically.
+type ReadTrans interface {
+	ss.NoCopy
+
+	IsEmpty() bool
+	GetSize() int
+
+	// Get adds the record to read by key, the record is filled by the read
+	// result if it's found.
+	Get(KeyRecordBuffer) ReadTransGet
+
+	MarshalLogMsg(destination map[string]interface{})
+
+	GetResult() *dynamodb.TransactGetItemsInput
+	getRecords() []RecordBuffer
+}
+
+// NewReadTrans creates new read transaction builder.
+func NewReadTrans() ReadTrans {
+	return &readTrans{result: []types.TransactGetItem{}}
+}
+
+// ReadTransGet identifies the record in the read transaction.
+type ReadTransGet int
+
+func (get ReadTransGet) GetIndex() int { return int(get) }
+
+// ReadTransResult describes the read transaction result.
+type ReadTransResult interface {
+	// IsFound returns true if all records are found.
+	IsFound() bool
+	// IsFoundAt returns true if all the given records are found.
+	IsFoundAt(records ...ReadTransGet) bool
+	// GetMissing returns records which are not found.
+	GetMissing() []ReadTransGet
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type readTrans struct {
+	ss.NoCopyImpl
+
+	result  []types.TransactGetItem
+	records []RecordBuffer
+}
+
+func (trans *readTrans) GetResult() *dynamodb.TransactGetItemsInput {
+	return &dynamodb.TransactGetItemsInput{TransactItems: trans.result}
+}
+
+func (trans *readTrans) getRecords() []RecordBuffer { return trans.records }
+
+func (trans *readTrans) IsEmpty() bool { return len(trans.result) == 0 }
+func (trans *readTrans) GetSize() int  { return len(trans.result) }
+
+func (trans *readTrans) MarshalLogMsg(destination map[string]interface{}) {
+	ss.MarshalLogMsgAttrDump(trans.result, destination)
+}
+
+func (trans *readTrans) Get(record KeyRecordBuffer) ReadTransGet {
+	input := &types.Get{
+		TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
+	}
+	var err error
+	input.Key, err = MarshalMap(record.GetKey())
+	if err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg(
+					"failed to serialize key to read from table %q",
+					record.GetTable()).
+				AddDump(record.GetKey()).
+				AddDump(input).
+				AddErr(err))
+	}
+	input.ProjectionExpression = getRecordProjection(
+		record,
+		&input.ExpressionAttributeNames)
+
+	trans.result = append(trans.result, types.TransactGetItem{Get: input})
+	trans.records = append(trans.records, record)
+	return ReadTransGet(len(trans.result) - 1)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (client *client) Read(trans ReadTrans) ReadTransResult {
+	result, err := client.ReadE(trans)
+	if err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg("failed to read DDB transaction").
+				AddDump(trans).
+				AddErr(err))
+	}
+	return result
+}
+
+func (client *client) ReadE(trans ReadTrans) (ReadTransResult, error) {
+	ctx, cancel := client.newRequestContext()
+	defer cancel()
+	var output *dynamodb.TransactGetItemsOutput
+	err := client.retry(ctx, func() (err error) {
+		output, err = client.db.TransactGetItems(ctx, trans.GetResult())
+		return err
+	})
+	if err != nil {
+		return nil, newError(err)
+	}
+
+	records := trans.getRecords()
+	result := readTransResult{}
+	for i, record := range records {
+		var item map[string]types.AttributeValue
+		if i < len(output.Responses) {
+			item = output.Responses[i].Item
+		}
+		if len(item) == 0 {
+			result.missing = append(result.missing, ReadTransGet(i))
+			continue
+		}
+		record.Clear()
+		if err := UnmarshalMap(item, record); err != nil {
+			return nil, newSerializationError(
+				err,
+				"failed to read transaction response for table %q",
+				record.GetTable())
+		}
+	}
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type readTransResult struct{ missing []ReadTransGet }
+
+func (result readTransResult) IsFound() bool { return len(result.missing) == 0 }
+
+func (result readTransResult) IsFoundAt(records ...ReadTransGet) bool {
+	for _, record := range records {
+		for _, missing := range result.missing {
+			if record == missing {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (result readTransResult) GetMissing() []ReadTransGet {
+	return result.missing
+}
+
+////////////////////////////////////////////////////////////////////////////////
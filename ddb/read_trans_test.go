@@ -0,0 +1,49 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_ReadTrans_Read(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+	client.CreateOrReplace(newTestData("2", "b", 20)).Request()
+
+	first := testBuffer{testKey: newTestKey("1")}
+	second := testBuffer{testKey: newTestKey("2")}
+	missing := testBuffer{testKey: newTestKey("3")}
+
+	trans := ddb.NewReadTrans()
+	firstGet := trans.Get(&first)
+	secondGet := trans.Get(&second)
+	missingGet := trans.Get(&missing)
+	assert.Equal(3, trans.GetSize())
+
+	result := client.Read(trans)
+	assert.False(result.IsFound())
+	assert.True(result.IsFoundAt(firstGet, secondGet))
+	assert.False(result.IsFoundAt(firstGet, missingGet))
+	assert.Equal([]ddb.ReadTransGet{missingGet}, result.GetMissing())
+	assert.Equal("a", first.User)
+	assert.Equal(10, first.Time)
+	assert.Equal("b", second.User)
+	assert.Equal(20, second.Time)
+
+	trans = ddb.NewReadTrans()
+	trans.Get(&first)
+	trans.Get(&testBuffer{testKey: newTestKey("1")})
+	_, err := client.ReadE(trans)
+	assert.ErrorIs(err, ddb.ErrValidation)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -41,9 +41,9 @@ type DataRecord interface {
 	GetData() interface{}
 }
 
-// IndexRecord describes database index interface.
-type IndexRecord interface {
-	RecordBuffer
+// IndexDescription describes database index interface without record buffer.
+type IndexDescription interface {
+	Record
 	// GetIndex returns index name.
 	GetIndex() string
 	// GetIndexPartitionField returns index partition field name.
@@ -54,4 +54,10 @@ type IndexRecord interface {
 	GetProjection() []string
 }
 
+// IndexRecord describes database index interface.
+type IndexRecord interface {
+	RecordBuffer
+	IndexDescription
+}
+
 ////////////////////////////////////////////////////////////////////////////////
@@ -3,13 +3,6 @@
 
 package ddb
 
-import (
-	"errors"
-
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-)
-
 type Result bool
 
 func (result Result) IsSuccess() bool { return bool(result) }
@@ -23,17 +16,11 @@ func newResult(err error, isConditionalCheckFailAllowed bool) (Result, error) {
 		return true, nil
 	}
 
-	if isConditionalCheckFailAllowed {
-		var awsErr awserr.Error
-		if errors.As(err, &awsErr) {
-			switch awsErr.Code() {
-			case dynamodb.ErrCodeConditionalCheckFailedException:
-				return false, nil
-			}
-		}
+	if isConditionalCheckFailAllowed && getErrorKind(err) == ErrConditionFailed {
+		return false, nil
 	}
 
-	return false, err
+	return false, newError(err)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,84 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy describes how requests failed by throttling or by transaction
+// conflict are repeated. The policy is also used to retry unprocessed items
+// of batch requests. Retries are bounded by the lambda timeout, as the request
+// context is canceled when the lambda is about to reach its timeout.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first one,
+	// 1 means no retries.
+	MaxAttempts int
+	// BaseDelay is the maximum delay before the first retry, each next retry
+	// doubles it. The real delay is random from 0 to the maximum delay.
+	BaseDelay time.Duration
+	// MaxDelay is the limit of the delay before any retry.
+	MaxDelay time.Duration
+}
+
+// NewDefaultRetryPolicy creates retry policy which is used by clients
+// by default.
+func NewDefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 9,
+		BaseDelay:   50 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+}
+
+// hasAttempt returns true if the policy allows the next attempt after
+// the given one, attempt is the number of the attempt starting from 0.
+func (policy RetryPolicy) hasAttempt(attempt int) bool {
+	return attempt+1 < policy.MaxAttempts
+}
+
+// wait waits before the next retry by exponential backoff with full
+// jitter, attempt is the number of the retry starting from 0. Returns context
+// error if the context is done before the delay.
+func (policy RetryPolicy) wait(ctx context.Context, attempt int) error {
+	delay := policy.MaxDelay
+	if attempt < 16 {
+		if limit := policy.BaseDelay << attempt; limit < delay {
+			delay = limit
+		}
+	}
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(delay) + 1)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// retry executes the request and repeats it by the client retry policy
+// if the request is failed by throttling or by transaction conflict. Returns
+// the last request error, or the context error if it's canceled while waiting.
+func (client *client) retry(ctx context.Context, request func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := request()
+		if err == nil || !client.retryPolicy.hasAttempt(attempt) {
+			return err
+		}
+		if kind := getErrorKind(err); kind != ErrThrottled &&
+			kind != ErrTransactionConflict {
+			return err
+		}
+		if err := client.retryPolicy.wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
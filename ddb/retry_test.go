@@ -0,0 +1,51 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Retry_Policy(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+	client = client.WithRetryPolicy(ddb.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    time.Millisecond,
+	})
+
+	db.SetThrottledRequests(2)
+	assert.True(
+		client.CreateOrReplace(newTestData("1", "a", 10)).Request().IsSuccess())
+	db.SetThrottledRequests(3)
+	{
+		_, err := client.CreateOrReplace(newTestData("2", "a", 10)).RequestE()
+		assert.ErrorIs(err, ddb.ErrThrottled)
+	}
+	assert.Equal(1, db.GetSize(testRecord{}))
+
+	db.SetTransactionConflicts(2)
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.CreateIfNotExists(newTestData("2", "b", 20))
+		assert.True(client.Write(trans).IsSuccess())
+	}
+	db.SetTransactionConflicts(3)
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.CreateIfNotExists(newTestData("3", "b", 20))
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrTransactionConflict)
+	}
+	assert.Equal(2, db.GetSize(testRecord{}))
+}
+
+////////////////////////////////////////////////////////////////////////////////
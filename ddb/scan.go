@@ -0,0 +1,336 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/palchukovsky/ss"
+)
+
+// Scan describes the interface to scan all records of a table or an index.
+type Scan interface {
+	ss.NoCopy
+
+	Filter(string) Scan
+	FilterExpr(Condition) Scan
+	Values(Values) Scan
+	Value(name string, value interface{}) Scan
+	// Limit sets the maximum number of records to read by one request,
+	// it's not the limit of records for the whole scan.
+	Limit(int64) Scan
+	ConsistentRead() Scan
+	// Parallel splits the scan into segments which are read concurrently,
+	// but the result is still returned by one iterator.
+	Parallel(segments int) Scan
+	// StartFrom continues reading after the position of the cursor, which is
+	// returned by Iterator.Cursor for the same table or index. Cursor is not
+	// supported for parallel scan, the request returns ErrValidation if both
+	// are set.
+	StartFrom(cursor string) Scan
+
+	RequestPaged() Iterator
+	RequestPagedE() IteratorE
+	// RequestAll reads all pages.
+	RequestAll() CacheIterator
+	RequestAllE() (CacheIterator, error)
+	// Count returns the number of records which pass the filter, it reads
+	// all pages, but doesn't read records.
+	Count() int
+	CountE() (int, error)
+	// Exists returns true if any record passes the filter, it reads only
+	// record keys.
+	Exists() bool
+	ExistsE() (bool, error)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (client *client) Scan(record RecordBuffer) Scan {
+	return newScan(client, record)
+}
+
+func newScan(client *client, record RecordBuffer) *scan {
+	result := &scan{
+		client: client,
+		Record: record,
+		Input: dynamodb.ScanInput{
+			TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
+		},
+		Segments: 1,
+	}
+	result.Input.ProjectionExpression = getRecordProjection(
+		record,
+		&result.Input.ExpressionAttributeNames)
+	return result
+}
+
+type scan struct {
+	ss.NoCopyImpl
+
+	client   *client            `json:"-"`
+	Record   RecordBuffer       `json:"record"`
+	Input    dynamodb.ScanInput `json:"input"`
+	Segments int                `json:"segments"`
+	err      error
+}
+
+func (scan *scan) Filter(filter string) Scan {
+	scan.Input.FilterExpression = aliasReservedInString(
+		filter,
+		&scan.Input.ExpressionAttributeNames)
+	return scan
+}
+
+func (scan *scan) FilterExpr(filter Condition) Scan {
+	var err error
+	scan.Input.FilterExpression, err = newExpressionBuilder(
+		&scan.Input.ExpressionAttributeNames,
+		&scan.Input.ExpressionAttributeValues,
+	).buildCondition(filter)
+	if err != nil && scan.err == nil {
+		scan.err = err
+	}
+	return scan
+}
+
+func (scan *scan) Values(values Values) Scan {
+	err := values.marshal(&scan.Input.ExpressionAttributeValues)
+	if err != nil && scan.err == nil {
+		scan.err = err
+	}
+	return scan
+}
+
+func (scan *scan) Value(name string, value interface{}) Scan {
+	return scan.Values(Values{name: value})
+}
+
+func (scan *scan) Limit(limit int64) Scan {
+	scan.Input.Limit = aws.Int32(int32(limit))
+	return scan
+}
+
+func (scan *scan) ConsistentRead() Scan {
+	scan.Input.ConsistentRead = aws.Bool(true)
+	return scan
+}
+
+func (scan *scan) Parallel(segments int) Scan {
+	if segments < 1 && scan.err == nil {
+		scan.err = Error{
+			kind: ErrValidation,
+			err:  fmt.Errorf("scan segments number %d is invalid", segments),
+		}
+	}
+	scan.Segments = segments
+	return scan
+}
+
+func (scan *scan) StartFrom(cursor string) Scan {
+	key, err := newCursorSource(
+		scan.Record,
+		scan.Input.TableName,
+		scan.Input.IndexName,
+		scan.Input.ExclusiveStartKey,
+	).parseCursor(cursor)
+	if err != nil {
+		if scan.err == nil {
+			scan.err = err
+		}
+		return scan
+	}
+	scan.Input.ExclusiveStartKey = key
+	return scan
+}
+
+func (scan *scan) RequestPaged() Iterator { return scan.requestPaged() }
+
+func (scan *scan) RequestPagedE() IteratorE { return scan.requestPaged() }
+
+func (scan *scan) requestPaged() *pagedIterator {
+	err := scan.getErr()
+	if scan.Segments == 1 {
+		reader := &scanPageReader{client: scan.client, input: scan.Input}
+		cursor := newCursorSource(
+			scan.Record,
+			scan.Input.TableName,
+			scan.Input.IndexName,
+			scan.Input.ExclusiveStartKey)
+		cursor.addToProjection(
+			&reader.input.ProjectionExpression,
+			&reader.input.ExpressionAttributeNames)
+		return newPagedIterator(
+			scan.client,
+			[]pageReader{reader},
+			scan.Record,
+			cursor,
+			err)
+	}
+	readers := make([]pageReader, 0, scan.Segments)
+	for i := 0; i < scan.Segments; i++ {
+		reader := &scanPageReader{client: scan.client, input: scan.Input}
+		reader.input.Segment = aws.Int32(int32(i))
+		reader.input.TotalSegments = aws.Int32(int32(scan.Segments))
+		readers = append(readers, reader)
+	}
+	// Cursor is not supported as each segment has own position.
+	return newPagedIterator(scan.client, readers, scan.Record, nil, err)
+}
+
+func (scan *scan) RequestAll() CacheIterator {
+	result, err := scan.RequestAllE()
+	if err != nil {
+		scan.panic(err)
+	}
+	return result
+}
+
+func (scan *scan) RequestAllE() (CacheIterator, error) {
+	items, err := scan.requestPaged().readAll()
+	if err != nil {
+		return nil, err
+	}
+	return newCacheIterator(items, scan.Record), nil
+}
+
+func (scan *scan) Count() int {
+	result, err := scan.CountE()
+	if err != nil {
+		scan.panic(err)
+	}
+	return result
+}
+
+func (scan *scan) CountE() (int, error) {
+	if err := scan.getErr(); err != nil {
+		return 0, err
+	}
+	readers := scan.newCountReaders(nil, func(input *dynamodb.ScanInput) {
+		input.Select = types.SelectCount
+	})
+	return readCount(scan.client, readers, isCountEnough)
+}
+
+func (scan *scan) Exists() bool {
+	result, err := scan.ExistsE()
+	if err != nil {
+		scan.panic(err)
+	}
+	return result
+}
+
+func (scan *scan) ExistsE() (bool, error) {
+	if err := scan.getErr(); err != nil {
+		return false, err
+	}
+	keys := newCursorSource(
+		scan.Record,
+		scan.Input.TableName,
+		scan.Input.IndexName,
+		nil,
+	).keyFields
+	readers := scan.newCountReaders(keys, func(input *dynamodb.ScanInput) {
+		if input.FilterExpression == nil {
+			// Limit is applied before the filter, so it could be set only if
+			// there is no filter.
+			input.Limit = aws.Int32(1)
+		}
+	})
+	count, err := readCount(scan.client, readers, isExistsEnough)
+	return count > 0, err
+}
+
+func (scan *scan) newCountReaders(
+	projection []string,
+	setup func(*dynamodb.ScanInput),
+) []countReader {
+	result := make([]countReader, scan.Segments)
+	for i := range result {
+		reader := &scanPageReader{client: scan.client, input: scan.Input}
+		if scan.Segments > 1 {
+			reader.input.Segment = aws.Int32(int32(i))
+			reader.input.TotalSegments = aws.Int32(int32(scan.Segments))
+		}
+		setCountProjection(
+			&reader.input.ProjectionExpression,
+			&reader.input.ExpressionAttributeNames,
+			projection,
+			reader.input.FilterExpression)
+		setup(&reader.input)
+		result[i] = reader
+	}
+	return result
+}
+
+func (scan *scan) getErr() error {
+	if scan.err != nil {
+		return scan.err
+	}
+	if scan.Segments > 1 && scan.Input.ExclusiveStartKey != nil {
+		// Checked at the request as Parallel and StartFrom could be called in
+		// any order.
+		return Error{
+			kind: ErrValidation,
+			err:  errors.New("cursor is not supported for parallel scan"),
+		}
+	}
+	return nil
+}
+
+func (scan *scan) panic(err error) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg(`failed to scan table %q`, scan.Record.GetTable()).
+			AddErr(err).
+			AddDump(scan))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type scanPageReader struct {
+	client *client
+	input  dynamodb.ScanInput
+}
+
+func (reader *scanPageReader) readPage(ctx context.Context) (
+	[]map[string]types.AttributeValue,
+	map[string]types.AttributeValue,
+	error,
+) {
+	var page *dynamodb.ScanOutput
+	err := reader.client.retry(ctx, func() (err error) {
+		page, err = reader.client.db.Scan(ctx, &reader.input)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	reader.input.ExclusiveStartKey = page.LastEvaluatedKey
+	return page.Items, page.LastEvaluatedKey, nil
+}
+
+func (reader *scanPageReader) readCount(ctx context.Context) (
+	int,
+	bool,
+	error,
+) {
+	var page *dynamodb.ScanOutput
+	err := reader.client.retry(ctx, func() (err error) {
+		page, err = reader.client.db.Scan(ctx, &reader.input)
+		return err
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	reader.input.ExclusiveStartKey = page.LastEvaluatedKey
+	return int(page.Count), len(page.LastEvaluatedKey) == 0, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,113 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Scan_Parallel(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	for i := 0; i < 50; i++ {
+		user := "a"
+		if i%2 != 0 {
+			user = "b"
+		}
+		client.CreateOrReplace(newTestData(strconv.Itoa(i), user, i)).Request()
+	}
+
+	var record testBuffer
+	{
+		it := client.Scan(&record).Limit(7).RequestPaged()
+		ids := map[string]struct{}{}
+		for it.Next() {
+			ids[record.ID] = struct{}{}
+		}
+		assert.Equal(50, len(ids))
+	}
+	{
+		it := client.
+			Scan(&record).
+			Filter("user = :u").
+			Value(":u", "b").
+			Limit(3).
+			Parallel(5).
+			RequestPaged()
+		ids := map[string]struct{}{}
+		for it.Next() {
+			assert.Equal("b", record.User)
+			ids[record.ID] = struct{}{}
+		}
+		assert.Equal(25, len(ids))
+	}
+	{
+		var index testUserIndex
+		all := client.
+			Index(&index).
+			Scan().
+			Filter("time < :t").
+			Value(":t", 10).
+			Limit(2).
+			RequestAll()
+		assert.Equal(10, all.GetSize())
+	}
+	{
+		_, err := client.
+			Index(&testUserIndex{}).
+			Scan().
+			ConsistentRead().
+			RequestAllE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Scan_ParallelCursor(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	for i := 0; i < 3; i++ {
+		client.CreateOrReplace(newTestData(strconv.Itoa(i), "a", i)).Request()
+	}
+
+	var record testBuffer
+	it := client.Scan(&record).Limit(1).RequestPaged()
+	assert.True(it.Next())
+	cursor := it.Cursor()
+
+	{
+		_, err := client.
+			Scan(&record).
+			StartFrom(cursor).
+			Parallel(2).
+			RequestPagedE().
+			NextE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		_, err := client.
+			Scan(&record).
+			Parallel(2).
+			StartFrom(cursor).
+			RequestAllE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		_, err := client.Scan(&record).Parallel(2).StartFrom(cursor).CountE()
+		assert.ErrorIs(err, ddb.ErrValidation)
+	}
+	{
+		count, err := client.Scan(&record).Parallel(1).StartFrom(cursor).CountE()
+		assert.NoError(err)
+		assert.Equal(2, count)
+	}
+}
@@ -0,0 +1,231 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/palchukovsky/ss"
+)
+
+// Sequence generates monotonically increasing numbers, starting from 1,
+// by the counter in the sequence table. Values are reserved by ranges, which
+// are kept by the sequence object in the warm lambda, so numbers from
+// different lambdas are unique, but could be not in order of requests, and
+// numbers of unused ranges are lost.
+type Sequence struct {
+	ss.NoCopyImpl
+
+	name      string
+	rangeSize uint64
+
+	mutex sync.Mutex
+	// next and last are the reserved range which is not used yet.
+	next uint64
+	last uint64
+}
+
+// NewSequence creates sequence with the given name, which reserves values by
+// ranges of the given size, the range size 1 means that each value is
+// requested from the database.
+func NewSequence(name string, rangeSize uint64) *Sequence {
+	if rangeSize == 0 {
+		rangeSize = 1
+	}
+	return &Sequence{name: name, rangeSize: rangeSize}
+}
+
+// Next returns the next sequence value.
+func (sequence *Sequence) Next(client Client) uint64 {
+	result, err := sequence.NextE(client)
+	if err != nil {
+		sequence.panic(err, "failed to get next value")
+	}
+	return result
+}
+
+// NextE returns the next sequence value, or typed error instead of panic.
+func (sequence *Sequence) NextE(client Client) (uint64, error) {
+	sequence.mutex.Lock()
+	defer sequence.mutex.Unlock()
+
+	if sequence.next == 0 || sequence.next > sequence.last {
+		first, err := sequence.reserve(client, sequence.rangeSize)
+		if err != nil {
+			return 0, err
+		}
+		sequence.next = first
+		sequence.last = first + sequence.rangeSize - 1
+	}
+	result := sequence.next
+	sequence.next++
+	return result, nil
+}
+
+// Reserve reserves the given number of values, and returns the first value
+// of the reserved range. The range is not taken from the cached range.
+func (sequence *Sequence) Reserve(client Client, count uint64) uint64 {
+	result, err := sequence.ReserveE(client, count)
+	if err != nil {
+		sequence.panic(err, "failed to reserve range")
+	}
+	return result
+}
+
+// ReserveE reserves the given number of values, and returns the first value
+// of the reserved range, or typed error instead of panic.
+func (sequence *Sequence) ReserveE(
+	client Client,
+	count uint64,
+) (uint64, error) {
+	if count == 0 {
+		return 0, Error{
+			kind: ErrValidation,
+			err:  fmt.Errorf("sequence %q range is empty", sequence.name),
+		}
+	}
+	return sequence.reserve(client, count)
+}
+
+// NextInTrans adds the sequence counter update into the transaction, and
+// returns the value which is taken if the transaction is succeeded. If
+// the counter is changed by another request before the transaction, the
+// transaction is failed by conditional check, so the value is never taken
+// twice, and values are taken without gaps. Cached ranges are not used,
+// as each value has to be reserved by the transaction.
+func (sequence *Sequence) NextInTrans(client Client, trans WriteTrans) uint64 {
+	result, err := sequence.NextInTransE(client, trans)
+	if err != nil {
+		sequence.panic(err, "failed to add next value into transaction")
+	}
+	return result
+}
+
+// NextInTransE adds the sequence counter update into the transaction, and
+// returns the value which is taken if the transaction is succeeded, or typed
+// error instead of panic.
+func (sequence *Sequence) NextInTransE(
+	client Client,
+	trans WriteTrans,
+) (uint64, error) {
+	current := SequenceRecord{
+		SequenceKeyValue: newSequenceKeyValue(sequence.name),
+	}
+	isFound, err := client.Find(&current).RequestE()
+	if err != nil {
+		return 0, err
+	}
+	result := current.Value + 1
+	if !isFound {
+		trans.CreateIfNotExists(newSequenceRecord(sequence.name, result))
+		return result, nil
+	}
+	trans.
+		UpdateExpr(
+			newSequenceKey(sequence.name),
+			Set(sequenceValueField, result)).
+		ConditionExpr(Name(sequenceValueField).Equal(Value(current.Value)))
+	return result, nil
+}
+
+func (sequence *Sequence) reserve(client Client, count uint64) (uint64, error) {
+	var record SequenceRecord
+	for isCreated := false; ; isCreated = true {
+		update := client.
+			Update(newSequenceKey(sequence.name)).
+			Add(sequenceValueField, count)
+		update.AllowConditionalCheckFail()
+		result, err := update.RequestAndReturnE(&record)
+		if err != nil {
+			return 0, err
+		}
+		if result.IsSuccess() {
+			break
+		}
+		if isCreated {
+			return 0, Error{
+				kind: ErrConditionFailed,
+				err:  fmt.Errorf("sequence %q is not created", sequence.name),
+			}
+		}
+		// The sequence is used first time.
+		create := client.CreateIfNotExists(newSequenceRecord(sequence.name, 0))
+		create.AllowConditionalCheckFail()
+		if _, err := create.RequestE(); err != nil {
+			return 0, err
+		}
+	}
+	return record.Value - count + 1, nil
+}
+
+func (sequence *Sequence) panic(err error, message string) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg("sequence %q: %s", sequence.name, message).
+			AddErr(err))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+const sequenceValueField = "val"
+
+type sequenceRecord struct{}
+
+// GetTable returns table name.
+func (sequenceRecord) GetTable() string { return "Sequence" }
+
+// GetKeyPartitionField returns partition field name.
+func (sequenceRecord) GetKeyPartitionField() string { return "id" }
+
+// GetKeySortField returns sort field name.
+func (sequenceRecord) GetKeySortField() string { return "" }
+
+type SequenceKeyValue struct {
+	Name string `json:"id"`
+}
+
+func newSequenceKeyValue(name string) SequenceKeyValue {
+	return SequenceKeyValue{Name: name}
+}
+
+type sequenceKey struct {
+	sequenceRecord
+	SequenceKeyValue
+}
+
+func newSequenceKey(name string) sequenceKey {
+	return sequenceKey{SequenceKeyValue: newSequenceKeyValue(name)}
+}
+
+func (key sequenceKey) GetKey() interface{} { return key.SequenceKeyValue }
+
+// SequenceRecord describes the record of the table with sequence counters,
+// the table is created by the database installer if the project installer
+// implements dbinstall.SequenceInstaller.
+type SequenceRecord struct {
+	sequenceRecord
+	SequenceKeyValue
+	Value uint64 `json:"val"`
+}
+
+func newSequenceRecord(name string, value uint64) SequenceRecord {
+	return SequenceRecord{
+		SequenceKeyValue: newSequenceKeyValue(name),
+		Value:            value,
+	}
+}
+
+// GetKey returns record key.
+func (record SequenceRecord) GetKey() interface{} {
+	return record.SequenceKeyValue
+}
+
+// GetData returns record's data.
+func (record SequenceRecord) GetData() interface{} { return record }
+
+// Clear resets the record to read the database response.
+func (record *SequenceRecord) Clear() { *record = SequenceRecord{} }
+
+////////////////////////////////////////////////////////////////////////////////
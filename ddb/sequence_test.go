@@ -0,0 +1,48 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Sequence_Next(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+	db.CreateTable(ddb.SequenceRecord{})
+
+	orders := ddb.NewSequence("order", 3)
+	for i := uint64(1); i <= 4; i++ {
+		assert.Equal(i, orders.Next(client))
+	}
+	// Another lambda has own reserved range.
+	assert.Equal(uint64(7), ddb.NewSequence("order", 3).Next(client))
+	assert.Equal(uint64(5), orders.Next(client))
+	assert.Equal(uint64(10), orders.Reserve(client, 5))
+	assert.Equal(uint64(6), orders.Next(client))
+	assert.Equal(uint64(15), orders.Next(client))
+
+	invites := ddb.NewSequence("invite", 1)
+	for i := uint64(1); i <= 2; i++ {
+		trans := ddb.NewWriteTrans(false)
+		assert.Equal(i, invites.NextInTrans(client, trans))
+		trans.CreateOrReplace(newTestData("1", "a", int(i)))
+		assert.True(client.Write(trans).IsSuccess())
+	}
+	{
+		trans := ddb.NewWriteTrans(false)
+		assert.Equal(uint64(3), invites.NextInTrans(client, trans))
+		assert.Equal(uint64(3), invites.Next(client))
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrConditionFailed)
+	}
+	assert.Equal(uint64(4), invites.Next(client))
+}
+
+////////////////////////////////////////////////////////////////////////////////
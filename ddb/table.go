@@ -0,0 +1,339 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Table provides typed access to the table records. Each read returns a new
+// record, so the record type doesn't need Clear method and shared buffer.
+// The record type has to be a value type, not a pointer.
+type Table[T DataRecord] struct{ client Client }
+
+// NewTable creates typed table access by the client.
+func NewTable[T DataRecord](client Client) Table[T] {
+	return Table[T]{client: client}
+}
+
+// Get returns the record by key and true, or false if the record is not found.
+func (table Table[T]) Get(key Key) (T, bool) {
+	buffer := newRecordBuffer[T](key)
+	isFound := table.client.Find(buffer).Request()
+	return buffer.record, isFound
+}
+
+// GetE returns the record by key and true, or false if the record is not
+// found, or typed error instead of panic.
+func (table Table[T]) GetE(key Key) (T, bool, error) {
+	buffer := newRecordBuffer[T](key)
+	isFound, err := table.client.Find(buffer).RequestE()
+	return buffer.record, isFound, err
+}
+
+func (table Table[T]) Query(
+	keyCondition string,
+	values Values,
+) RecordQuery[T] {
+	buffer := newRecordBuffer[T](nil)
+	return newRecordQuery(
+		table.client.Query(buffer, keyCondition, values),
+		buffer)
+}
+
+func (table Table[T]) QueryExpr(keyCondition Condition) RecordQuery[T] {
+	buffer := newRecordBuffer[T](nil)
+	return newRecordQuery(table.client.QueryExpr(buffer, keyCondition), buffer)
+}
+
+func (table Table[T]) QueryByPartition(partition interface{}) RecordQuery[T] {
+	buffer := newRecordBuffer[T](nil)
+	return newRecordQuery(
+		table.client.QueryByPartition(buffer, partition),
+		buffer)
+}
+
+func (table Table[T]) QueryByPartitionAndSort(
+	partition interface{},
+	sort SortCondition,
+) RecordQuery[T] {
+	buffer := newRecordBuffer[T](nil)
+	return newRecordQuery(
+		table.client.QueryByPartitionAndSort(buffer, partition, sort),
+		buffer)
+}
+
+// Put creates the record or replaces existing, versioned records are
+// written with the version check as CreateOrReplace does.
+func (table Table[T]) Put(record T) Create {
+	return table.client.CreateOrReplace(record)
+}
+
+// Update updates existing record by key with the given actions.
+func (table Table[T]) Update(key Key, actions ...UpdateAction) Update {
+	return table.client.Update(newRecordBuffer[T](key)).Apply(actions...)
+}
+
+// Delete deletes existing record by key.
+func (table Table[T]) Delete(key Key) Delete {
+	return table.client.Delete(newRecordBuffer[T](key))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// TableIndex provides typed access to the table index records, it's the same
+// as Table, but for index.
+type TableIndex[T IndexDescription] struct{ client Client }
+
+// NewTableIndex creates typed table index access by the client.
+func NewTableIndex[T IndexDescription](client Client) TableIndex[T] {
+	return TableIndex[T]{client: client}
+}
+
+func (index TableIndex[T]) Query(
+	keyCondition string,
+	values Values,
+) RecordQuery[T] {
+	buffer := newIndexRecordBuffer[T]()
+	return newRecordQuery(
+		index.client.Index(buffer).Query(keyCondition, values),
+		&buffer.recordBuffer)
+}
+
+func (index TableIndex[T]) QueryExpr(keyCondition Condition) RecordQuery[T] {
+	buffer := newIndexRecordBuffer[T]()
+	return newRecordQuery(
+		index.client.Index(buffer).QueryExpr(keyCondition),
+		&buffer.recordBuffer)
+}
+
+func (index TableIndex[T]) QueryByPartition(
+	partition interface{},
+) RecordQuery[T] {
+	buffer := newIndexRecordBuffer[T]()
+	return newRecordQuery(
+		index.client.Index(buffer).QueryByPartition(partition),
+		&buffer.recordBuffer)
+}
+
+func (index TableIndex[T]) QueryByPartitionAndSort(
+	partition interface{},
+	sort SortCondition,
+) RecordQuery[T] {
+	buffer := newIndexRecordBuffer[T]()
+	return newRecordQuery(
+		index.client.Index(buffer).QueryByPartitionAndSort(partition, sort),
+		&buffer.recordBuffer)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// RecordQuery describes the interface to query typed records.
+type RecordQuery[T Record] interface {
+	Filter(string) RecordQuery[T]
+	FilterExpr(Condition) RecordQuery[T]
+	Limit(int64) RecordQuery[T]
+	Descending() RecordQuery[T]
+	StartFrom(cursor string) RecordQuery[T]
+
+	RequestOne() (T, bool)
+	RequestPaged() RecordIterator[T]
+	RequestAll() []T
+	Count() int
+	Exists() bool
+
+	RequestOneE() (T, bool, error)
+	RequestPagedE() RecordIteratorE[T]
+	RequestAllE() ([]T, error)
+	CountE() (int, error)
+	ExistsE() (bool, error)
+}
+
+// RecordIterator describes intreface to read paged typed records.
+type RecordIterator[T Record] interface {
+	IteratorMover
+	Get() T
+	Cursor() string
+}
+
+// RecordIteratorE describes intreface to read paged typed records,
+// which returns typed error instead of panic.
+type RecordIteratorE[T Record] interface {
+	NextE() (bool, error)
+	Get() T
+	CursorE() (string, error)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func newRecordQuery[T Record](
+	query Query,
+	buffer *recordBuffer[T],
+) *recordQuery[T] {
+	return &recordQuery[T]{query: query, buffer: buffer}
+}
+
+type recordQuery[T Record] struct {
+	query  Query
+	buffer *recordBuffer[T]
+}
+
+func (query *recordQuery[T]) Filter(filter string) RecordQuery[T] {
+	query.query.Filter(filter)
+	return query
+}
+
+func (query *recordQuery[T]) FilterExpr(filter Condition) RecordQuery[T] {
+	query.query.FilterExpr(filter)
+	return query
+}
+
+func (query *recordQuery[T]) Limit(limit int64) RecordQuery[T] {
+	query.query.Limit(limit)
+	return query
+}
+
+func (query *recordQuery[T]) Descending() RecordQuery[T] {
+	query.query.Descending()
+	return query
+}
+
+func (query *recordQuery[T]) StartFrom(cursor string) RecordQuery[T] {
+	query.query.StartFrom(cursor)
+	return query
+}
+
+func (query *recordQuery[T]) RequestOne() (T, bool) {
+	isFound := query.query.RequestOne()
+	return query.buffer.record, isFound
+}
+
+func (query *recordQuery[T]) RequestOneE() (T, bool, error) {
+	isFound, err := query.query.RequestOneE()
+	return query.buffer.record, isFound, err
+}
+
+func (query *recordQuery[T]) RequestPaged() RecordIterator[T] {
+	return &recordIterator[T]{
+		Iterator: query.query.RequestPaged(),
+		buffer:   query.buffer,
+	}
+}
+
+func (query *recordQuery[T]) RequestPagedE() RecordIteratorE[T] {
+	return &recordIteratorE[T]{
+		IteratorE: query.query.RequestPagedE(),
+		buffer:    query.buffer,
+	}
+}
+
+func (query *recordQuery[T]) RequestAll() []T {
+	return query.buffer.readAll(query.query.RequestAll())
+}
+
+func (query *recordQuery[T]) RequestAllE() ([]T, error) {
+	it, err := query.query.RequestAllE()
+	if err != nil {
+		return nil, err
+	}
+	return query.buffer.readAll(it), nil
+}
+
+func (query *recordQuery[T]) Count() int { return query.query.Count() }
+
+func (query *recordQuery[T]) CountE() (int, error) {
+	return query.query.CountE()
+}
+
+func (query *recordQuery[T]) Exists() bool { return query.query.Exists() }
+
+func (query *recordQuery[T]) ExistsE() (bool, error) {
+	return query.query.ExistsE()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type recordIterator[T Record] struct {
+	Iterator
+	buffer *recordBuffer[T]
+}
+
+func (it *recordIterator[T]) Get() T { return it.buffer.record }
+
+type recordIteratorE[T Record] struct {
+	IteratorE
+	buffer *recordBuffer[T]
+}
+
+func (it *recordIteratorE[T]) Get() T { return it.buffer.record }
+
+////////////////////////////////////////////////////////////////////////////////
+
+func newRecordBuffer[T Record](key Key) *recordBuffer[T] {
+	return &recordBuffer[T]{key: key}
+}
+
+// recordBuffer is the record buffer for the untyped API, which unmarshals each
+// record into a new record instance. The record projection is taken from
+// the record type as the record field doesn't have a tag.
+type recordBuffer[T Record] struct {
+	record T
+	key    Key
+}
+
+func (buffer *recordBuffer[T]) GetTable() string {
+	return buffer.record.GetTable()
+}
+func (buffer *recordBuffer[T]) GetKeyPartitionField() string {
+	return buffer.record.GetKeyPartitionField()
+}
+func (buffer *recordBuffer[T]) GetKeySortField() string {
+	return buffer.record.GetKeySortField()
+}
+
+func (buffer *recordBuffer[T]) GetKey() interface{} { return buffer.key.GetKey() }
+func (buffer *recordBuffer[T]) getKeySource() Key   { return buffer.key }
+
+func (buffer *recordBuffer[T]) Clear() {
+	var record T
+	buffer.record = record
+}
+
+func (buffer *recordBuffer[T]) UnmarshalDynamoDBAttributeValue(
+	source types.AttributeValue,
+) error {
+	buffer.Clear()
+	return unmarshal(source, &buffer.record)
+}
+
+func (buffer *recordBuffer[T]) readAll(it CacheIterator) []T {
+	result := make([]T, 0, it.GetSize())
+	for it.Next() {
+		result = append(result, buffer.record)
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func newIndexRecordBuffer[T IndexDescription]() *indexRecordBuffer[T] {
+	return &indexRecordBuffer[T]{}
+}
+
+type indexRecordBuffer[T IndexDescription] struct{ recordBuffer[T] }
+
+func (buffer *indexRecordBuffer[T]) GetIndex() string {
+	return buffer.record.GetIndex()
+}
+func (buffer *indexRecordBuffer[T]) GetIndexPartitionField() string {
+	return buffer.record.GetIndexPartitionField()
+}
+func (buffer *indexRecordBuffer[T]) GetIndexSortField() string {
+	return buffer.record.GetIndexSortField()
+}
+func (buffer *indexRecordBuffer[T]) GetProjection() []string {
+	return buffer.record.GetProjection()
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,65 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Table_Access(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	table := ddb.NewTable[testData](client)
+	for _, record := range []testData{
+		newTestData("1", "a", 30),
+		newTestData("2", "b", 20),
+		newTestData("3", "a", 10),
+	} {
+		assert.True(table.Put(record).Request().IsSuccess())
+	}
+
+	record, isFound := table.Get(newTestKey("1"))
+	assert.True(isFound)
+	assert.Equal(newTestData("1", "a", 30), record)
+	record, isFound = table.Get(newTestKey("4"))
+	assert.False(isFound)
+	assert.Equal(testData{}, record)
+
+	assert.True(
+		table.Update(newTestKey("2"), ddb.Set("val", 5)).Request().IsSuccess())
+	record, isFound, err := table.GetE(newTestKey("2"))
+	assert.NoError(err)
+	assert.True(isFound)
+	assert.Equal(5, record.Value)
+	assert.Equal("b", record.User)
+
+	records := ddb.
+		NewTableIndex[testUserIndex](client).
+		QueryExpr(ddb.Name("user").Equal(ddb.Value("a"))).
+		RequestAll()
+	if assert.Equal(2, len(records)) {
+		// Each record is a separate instance, not a shared buffer.
+		assert.Equal("3", records[0].ID)
+		assert.Equal(10, records[0].Time)
+		assert.Equal("1", records[1].ID)
+		assert.Equal(30, records[1].Time)
+	}
+
+	it := table.Query("id = :id", ddb.Values{":id": "3"}).RequestPaged()
+	assert.True(it.Next())
+	assert.Equal(newTestData("3", "a", 10), it.Get())
+	assert.False(it.Next())
+
+	assert.True(table.Delete(newTestKey("3")).Request().IsSuccess())
+	_, isFound = table.Get(newTestKey("3"))
+	assert.False(isFound)
+}
+
+////////////////////////////////////////////////////////////////////////////////
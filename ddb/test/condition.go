@@ -0,0 +1,363 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type condition interface {
+	eval(item) (bool, error)
+}
+
+// parseCondition parses condition, filter or key condition expression.
+// Returns nil if the expression is not set.
+func parseCondition(
+	source *string,
+	context *expressionContext,
+) (condition, error) {
+	if source == nil {
+		return nil, nil
+	}
+	parser, err := newParser(*source, context)
+	if err != nil {
+		return nil, err
+	}
+	result, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return result, parser.expectEnd()
+}
+
+// checkCondition returns true if the condition is not set or passed.
+func checkCondition(condition condition, source item) (bool, error) {
+	if condition == nil {
+		return true, nil
+	}
+	return condition.eval(source)
+}
+
+func (parser *parser) parseOr() (condition, error) {
+	result, err := parser.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for parser.isKeyword("OR") {
+		parser.next()
+		rhs, err := parser.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		result = orCondition{lhs: result, rhs: rhs}
+	}
+	return result, nil
+}
+
+func (parser *parser) parseAnd() (condition, error) {
+	result, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for parser.isKeyword("AND") {
+		parser.next()
+		rhs, err := parser.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		result = andCondition{lhs: result, rhs: rhs}
+	}
+	return result, nil
+}
+
+func (parser *parser) parseNot() (condition, error) {
+	if !parser.isKeyword("NOT") {
+		return parser.parsePrimary()
+	}
+	parser.next()
+	result, err := parser.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return notCondition{condition: result}, nil
+}
+
+func (parser *parser) parsePrimary() (condition, error) {
+	if parser.isPunct("(") {
+		parser.next()
+		result, err := parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return result, parser.expectPunct(")")
+	}
+
+	for _, name := range []string{
+		"attribute_exists",
+		"attribute_not_exists",
+		"attribute_type",
+		"begins_with",
+		"contains",
+	} {
+		if parser.isFunction(name) {
+			return parser.parseFunction(name)
+		}
+	}
+
+	lhs, err := parser.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case parser.isKeyword("BETWEEN"):
+		parser.next()
+		low, err := parser.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.isKeyword("AND") {
+			return nil, parser.newError(`expected "AND"`)
+		}
+		parser.next()
+		high, err := parser.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return betweenCondition{operand: lhs, low: low, high: high}, nil
+
+	case parser.isKeyword("IN"):
+		parser.next()
+		if err := parser.expectPunct("("); err != nil {
+			return nil, err
+		}
+		result := inCondition{operand: lhs}
+		for {
+			option, err := parser.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			result.options = append(result.options, option)
+			if !parser.isPunct(",") {
+				break
+			}
+			parser.next()
+		}
+		return result, parser.expectPunct(")")
+	}
+
+	token := parser.peek()
+	if token.Type != tokenPunct {
+		return nil, parser.newError("expected comparator")
+	}
+	switch token.Text {
+	case "=", "<>", "<", "<=", ">", ">=":
+		parser.next()
+	default:
+		return nil, parser.newError("expected comparator")
+	}
+	rhs, err := parser.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return comparison{comparator: token.Text, lhs: lhs, rhs: rhs}, nil
+}
+
+func (parser *parser) parseFunction(name string) (condition, error) {
+	parser.next()
+	parser.next()
+	path, err := parser.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	result := functionCondition{name: strings.ToLower(name), path: path}
+	switch result.name {
+	case "attribute_type", "begins_with", "contains":
+		if err := parser.expectPunct(","); err != nil {
+			return nil, err
+		}
+		if result.arg, err = parser.parseOperand(); err != nil {
+			return nil, err
+		}
+	}
+	return result, parser.expectPunct(")")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type orCondition struct{ lhs, rhs condition }
+
+func (condition orCondition) eval(source item) (bool, error) {
+	result, err := condition.lhs.eval(source)
+	if err != nil || result {
+		return result, err
+	}
+	return condition.rhs.eval(source)
+}
+
+type andCondition struct{ lhs, rhs condition }
+
+func (condition andCondition) eval(source item) (bool, error) {
+	result, err := condition.lhs.eval(source)
+	if err != nil || !result {
+		return result, err
+	}
+	return condition.rhs.eval(source)
+}
+
+type notCondition struct{ condition condition }
+
+func (condition notCondition) eval(source item) (bool, error) {
+	result, err := condition.condition.eval(source)
+	return !result, err
+}
+
+type comparison struct {
+	comparator string
+	lhs        operand
+	rhs        operand
+}
+
+func (condition comparison) eval(source item) (bool, error) {
+	lhs, hasLHS, err := condition.lhs.eval(source)
+	if err != nil {
+		return false, err
+	}
+	rhs, hasRHS, err := condition.rhs.eval(source)
+	if err != nil {
+		return false, err
+	}
+	if condition.comparator == "<>" {
+		return !hasLHS || !hasRHS || !lhs.isEqual(rhs), nil
+	}
+	if !hasLHS || !hasRHS {
+		return false, nil
+	}
+	if condition.comparator == "=" {
+		return lhs.isEqual(rhs), nil
+	}
+	result, isComparable := lhs.compare(rhs)
+	if !isComparable {
+		return false, nil
+	}
+	switch condition.comparator {
+	case "<":
+		return result < 0, nil
+	case "<=":
+		return result <= 0, nil
+	case ">":
+		return result > 0, nil
+	default:
+		return result >= 0, nil
+	}
+}
+
+type betweenCondition struct{ operand, low, high operand }
+
+func (condition betweenCondition) eval(source item) (bool, error) {
+	operands := make([]value, 3)
+	for i, operand := range []operand{
+		condition.operand,
+		condition.low,
+		condition.high,
+	} {
+		var has bool
+		var err error
+		operands[i], has, err = operand.eval(source)
+		if err != nil || !has {
+			return false, err
+		}
+	}
+	low, isComparable := operands[0].compare(operands[1])
+	if !isComparable || low < 0 {
+		return false, nil
+	}
+	high, isComparable := operands[0].compare(operands[2])
+	return isComparable && high <= 0, nil
+}
+
+type inCondition struct {
+	operand operand
+	options []operand
+}
+
+func (condition inCondition) eval(source item) (bool, error) {
+	operand, has, err := condition.operand.eval(source)
+	if err != nil || !has {
+		return false, err
+	}
+	for _, option := range condition.options {
+		value, has, err := option.eval(source)
+		if err != nil {
+			return false, err
+		}
+		if has && operand.isEqual(value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type functionCondition struct {
+	name string
+	path path
+	arg  operand
+}
+
+func (condition functionCondition) eval(source item) (bool, error) {
+	attr, has := condition.path.get(source)
+	switch condition.name {
+	case "attribute_exists":
+		return has, nil
+	case "attribute_not_exists":
+		return !has, nil
+	}
+	if !has {
+		return false, nil
+	}
+	arg, hasArg, err := condition.arg.eval(source)
+	if err != nil || !hasArg {
+		return false, err
+	}
+	switch condition.name {
+	case "attribute_type":
+		if arg.Type != valueTypeS {
+			return false, fmt.Errorf("attribute_type requires string type name")
+		}
+		return string(attr.Type) == arg.Scalar, nil
+	case "begins_with":
+		if attr.Type != arg.Type ||
+			(attr.Type != valueTypeS && attr.Type != valueTypeB) {
+			return false, nil
+		}
+		return strings.HasPrefix(attr.Scalar, arg.Scalar), nil
+	default: // contains
+		switch attr.Type {
+		case valueTypeS:
+			return arg.Type == valueTypeS &&
+				strings.Contains(attr.Scalar, arg.Scalar), nil
+		case valueTypeSS:
+			return arg.Type == valueTypeS && attr.hasInSet(arg.Scalar), nil
+		case valueTypeBS:
+			return arg.Type == valueTypeB && attr.hasInSet(arg.Scalar), nil
+		case valueTypeNS:
+			if arg.Type != valueTypeN {
+				return false, nil
+			}
+			return attr.hasInSet(arg.Scalar), nil
+		case valueTypeL:
+			for _, element := range attr.List {
+				if element.isEqual(arg) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
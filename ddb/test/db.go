@@ -0,0 +1,1082 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+// Package ddbtest implements in-memory database to test code which works
+// through ddb.Client without a real DynamoDB table.
+package ddbtest
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DB is an in-memory database which implements ddb.API.
+type DB struct {
+	ss.NoCopyImpl
+
+	mutex  sync.Mutex
+	tables map[string]*table
+
+	batchGetItemLimit   int
+	batchWriteItemLimit int
+
+	throttledRequests    int
+	transactionConflicts int
+}
+
+// NewDB creates new empty in-memory database.
+func NewDB() *DB { return &DB{tables: map[string]*table{}} }
+
+// NewClient creates new ddb.Client which works with the in-memory database.
+func NewClient(db *DB, interceptors ...ddb.Interceptor) ddb.Client {
+	return ddb.NewClient(db, interceptors...)
+}
+
+// CreateTable creates table by the record key, and creates global secondary
+// indexes by index records as ddbinstall does it.
+func (db *DB) CreateTable(record ddb.Record, indexes ...ddb.IndexDescription) {
+	result := newTable(
+		ss.S.NewBuildEntityName(record.GetTable()),
+		keySchema{
+			Partition: record.GetKeyPartitionField(),
+			Sort:      record.GetKeySortField(),
+		})
+	for _, index := range indexes {
+		if index.GetTable() != record.GetTable() {
+			ss.S.Log().Panic(
+				ss.NewLogMsg(
+					"index %q is from table %q, but not from %q",
+					index.GetIndex(),
+					index.GetTable(),
+					record.GetTable()))
+		}
+		result.indexes[index.GetIndex()] = keySchema{
+			Partition: index.GetIndexPartitionField(),
+			Sort:      index.GetIndexSortField(),
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.tables[result.name] = result
+}
+
+// SetBatchGetItemLimit sets the maximum number of keys processed by one
+// BatchGetItem call, other keys are returned as unprocessed as DynamoDB does
+// it by throttling or by the response size limit. Zero disables the limit.
+func (db *DB) SetBatchGetItemLimit(limit int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.batchGetItemLimit = limit
+}
+
+// SetBatchWriteItemLimit sets the maximum number of items processed by one
+// BatchWriteItem call, other items are returned as unprocessed. Zero disables
+// the limit.
+func (db *DB) SetBatchWriteItemLimit(limit int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.batchWriteItemLimit = limit
+}
+
+// SetThrottledRequests sets the number of next requests which fail by
+// throttling as DynamoDB does it when provisioned throughput is exceeded.
+func (db *DB) SetThrottledRequests(number int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.throttledRequests = number
+}
+
+// SetTransactionConflicts sets the number of next write transactions which are
+// canceled by the conflict with another transaction.
+func (db *DB) SetTransactionConflicts(number int) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	db.transactionConflicts = number
+}
+
+// GetSize returns number of items in the table.
+func (db *DB) GetSize(record ddb.Record) int {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	name := ss.S.NewBuildEntityName(record.GetTable())
+	table, err := db.getTable(&name)
+	if err != nil {
+		ss.S.Log().Panic(ss.NewLogMsg("failed to get table size").AddErr(err))
+	}
+	return len(table.items)
+}
+
+func (db *DB) getTable(name *string) (*table, error) {
+	if name == nil {
+		return nil, newValidationError("table name is not set")
+	}
+	result, has := db.tables[*name]
+	if !has {
+		return nil, &types.ResourceNotFoundException{
+			Message: aws.String(
+				fmt.Sprintf("Requested resource not found: Table: %s not found", *name)),
+		}
+	}
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) GetItem(
+	ctx context.Context,
+	input *dynamodb.GetItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.GetItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	item, err := db.getItem(
+		input.TableName,
+		input.Key,
+		input.ProjectionExpression,
+		input.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+// getItem returns the item by key with the projection, or nil if the item
+// doesn't exist.
+func (db *DB) getItem(
+	tableName *string,
+	key map[string]types.AttributeValue,
+	projectionExpression *string,
+	names map[string]string,
+) (map[string]types.AttributeValue, error) {
+	table, err := db.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	context := newExpressionContext(names, nil)
+	projection, err := parseProjection(projectionExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	if err := context.checkUsage(); err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	id, err := table.getKeyIDByInput(key)
+	if err != nil {
+		return nil, err
+	}
+	item, has := table.items[id]
+	if !has {
+		return nil, nil
+	}
+	return exportItem(projection.apply(item)), nil
+}
+
+func (db *DB) BatchGetItem(
+	ctx context.Context,
+	input *dynamodb.BatchGetItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.BatchGetItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	count := 0
+	for _, request := range input.RequestItems {
+		count += len(request.Keys)
+	}
+	if count == 0 {
+		return nil, newValidationError("batch get request does not have keys")
+	}
+	if count > 100 {
+		return nil, newValidationError(
+			"too many items requested for the BatchGetItem call: %d",
+			count)
+	}
+
+	result := dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]types.AttributeValue{},
+		UnprocessedKeys: map[string]types.KeysAndAttributes{},
+	}
+	processed := 0
+	for tableName, request := range input.RequestItems {
+		table, err := db.getTable(aws.String(tableName))
+		if err != nil {
+			return nil, err
+		}
+		context := newExpressionContext(request.ExpressionAttributeNames, nil)
+		projection, err := parseProjection(request.ProjectionExpression, context)
+		if err != nil {
+			return nil, newValidationError("%v", err)
+		}
+		if err := context.checkUsage(); err != nil {
+			return nil, newValidationError("%v", err)
+		}
+		items := []map[string]types.AttributeValue{}
+		ids := map[string]struct{}{}
+		for _, key := range request.Keys {
+			id, err := table.getKeyIDByInput(key)
+			if err != nil {
+				return nil, err
+			}
+			if _, has := ids[id]; has {
+				return nil, newValidationError(
+					"provided list of item keys contains duplicates")
+			}
+			ids[id] = struct{}{}
+			if db.batchGetItemLimit > 0 && processed >= db.batchGetItemLimit {
+				unprocessed, has := result.UnprocessedKeys[tableName]
+				if !has {
+					unprocessed = types.KeysAndAttributes{
+						ConsistentRead:           request.ConsistentRead,
+						ProjectionExpression:     request.ProjectionExpression,
+						ExpressionAttributeNames: request.ExpressionAttributeNames,
+					}
+				}
+				unprocessed.Keys = append(unprocessed.Keys, key)
+				result.UnprocessedKeys[tableName] = unprocessed
+				continue
+			}
+			processed++
+			if item, has := table.items[id]; has {
+				items = append(items, exportItem(projection.apply(item)))
+			}
+		}
+		result.Responses[tableName] = items
+	}
+	return &result, nil
+}
+
+func (db *DB) Query(
+	ctx context.Context,
+	input *dynamodb.QueryInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.QueryOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	table, err := db.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := table.getSchema(input.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if input.IndexName != nil && aws.ToBool(input.ConsistentRead) {
+		return nil, newValidationError(
+			"consistent reads are not supported on global secondary indexes")
+	}
+
+	context := newExpressionContext(
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	keyCondition, err := parseCondition(input.KeyConditionExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	if keyCondition == nil {
+		return nil, newValidationError("query key condition is not set")
+	}
+	if err := schema.checkKeyCondition(keyCondition); err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	filter, err := parseCondition(input.FilterExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	projection, err := parseProjection(input.ProjectionExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	if err := context.checkUsage(); err != nil {
+		return nil, newValidationError("%v", err)
+	}
+
+	items, err := table.query(
+		schema,
+		keyCondition,
+		input.ScanIndexForward == nil || *input.ScanIndexForward)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	items, lastKey, err := table.readPage(
+		schema,
+		items,
+		input.ExclusiveStartKey,
+		input.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dynamodb.QueryOutput{ScannedCount: int32(len(items))}
+	if lastKey != nil {
+		result.LastEvaluatedKey = exportItem(lastKey)
+	}
+	result.Items, result.Count, err = exportPage(
+		items,
+		filter,
+		projection,
+		input.Select == types.SelectCount)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (db *DB) Scan(
+	ctx context.Context,
+	input *dynamodb.ScanInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.ScanOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	table, err := db.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := table.getSchema(input.IndexName)
+	if err != nil {
+		return nil, err
+	}
+	if input.IndexName != nil && aws.ToBool(input.ConsistentRead) {
+		return nil, newValidationError(
+			"consistent reads are not supported on global secondary indexes")
+	}
+	segment := aws.ToInt32(input.Segment)
+	totalSegments := aws.ToInt32(input.TotalSegments)
+	if (input.Segment == nil) != (input.TotalSegments == nil) ||
+		(input.TotalSegments != nil &&
+			(totalSegments < 1 || totalSegments > 1000000 ||
+				segment < 0 || segment >= totalSegments)) {
+		return nil, newValidationError(
+			"invalid segment %d of %d",
+			segment,
+			totalSegments)
+	}
+
+	context := newExpressionContext(
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	filter, err := parseCondition(input.FilterExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	projection, err := parseProjection(input.ProjectionExpression, context)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	if err := context.checkUsage(); err != nil {
+		return nil, newValidationError("%v", err)
+	}
+
+	items, err := table.query(schema, nil, true)
+	if err != nil {
+		return nil, newValidationError("%v", err)
+	}
+	if input.TotalSegments != nil {
+		items = getSegment(schema, items, segment, totalSegments)
+	}
+	items, lastKey, err := table.readPage(
+		schema,
+		items,
+		input.ExclusiveStartKey,
+		input.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := dynamodb.ScanOutput{ScannedCount: int32(len(items))}
+	if lastKey != nil {
+		result.LastEvaluatedKey = exportItem(lastKey)
+	}
+	result.Items, result.Count, err = exportPage(
+		items,
+		filter,
+		projection,
+		input.Select == types.SelectCount)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getSegment returns items of the scan segment, items are distributed
+// between segments by the partition key hash.
+func getSegment(
+	schema keySchema,
+	source []item,
+	segment int32,
+	totalSegments int32,
+) []item {
+	result := []item{}
+	for _, item := range source {
+		partition := item[schema.Partition]
+		hash := fnv.New64a()
+		hash.Write([]byte(string(partition.Type) + ":" + partition.Scalar))
+		if int32(hash.Sum64()%uint64(totalSegments)) == segment {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// exportPage filters the read page and applies projection.
+func exportPage(
+	source []item,
+	filter condition,
+	projection projection,
+	isCount bool,
+) ([]map[string]types.AttributeValue, int32, error) {
+	var result []map[string]types.AttributeValue
+	if !isCount {
+		result = []map[string]types.AttributeValue{}
+	}
+	count := int32(0)
+	for _, item := range source {
+		if isPassed, err := checkCondition(filter, item); err != nil {
+			return nil, 0, newValidationError("%v", err)
+		} else if !isPassed {
+			continue
+		}
+		count++
+		if !isCount {
+			result = append(result, exportItem(projection.apply(item)))
+		}
+	}
+	return result, count, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) PutItem(
+	ctx context.Context,
+	input *dynamodb.PutItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.PutItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	change, err := db.preparePut(
+		input.TableName,
+		input.Item,
+		input.ConditionExpression,
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if change.isConditionFailed {
+		return nil, newConditionalCheckFailedError()
+	}
+	change.commit()
+
+	result := dynamodb.PutItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld &&
+		change.old != nil {
+		result.Attributes = exportItem(change.old)
+	}
+	return &result, nil
+}
+
+func (db *DB) UpdateItem(
+	ctx context.Context,
+	input *dynamodb.UpdateItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.UpdateItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	change, err := db.prepareUpdate(
+		input.TableName,
+		input.Key,
+		input.UpdateExpression,
+		input.ConditionExpression,
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if change.isConditionFailed {
+		return nil, newConditionalCheckFailedError()
+	}
+	change.commit()
+
+	result := dynamodb.UpdateItemOutput{}
+	switch input.ReturnValues {
+	case types.ReturnValueAllOld:
+		if change.old != nil {
+			result.Attributes = exportItem(change.old)
+		}
+	case types.ReturnValueAllNew:
+		result.Attributes = exportItem(change.new)
+	case types.ReturnValueUpdatedOld:
+		result.Attributes = exportItem(getUpdated(change.old, change.new))
+	case types.ReturnValueUpdatedNew:
+		result.Attributes = exportItem(getUpdated(change.new, change.old))
+	}
+	return &result, nil
+}
+
+func (db *DB) DeleteItem(
+	ctx context.Context,
+	input *dynamodb.DeleteItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.DeleteItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	change, err := db.prepareDelete(
+		input.TableName,
+		input.Key,
+		input.ConditionExpression,
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+	if change.isConditionFailed {
+		return nil, newConditionalCheckFailedError()
+	}
+	change.commit()
+
+	result := dynamodb.DeleteItemOutput{}
+	if input.ReturnValues == types.ReturnValueAllOld &&
+		change.old != nil {
+		result.Attributes = exportItem(change.old)
+	}
+	return &result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) BatchWriteItem(
+	ctx context.Context,
+	input *dynamodb.BatchWriteItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	count := 0
+	for _, requests := range input.RequestItems {
+		count += len(requests)
+	}
+	if count == 0 || count > 25 {
+		return nil, newValidationError(
+			"batch write has to have from 1 to 25 items, but has %d",
+			count)
+	}
+
+	result := dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]types.WriteRequest{},
+	}
+	changes := make([]change, 0, count)
+	ids := map[string]struct{}{}
+	for tableName, requests := range input.RequestItems {
+		for _, request := range requests {
+			var change change
+			var err error
+			switch {
+			case request.PutRequest != nil && request.DeleteRequest == nil:
+				change, err = db.preparePut(
+					aws.String(tableName),
+					request.PutRequest.Item,
+					nil,
+					nil,
+					nil)
+			case request.DeleteRequest != nil && request.PutRequest == nil:
+				change, err = db.prepareDelete(
+					aws.String(tableName),
+					request.DeleteRequest.Key,
+					nil,
+					nil,
+					nil)
+			default:
+				err = newValidationError(
+					"write request has to have put or delete request")
+			}
+			if err != nil {
+				return nil, err
+			}
+			id := tableName + "/" + change.id
+			if _, has := ids[id]; has {
+				return nil, newValidationError(
+					"provided list of item keys contains duplicates")
+			}
+			ids[id] = struct{}{}
+
+			if db.batchWriteItemLimit > 0 && len(changes) >= db.batchWriteItemLimit {
+				result.UnprocessedItems[tableName] = append(
+					result.UnprocessedItems[tableName],
+					request)
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	for _, change := range changes {
+		change.commit()
+	}
+	return &result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) TransactWriteItems(
+	ctx context.Context,
+	input *dynamodb.TransactWriteItemsInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > 100 {
+		return nil, newValidationError(
+			"transaction has to have from 1 to 100 items, but has %d",
+			len(input.TransactItems))
+	}
+
+	if db.transactionConflicts > 0 {
+		db.transactionConflicts--
+		return nil, newTransactionConflictError(len(input.TransactItems))
+	}
+
+	changes := make([]change, len(input.TransactItems))
+	isOldReturned := make([]bool, len(input.TransactItems))
+	ids := map[string]struct{}{}
+	isFailed := false
+	for i, request := range input.TransactItems {
+		var err error
+		switch {
+		case request.Put != nil:
+			changes[i], err = db.preparePut(
+				request.Put.TableName,
+				request.Put.Item,
+				request.Put.ConditionExpression,
+				request.Put.ExpressionAttributeNames,
+				request.Put.ExpressionAttributeValues)
+			isOldReturned[i] = isOldReturnedOnConditionCheckFailure(
+				request.Put.ReturnValuesOnConditionCheckFailure)
+		case request.Update != nil:
+			changes[i], err = db.prepareUpdate(
+				request.Update.TableName,
+				request.Update.Key,
+				request.Update.UpdateExpression,
+				request.Update.ConditionExpression,
+				request.Update.ExpressionAttributeNames,
+				request.Update.ExpressionAttributeValues)
+			isOldReturned[i] = isOldReturnedOnConditionCheckFailure(
+				request.Update.ReturnValuesOnConditionCheckFailure)
+		case request.Delete != nil:
+			changes[i], err = db.prepareDelete(
+				request.Delete.TableName,
+				request.Delete.Key,
+				request.Delete.ConditionExpression,
+				request.Delete.ExpressionAttributeNames,
+				request.Delete.ExpressionAttributeValues)
+			isOldReturned[i] = isOldReturnedOnConditionCheckFailure(
+				request.Delete.ReturnValuesOnConditionCheckFailure)
+		case request.ConditionCheck != nil:
+			changes[i], err = db.prepareCheck(request.ConditionCheck)
+			isOldReturned[i] = isOldReturnedOnConditionCheckFailure(
+				request.ConditionCheck.ReturnValuesOnConditionCheckFailure)
+		default:
+			err = newValidationError("transaction item %d is empty", i)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		id := changes[i].table.name + "/" + changes[i].id
+		if _, has := ids[id]; has {
+			return nil, newValidationError(
+				"transaction request cannot include multiple operations on one item")
+		}
+		ids[id] = struct{}{}
+
+		if changes[i].isConditionFailed {
+			isFailed = true
+		}
+	}
+
+	if isFailed {
+		return nil, newTransactionCanceledError(changes, isOldReturned)
+	}
+
+	for _, change := range changes {
+		change.commit()
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (db *DB) TransactGetItems(
+	ctx context.Context,
+	input *dynamodb.TransactGetItemsInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.TransactGetItemsOutput, error) {
+	if err := db.checkRequest(ctx); err != nil {
+		return nil, err
+	}
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if len(input.TransactItems) == 0 || len(input.TransactItems) > 100 {
+		return nil, newValidationError(
+			"transaction has to have from 1 to 100 items, but has %d",
+			len(input.TransactItems))
+	}
+
+	result := dynamodb.TransactGetItemsOutput{
+		Responses: make([]types.ItemResponse, len(input.TransactItems)),
+	}
+	ids := map[string]struct{}{}
+	for i, request := range input.TransactItems {
+		if request.Get == nil {
+			return nil, newValidationError("transaction item %d is empty", i)
+		}
+		table, err := db.getTable(request.Get.TableName)
+		if err != nil {
+			return nil, err
+		}
+		id, err := table.getKeyIDByInput(request.Get.Key)
+		if err != nil {
+			return nil, err
+		}
+		id = table.name + "/" + id
+		if _, has := ids[id]; has {
+			return nil, newValidationError(
+				"transaction request cannot include multiple operations on one item")
+		}
+		ids[id] = struct{}{}
+
+		item, err := db.getItem(
+			request.Get.TableName,
+			request.Get.Key,
+			request.Get.ProjectionExpression,
+			request.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+		result.Responses[i] = types.ItemResponse{Item: item}
+	}
+	return &result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// change is a prepared item change, which has to be committed
+// if the condition is passed.
+type change struct {
+	table             *table
+	id                string
+	old               item
+	new               item
+	isConditionFailed bool
+}
+
+func (change change) commit() {
+	if change.new == nil {
+		delete(change.table.items, change.id)
+		return
+	}
+	change.table.items[change.id] = change.new
+}
+
+func (db *DB) prepareChange(
+	tableName *string,
+	key map[string]types.AttributeValue,
+	condition *string,
+	context *expressionContext,
+) (change, error) {
+	table, err := db.getTable(tableName)
+	if err != nil {
+		return change{}, err
+	}
+	result := change{table: table}
+	if result.id, err = table.getKeyIDByInput(key); err != nil {
+		return change{}, err
+	}
+	result.old = table.items[result.id]
+
+	parsedCondition, err := parseCondition(condition, context)
+	if err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	old := result.old
+	if old == nil {
+		old = item{}
+	}
+	isPassed, err := checkCondition(parsedCondition, old)
+	if err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	result.isConditionFailed = !isPassed
+
+	return result, nil
+}
+
+func (db *DB) preparePut(
+	tableName *string,
+	source map[string]types.AttributeValue,
+	condition *string,
+	names map[string]string,
+	values map[string]types.AttributeValue,
+) (change, error) {
+	newItem, err := importItem(source)
+	if err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	table, err := db.getTable(tableName)
+	if err != nil {
+		return change{}, err
+	}
+	context := newExpressionContext(names, values)
+	result, err := db.prepareChange(
+		tableName,
+		exportItem(table.key.extractKey(newItem)),
+		condition,
+		context)
+	if err != nil {
+		return change{}, err
+	}
+	if err := context.checkUsage(); err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	result.new = newItem
+	return result, nil
+}
+
+func (db *DB) prepareUpdate(
+	tableName *string,
+	key map[string]types.AttributeValue,
+	update *string,
+	condition *string,
+	names map[string]string,
+	values map[string]types.AttributeValue,
+) (change, error) {
+	context := newExpressionContext(names, values)
+	result, err := db.prepareChange(tableName, key, condition, context)
+	if err != nil {
+		return change{}, err
+	}
+	expression, err := parseUpdate(update, context)
+	if err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	if err := context.checkUsage(); err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	if result.isConditionFailed {
+		return result, nil
+	}
+
+	old := result.old
+	if old == nil {
+		// Update creates a new item if it doesn't exist.
+		if old, err = importItem(key); err != nil {
+			return change{}, newValidationError("%v", err)
+		}
+	}
+	if result.new, err = expression.apply(old); err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	if err := result.table.checkUpdate(old, result.new); err != nil {
+		return change{}, err
+	}
+	return result, nil
+}
+
+func (db *DB) prepareDelete(
+	tableName *string,
+	key map[string]types.AttributeValue,
+	condition *string,
+	names map[string]string,
+	values map[string]types.AttributeValue,
+) (change, error) {
+	context := newExpressionContext(names, values)
+	result, err := db.prepareChange(tableName, key, condition, context)
+	if err != nil {
+		return change{}, err
+	}
+	if err := context.checkUsage(); err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	return result, nil
+}
+
+func (db *DB) prepareCheck(input *types.ConditionCheck) (change, error) {
+	if input.ConditionExpression == nil {
+		return change{}, newValidationError("condition check has no condition")
+	}
+	context := newExpressionContext(
+		input.ExpressionAttributeNames,
+		input.ExpressionAttributeValues)
+	result, err := db.prepareChange(
+		input.TableName,
+		input.Key,
+		input.ConditionExpression,
+		context)
+	if err != nil {
+		return change{}, err
+	}
+	if err := context.checkUsage(); err != nil {
+		return change{}, newValidationError("%v", err)
+	}
+	// Check doesn't change the item, so commit stores the same state.
+	result.new = result.old
+	return result, nil
+}
+
+// getUpdated returns top-level attributes from the source,
+// which are different in the comparison item.
+func getUpdated(source, comparison item) item {
+	result := item{}
+	for name, value := range source {
+		if other, has := comparison[name]; !has || !value.isEqual(other) {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func (table *table) getKeyIDByInput(
+	key map[string]types.AttributeValue,
+) (string, error) {
+	source, err := importItem(key)
+	if err != nil {
+		return "", newValidationError("%v", err)
+	}
+	return table.getKeyID(source)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// checkRequest returns the same error as the SDK returns for canceled request,
+// or throttling error if the request has to be throttled.
+func (db *DB) checkRequest(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return &smithy.CanceledError{Err: err}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+	if db.throttledRequests > 0 {
+		db.throttledRequests--
+		return &types.ProvisionedThroughputExceededException{
+			Message: aws.String("The level of configured provisioned throughput" +
+				" for the table was exceeded"),
+		}
+	}
+
+	return nil
+}
+
+func newValidationError(format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{
+		Code:    "ValidationException",
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func newConditionalCheckFailedError() error {
+	return &types.ConditionalCheckFailedException{
+		Message: aws.String("The conditional request failed"),
+	}
+}
+
+func isOldReturnedOnConditionCheckFailure(
+	returnValues types.ReturnValuesOnConditionCheckFailure,
+) bool {
+	return returnValues == types.ReturnValuesOnConditionCheckFailureAllOld
+}
+
+func newTransactionCanceledError(changes []change, isOldReturned []bool) error {
+	reasons := make([]types.CancellationReason, len(changes))
+	codes := make([]string, len(changes))
+	for i, change := range changes {
+		if change.isConditionFailed {
+			codes[i] = "ConditionalCheckFailed"
+			reasons[i] = types.CancellationReason{
+				Code:    aws.String(codes[i]),
+				Message: aws.String("The conditional request failed"),
+			}
+			if isOldReturned[i] && change.old != nil {
+				reasons[i].Item = exportItem(change.old)
+			}
+			continue
+		}
+		codes[i] = "None"
+		reasons[i] = types.CancellationReason{Code: aws.String(codes[i])}
+	}
+	return &types.TransactionCanceledException{
+		Message: aws.String(
+			"Transaction cancelled, please refer cancellation reasons for" +
+				" specific reasons [" + strings.Join(codes, ", ") + "]"),
+		CancellationReasons: reasons,
+	}
+}
+
+func newTransactionConflictError(size int) error {
+	reasons := make([]types.CancellationReason, size)
+	codes := make([]string, size)
+	for i := range reasons {
+		codes[i] = "None"
+		if i == 0 {
+			codes[i] = "TransactionConflict"
+		}
+		reasons[i] = types.CancellationReason{Code: aws.String(codes[i])}
+	}
+	return &types.TransactionCanceledException{
+		Message: aws.String(
+			"Transaction cancelled, please refer cancellation reasons for" +
+				" specific reasons [" + strings.Join(codes, ", ") + "]"),
+		CancellationReasons: reasons,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,268 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testRecord struct{}
+
+func (testRecord) GetTable() string             { return "Test" }
+func (testRecord) GetKeyPartitionField() string { return "id" }
+func (testRecord) GetKeySortField() string      { return "" }
+
+type testKeyValue struct {
+	ID string `json:"id"`
+}
+
+type testKey struct {
+	testRecord
+	testKeyValue
+}
+
+func newTestKey(id string) testKey {
+	return testKey{testKeyValue: testKeyValue{ID: id}}
+}
+
+func (key testKey) GetKey() interface{} { return key.testKeyValue }
+
+type testData struct {
+	testRecord
+	testKeyValue
+	User  string `json:"user"`
+	Time  int    `json:"time"`
+	Value int    `json:"val"`
+}
+
+func newTestData(id, user string, time int) testData {
+	return testData{
+		testKeyValue: testKeyValue{ID: id},
+		User:         user,
+		Time:         time,
+	}
+}
+
+func (record testData) GetData() interface{} { return record }
+
+type testBuffer struct {
+	testKey
+	User  string `json:"user"`
+	Time  int    `json:"time"`
+	Value int    `json:"val"`
+}
+
+func (record *testBuffer) Clear() { *record = testBuffer{} }
+
+type testUserIndex struct {
+	testRecord
+	testKeyValue
+	Time int `json:"time"`
+}
+
+func (testUserIndex) GetIndex() string               { return "User" }
+func (testUserIndex) GetIndexPartitionField() string { return "user" }
+func (testUserIndex) GetIndexSortField() string      { return "time" }
+func (testUserIndex) GetProjection() []string        { return []string{} }
+func (record *testUserIndex) Clear()                 { *record = testUserIndex{} }
+
+func newTestClient(test *testing.T) (ddb.Client, *ddbtest.DB) {
+	ddbtest.SetService(test, ss.ServiceConfig{})
+	db := ddbtest.NewDB()
+	db.CreateTable(testRecord{}, &testUserIndex{})
+	return ddbtest.NewClient(db), db
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Test_CRUD(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	assert.True(
+		client.CreateIfNotExists(newTestData("1", "a", 10)).Request().IsSuccess())
+	{
+		create := client.CreateIfNotExists(newTestData("1", "b", 20))
+		create.AllowConditionalCheckFail()
+		assert.False(create.Request().IsSuccess())
+	}
+	assert.Equal(1, db.GetSize(testRecord{}))
+
+	record := testBuffer{testKey: newTestKey("1")}
+	assert.True(client.Find(&record).Request())
+	assert.Equal("a", record.User)
+	assert.Equal(10, record.Time)
+	assert.False(client.Find(&testBuffer{testKey: newTestKey("2")}).Request())
+
+	{
+		update := client.Update(newTestKey("1"))
+		update.Set("val = :v").Value(":v", 3).Condition("time = :t").Value(":t", 10)
+		update.Expression("add time :d").Value(":d", 5)
+		assert.True(update.RequestAndReturn(&record).IsSuccess())
+		assert.Equal(3, record.Value)
+		assert.Equal(15, record.Time)
+	}
+	{
+		update := client.Update(newTestKey("1"))
+		update.Set("val = :v").Value(":v", 4).Condition("time = :t").Value(":t", 10)
+		update.AllowConditionalCheckFail()
+		assert.False(update.Request().IsSuccess())
+	}
+	{
+		update := client.Update(newTestKey("2")).Set("val = :v").Value(":v", 1)
+		update.AllowConditionalCheckFail()
+		assert.False(update.Request().IsSuccess())
+		assert.Equal(1, db.GetSize(testRecord{}))
+	}
+
+	{
+		delete := client.Delete(newTestKey("1"))
+		assert.True(delete.RequestAndReturn(&record).IsSuccess())
+		assert.Equal(3, record.Value)
+	}
+	{
+		delete := client.Delete(newTestKey("1"))
+		delete.AllowConditionalCheckFail()
+		assert.False(delete.Request().IsSuccess())
+	}
+	assert.True(client.DeleteIfExisting(newTestKey("1")).Request().IsSuccess())
+	assert.Equal(0, db.GetSize(testRecord{}))
+	{
+		record := testBuffer{User: "x"}
+		delete := client.DeleteIfExisting(newTestKey("1"))
+		assert.False(delete.RequestAndReturn(&record).IsSuccess())
+		assert.Equal("x", record.User)
+	}
+
+	{
+		record := testBuffer{}
+		create := client.CreateOrReplace(newTestData("1", "a", 10))
+		assert.True(create.RequestAndReturnOld(&record).IsSuccess())
+		assert.Equal("", record.User)
+		create = client.CreateOrReplace(newTestData("1", "b", 20))
+		assert.True(create.RequestAndReturnOld(&record).IsSuccess())
+		assert.Equal("a", record.User)
+		assert.Equal(10, record.Time)
+	}
+}
+
+func Test_DDB_Test_Query(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	for _, record := range []testData{
+		newTestData("1", "a", 30),
+		newTestData("2", "b", 20),
+		newTestData("3", "a", 10),
+		newTestData("4", "a", 20),
+	} {
+		client.CreateOrReplace(record).Request()
+	}
+
+	var index testUserIndex
+	it := client.
+		Index(&index).
+		Query("user = :u", ddb.Values{":u": "a"}).
+		Limit(1).
+		RequestPaged()
+	ids := []string{}
+	times := []int{}
+	for it.Next() {
+		ids = append(ids, index.ID)
+		times = append(times, index.Time)
+	}
+	assert.Equal([]string{"3", "4", "1"}, ids)
+	assert.Equal([]int{10, 20, 30}, times)
+
+	all := client.
+		Index(&index).
+		Query("user = :u and time > :t", ddb.Values{":u": "a", ":t": 10}).
+		Descending().
+		RequestAll()
+	assert.Equal(2, all.GetSize())
+	assert.Equal("1", all.GetAt(0).(*testUserIndex).ID)
+	assert.Equal("4", all.GetAt(1).(*testUserIndex).ID)
+
+	assert.True(
+		client.
+			Index(&index).
+			Query("user = :u", ddb.Values{":u": "a", ":t": 30}).
+			Filter("time = :t").
+			RequestOne())
+	assert.Equal("1", index.ID)
+
+	var record testBuffer
+	assert.False(
+		client.
+			Query(&record, "id = :i", ddb.Values{":i": "5"}).
+			RequestOne())
+}
+
+func Test_DDB_Test_WriteTrans(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	client.CreateOrReplace(newTestData("1", "a", 30)).Request()
+
+	trans := ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("2", "b", 20))
+	isNotExisting := trans.
+		CreateIfNotExists(newTestData("1", "b", 20)).
+		AllowConditionalCheckFail()
+	isUpdated := trans.
+		Update(newTestKey("3"), "set val = :v").
+		Value(":v", 1).
+		AllowConditionalCheckFail()
+	result := client.Write(trans)
+	assert.False(result.IsSuccess())
+	assert.False(result.ParseConditions().IsPassed(isNotExisting))
+	assert.False(result.ParseConditions().IsPassed(isUpdated))
+	assert.Equal(1, db.GetSize(testRecord{}))
+
+	trans = ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("2", "b", 20))
+	trans.Check(newTestKey("1")).Condition("user = :u").Value(":u", "a")
+	trans.Delete(newTestKey("1"))
+	{
+		// The same item can't be used twice.
+		_, err := db.TransactWriteItems(
+			context.Background(),
+			trans.GetResult())
+		assert.Error(err)
+	}
+
+	trans = ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("2", "b", 20))
+	isChecked := trans.
+		Check(newTestKey("1")).
+		Condition("user = :u").
+		Value(":u", "b").
+		ReturnOnConditionalCheckFail().
+		AllowConditionalCheckFail()
+	result = client.Write(trans)
+	assert.False(result.IsSuccess())
+	assert.False(result.ParseConditions().IsPassed(isChecked))
+	{
+		record := testBuffer{}
+		assert.True(result.ReadConflictItem(isChecked, &record))
+		assert.Equal("a", record.User)
+		assert.Equal(30, record.Time)
+	}
+
+	trans = ddb.NewWriteTrans(false)
+	trans.CreateIfNotExists(newTestData("2", "b", 20))
+	trans.Update(newTestKey("1"), "set val = :v").Value(":v", 1)
+	assert.True(client.Write(trans).IsSuccess())
+	assert.Equal(2, db.GetSize(testRecord{}))
+}
+
+////////////////////////////////////////////////////////////////////////////////
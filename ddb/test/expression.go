@@ -0,0 +1,515 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type tokenType int
+
+const (
+	tokenEnd tokenType = iota
+	tokenIdent
+	tokenName
+	tokenValue
+	tokenNumber
+	tokenPunct
+)
+
+type token struct {
+	Type tokenType
+	Text string
+	Pos  int
+}
+
+func tokenize(source string) ([]token, error) {
+	result := []token{}
+	isIdentChar := func(c byte) bool {
+		return c == '_' ||
+			(c >= 'a' && c <= 'z') ||
+			(c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9')
+	}
+	readWord := func(begin int) int {
+		end := begin
+		for end < len(source) && isIdentChar(source[end]) {
+			end++
+		}
+		return end
+	}
+	for pos := 0; pos < len(source); {
+		c := source[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '#' || c == ':':
+			end := readWord(pos + 1)
+			if end == pos+1 {
+				return nil, fmt.Errorf("empty placeholder at %d in %q", pos, source)
+			}
+			tokenType := tokenName
+			if c == ':' {
+				tokenType = tokenValue
+			}
+			result = append(result, token{tokenType, source[pos:end], pos})
+			pos = end
+		case c >= '0' && c <= '9':
+			end := pos
+			for end < len(source) && source[end] >= '0' && source[end] <= '9' {
+				end++
+			}
+			result = append(result, token{tokenNumber, source[pos:end], pos})
+			pos = end
+		case isIdentChar(c):
+			end := readWord(pos)
+			result = append(result, token{tokenIdent, source[pos:end], pos})
+			pos = end
+		case c == '<' || c == '>':
+			end := pos + 1
+			if end < len(source) &&
+				(source[end] == '=' || (c == '<' && source[end] == '>')) {
+				end++
+			}
+			result = append(result, token{tokenPunct, source[pos:end], pos})
+			pos = end
+		case strings.IndexByte("()[],.=+-", c) >= 0:
+			result = append(result, token{tokenPunct, string(c), pos})
+			pos++
+		default:
+			return nil, fmt.Errorf("unexpected symbol %q at %d in %q", c, pos, source)
+		}
+	}
+	return append(result, token{Type: tokenEnd, Pos: len(source)}), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// expressionContext is a request expression attribute names and values,
+// shared between all expressions of one request. It tracks usage as
+// DynamoDB does not allow unused names and values.
+type expressionContext struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]struct{}
+	usedValues map[string]struct{}
+}
+
+func newExpressionContext(
+	names map[string]string,
+	values map[string]types.AttributeValue,
+) *expressionContext {
+	return &expressionContext{
+		names:      names,
+		values:     values,
+		usedNames:  map[string]struct{}{},
+		usedValues: map[string]struct{}{},
+	}
+}
+
+func (context *expressionContext) resolveName(placeholder string) (string, error) {
+	result, has := context.names[placeholder]
+	if !has {
+		return "", fmt.Errorf(
+			"expression attribute name %q is not defined",
+			placeholder)
+	}
+	context.usedNames[placeholder] = struct{}{}
+	return result, nil
+}
+
+func (context *expressionContext) resolveValue(placeholder string) (value, error) {
+	source, has := context.values[placeholder]
+	if !has {
+		return value{}, fmt.Errorf(
+			"expression attribute value %q is not defined",
+			placeholder)
+	}
+	context.usedValues[placeholder] = struct{}{}
+	return importValue(source)
+}
+
+// checkUsage returns an error if some names or values have not been used by
+// expressions.
+func (context *expressionContext) checkUsage() error {
+	for name := range context.names {
+		if _, has := context.usedNames[name]; !has {
+			return fmt.Errorf(
+				"expression attribute name %q is unused in expressions",
+				name)
+		}
+	}
+	for name := range context.values {
+		if _, has := context.usedValues[name]; !has {
+			return fmt.Errorf(
+				"expression attribute value %q is unused in expressions",
+				name)
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type parser struct {
+	context *expressionContext
+	source  string
+	tokens  []token
+	pos     int
+}
+
+func newParser(source string, context *expressionContext) (*parser, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{context: context, source: source, tokens: tokens}, nil
+}
+
+func (parser *parser) peek() token { return parser.tokens[parser.pos] }
+
+func (parser *parser) next() token {
+	result := parser.tokens[parser.pos]
+	if result.Type != tokenEnd {
+		parser.pos++
+	}
+	return result
+}
+
+func (parser *parser) isPunct(text string) bool {
+	token := parser.peek()
+	return token.Type == tokenPunct && token.Text == text
+}
+
+func (parser *parser) isKeyword(keyword string) bool {
+	token := parser.peek()
+	return token.Type == tokenIdent && strings.EqualFold(token.Text, keyword)
+}
+
+func (parser *parser) isFunction(name string) bool {
+	return parser.isKeyword(name) &&
+		parser.tokens[parser.pos+1].Type == tokenPunct &&
+		parser.tokens[parser.pos+1].Text == "("
+}
+
+func (parser *parser) expectPunct(text string) error {
+	if !parser.isPunct(text) {
+		return parser.newError("expected %q", text)
+	}
+	parser.next()
+	return nil
+}
+
+func (parser *parser) expectEnd() error {
+	if parser.peek().Type != tokenEnd {
+		return parser.newError("unexpected token")
+	}
+	return nil
+}
+
+func (parser *parser) newError(format string, args ...interface{}) error {
+	token := parser.peek()
+	return fmt.Errorf(
+		"invalid expression %q at %d (%q): %s",
+		parser.source,
+		token.Pos,
+		token.Text,
+		fmt.Sprintf(format, args...))
+}
+
+func (parser *parser) parsePath() (path, error) {
+	result := path{}
+	token := parser.next()
+	switch token.Type {
+	case tokenIdent:
+		result = append(result, pathElement{Name: token.Text})
+	case tokenName:
+		name, err := parser.context.resolveName(token.Text)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, pathElement{Name: name})
+	default:
+		parser.pos--
+		return nil, parser.newError("expected attribute name")
+	}
+	for {
+		switch {
+		case parser.isPunct("."):
+			parser.next()
+			element, err := parser.parsePath()
+			if err != nil {
+				return nil, err
+			}
+			return append(result, element...), nil
+		case parser.isPunct("["):
+			parser.next()
+			if parser.peek().Type != tokenNumber {
+				return nil, parser.newError("expected list index")
+			}
+			index, err := strconv.Atoi(parser.next().Text)
+			if err != nil {
+				return nil, err
+			}
+			if err := parser.expectPunct("]"); err != nil {
+				return nil, err
+			}
+			result = append(result, pathElement{Index: index, IsIndex: true})
+		default:
+			return result, nil
+		}
+	}
+}
+
+// parseOperand parses attribute path, value placeholder or function "size".
+func (parser *parser) parseOperand() (operand, error) {
+	if parser.isFunction("size") {
+		parser.next()
+		parser.next()
+		path, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		return sizeOperand{path: path}, nil
+	}
+	if parser.peek().Type == tokenValue {
+		result, err := parser.context.resolveValue(parser.next().Text)
+		if err != nil {
+			return nil, err
+		}
+		return valueOperand{value: result}, nil
+	}
+	path, err := parser.parsePath()
+	if err != nil {
+		return nil, err
+	}
+	return pathOperand{path: path}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type pathElement struct {
+	Name    string
+	Index   int
+	IsIndex bool
+}
+
+// path is a document path like "a.b[1].c".
+type path []pathElement
+
+func (path path) String() string {
+	result := ""
+	for _, element := range path {
+		if element.IsIndex {
+			result += fmt.Sprintf("[%d]", element.Index)
+			continue
+		}
+		if result != "" {
+			result += "."
+		}
+		result += element.Name
+	}
+	return result
+}
+
+func (path path) hasIndex() bool {
+	for _, element := range path {
+		if element.IsIndex {
+			return true
+		}
+	}
+	return false
+}
+
+func (path path) get(source item) (value, bool) {
+	current, has := source[path[0].Name]
+	if !has {
+		return value{}, false
+	}
+	for _, element := range path[1:] {
+		if element.IsIndex {
+			if current.Type != valueTypeL || element.Index >= len(current.List) {
+				return value{}, false
+			}
+			current = current.List[element.Index]
+			continue
+		}
+		if current.Type != valueTypeM {
+			return value{}, false
+		}
+		if current, has = current.Map[element.Name]; !has {
+			return value{}, false
+		}
+	}
+	return current, true
+}
+
+// set sets value by the path, the parent of the path has to exist.
+func (path path) set(destination item, newValue value) error {
+	if len(path) == 1 {
+		destination[path[0].Name] = newValue
+		return nil
+	}
+	parent, has := path[:len(path)-1].get(destination)
+	if !has {
+		return fmt.Errorf(
+			"document path %q is invalid for update",
+			path.String())
+	}
+	last := path[len(path)-1]
+	if last.IsIndex {
+		if parent.Type != valueTypeL {
+			return fmt.Errorf("document path %q is not a list", path.String())
+		}
+		if last.Index >= len(parent.List) {
+			parent.List = append(parent.List, newValue)
+		} else {
+			parent.List[last.Index] = newValue
+		}
+		// List could be reallocated, so it has to be stored again.
+		return path[:len(path)-1].set(destination, parent)
+	}
+	if parent.Type != valueTypeM {
+		return fmt.Errorf("document path %q is not a map", path.String())
+	}
+	parent.Map[last.Name] = newValue
+	return nil
+}
+
+func (path path) remove(destination item) {
+	if len(path) == 1 {
+		delete(destination, path[0].Name)
+		return
+	}
+	parent, has := path[:len(path)-1].get(destination)
+	if !has {
+		return
+	}
+	last := path[len(path)-1]
+	if last.IsIndex {
+		if parent.Type != valueTypeL || last.Index >= len(parent.List) {
+			return
+		}
+		parent.List = append(
+			append([]value{}, parent.List[:last.Index]...),
+			parent.List[last.Index+1:]...)
+		_ = path[:len(path)-1].set(destination, parent)
+		return
+	}
+	if parent.Type == valueTypeM {
+		delete(parent.Map, last.Name)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type operand interface {
+	// eval returns operand value and false if the value doesn't exist.
+	eval(item) (value, bool, error)
+}
+
+type pathOperand struct{ path path }
+
+func (operand pathOperand) eval(source item) (value, bool, error) {
+	result, has := operand.path.get(source)
+	return result, has, nil
+}
+
+type valueOperand struct{ value value }
+
+func (operand valueOperand) eval(item) (value, bool, error) {
+	return operand.value, true, nil
+}
+
+type sizeOperand struct{ path path }
+
+func (operand sizeOperand) eval(source item) (value, bool, error) {
+	attr, has := operand.path.get(source)
+	if !has {
+		return value{}, false, nil
+	}
+	size, isSized := attr.getSize()
+	if !isSized {
+		return value{}, false, fmt.Errorf(
+			"function size does not support type %s of %q",
+			attr.Type,
+			operand.path.String())
+	}
+	return value{Type: valueTypeN, Scalar: strconv.Itoa(size)}, true, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// projection is a list of attributes to return.
+type projection []path
+
+func parseProjection(
+	source *string,
+	context *expressionContext,
+) (projection, error) {
+	if source == nil {
+		return nil, nil
+	}
+	parser, err := newParser(*source, context)
+	if err != nil {
+		return nil, err
+	}
+	result := projection{}
+	for {
+		path, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, path)
+		if !parser.isPunct(",") {
+			break
+		}
+		parser.next()
+	}
+	return result, parser.expectEnd()
+}
+
+// apply returns a copy of the item with projected attributes only.
+func (projection projection) apply(source item) item {
+	if projection == nil {
+		return source.clone()
+	}
+	result := item{}
+	for _, path := range projection {
+		attr, has := path.get(source)
+		if !has {
+			continue
+		}
+		if path.hasIndex() {
+			// List elements are projected as the whole root attribute,
+			// it's enough for tests.
+			result[path[0].Name] = source[path[0].Name].clone()
+			continue
+		}
+		// Nested attributes are returned inside their parents, but parents
+		// have only requested fields.
+		destination := result
+		for _, element := range path[:len(path)-1] {
+			parent, has := destination[element.Name]
+			if !has || parent.Type != valueTypeM {
+				parent = value{Type: valueTypeM, Map: item{}}
+				destination[element.Name] = parent
+			}
+			destination = parent.Map
+		}
+		destination[path[len(path)-1].Name] = attr.clone()
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
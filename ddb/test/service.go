@@ -0,0 +1,46 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	mock_ss "github.com/palchukovsky/ss/mock"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SetService sets the service mock with the given config, which is enough
+// for ddb.Client: entity names have prefix "test_", the lambda never reaches
+// its timeout and lambda scope handlers are not called. The mock is returned
+// to expect other methods, like Log.
+func SetService(
+	test *testing.T,
+	config ss.ServiceConfig,
+) *mock_ss.MockService {
+	mock := gomock.NewController(test)
+	test.Cleanup(mock.Finish)
+
+	service := mock_ss.NewMockService(mock)
+	service.EXPECT().
+		NewBuildEntityName(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(name string) string { return "test_" + name })
+	service.EXPECT().
+		SubscribeForLambdaTimeout().
+		AnyTimes().
+		Return(make(chan struct{}))
+	service.EXPECT().Config().AnyTimes().Return(config)
+	service.EXPECT().
+		AddLambdaScopeHandler(gomock.Any()).
+		AnyTimes().
+		Return(func() {})
+	ss.Set(service)
+
+	return service
+}
+
+////////////////////////////////////////////////////////////////////////////////
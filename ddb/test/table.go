@@ -0,0 +1,260 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// keySchema describes table or index key.
+type keySchema struct {
+	Partition string
+	Sort      string
+}
+
+func (schema keySchema) getFields() []string {
+	if schema.Sort == "" {
+		return []string{schema.Partition}
+	}
+	return []string{schema.Partition, schema.Sort}
+}
+
+// hasKey checks that the item has all key attributes,
+// index doesn't have items without key attributes.
+func (schema keySchema) hasKey(source item) bool {
+	for _, field := range schema.getFields() {
+		if _, has := source[field]; !has {
+			return false
+		}
+	}
+	return true
+}
+
+func (schema keySchema) extractKey(source item) item {
+	result := item{}
+	for _, field := range schema.getFields() {
+		if value, has := source[field]; has {
+			result[field] = value
+		}
+	}
+	return result
+}
+
+func (schema keySchema) compare(lhs, rhs item) int {
+	for _, field := range schema.getFields() {
+		if result, _ := lhs[field].compare(rhs[field]); result != 0 {
+			return result
+		}
+	}
+	return 0
+}
+
+// checkKeyCondition checks that the key condition has partition key equality
+// and optional sort key condition only.
+func (schema keySchema) checkKeyCondition(source condition) error {
+	isPartitionCondition := func(source condition) bool {
+		comparison, isComparison := source.(comparison)
+		if !isComparison || comparison.comparator != "=" {
+			return false
+		}
+		path, isPath := comparison.lhs.(pathOperand)
+		return isPath &&
+			len(path.path) == 1 &&
+			path.path[0].Name == schema.Partition
+	}
+	isSortPath := func(path path) bool {
+		return schema.Sort != "" && len(path) == 1 && path[0].Name == schema.Sort
+	}
+	isSortCondition := func(source condition) bool {
+		switch condition := source.(type) {
+		case comparison:
+			path, isPath := condition.lhs.(pathOperand)
+			return isPath && condition.comparator != "<>" && isSortPath(path.path)
+		case betweenCondition:
+			path, isPath := condition.operand.(pathOperand)
+			return isPath && isSortPath(path.path)
+		case functionCondition:
+			return condition.name == "begins_with" && isSortPath(condition.path)
+		}
+		return false
+	}
+
+	if isPartitionCondition(source) {
+		return nil
+	}
+	if and, isAnd := source.(andCondition); isAnd {
+		if isPartitionCondition(and.lhs) && isSortCondition(and.rhs) {
+			return nil
+		}
+		if isPartitionCondition(and.rhs) && isSortCondition(and.lhs) {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"query key condition is not supported for key %q/%q",
+		schema.Partition,
+		schema.Sort)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type table struct {
+	name    string
+	key     keySchema
+	indexes map[string]keySchema
+	items   map[string]item
+}
+
+func newTable(name string, key keySchema) *table {
+	return &table{
+		name:    name,
+		key:     key,
+		indexes: map[string]keySchema{},
+		items:   map[string]item{},
+	}
+}
+
+// getKeyID returns the unique item ID to store the item in the table.
+// Checks that the key has key attributes only and they have the right types.
+func (table *table) getKeyID(key item) (string, error) {
+	fields := table.key.getFields()
+	if len(key) != len(fields) {
+		return "", newValidationError(
+			"the provided key element does not match the schema of table %q",
+			table.name)
+	}
+	result := make([]string, len(fields))
+	for i, field := range fields {
+		value, has := key[field]
+		if !has {
+			return "", newValidationError(
+				"the provided key element %q is missing for table %q",
+				field,
+				table.name)
+		}
+		switch value.Type {
+		case valueTypeS, valueTypeN, valueTypeB:
+		default:
+			return "", newValidationError(
+				"key attribute %q of table %q has unsupported type %s",
+				field,
+				table.name,
+				value.Type)
+		}
+		result[i] = fmt.Sprintf("%s:%q", value.Type, value.Scalar)
+	}
+	return strings.Join(result, "/"), nil
+}
+
+func (table *table) getItemKeyID(source item) (string, error) {
+	return table.getKeyID(table.key.extractKey(source))
+}
+
+// checkUpdate checks that the update doesn't change key attributes.
+func (table *table) checkUpdate(old, new item) error {
+	for _, field := range table.key.getFields() {
+		if !old[field].isEqual(new[field]) {
+			return newValidationError(
+				"key attribute %q of table %q could not be updated",
+				field,
+				table.name)
+		}
+	}
+	return nil
+}
+
+func (table *table) getSchema(index *string) (keySchema, error) {
+	if index == nil {
+		return table.key, nil
+	}
+	result, has := table.indexes[*index]
+	if !has {
+		return keySchema{}, newValidationError(
+			"table %q does not have index %q",
+			table.name,
+			*index)
+	}
+	return result, nil
+}
+
+// query returns sorted by the key items which passed the key condition.
+func (table *table) query(
+	schema keySchema,
+	keyCondition condition,
+	isForward bool,
+) ([]item, error) {
+	result := []item{}
+	for _, item := range table.items {
+		if !schema.hasKey(item) {
+			continue
+		}
+		if isPassed, err := checkCondition(keyCondition, item); err != nil {
+			return nil, err
+		} else if isPassed {
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		compare := schema.compare(result[i], result[j])
+		if compare == 0 {
+			// Index could have many items with the same key.
+			compare = table.key.compare(result[i], result[j])
+		}
+		if !isForward {
+			return compare > 0
+		}
+		return compare < 0
+	})
+	return result, nil
+}
+
+// readPage reads items page by the start key and limit, returns the last
+// evaluated key if not all items have been read.
+func (table *table) readPage(
+	schema keySchema,
+	source []item,
+	exclusiveStartKey map[string]types.AttributeValue,
+	limit *int32,
+) ([]item, item, error) {
+	if len(exclusiveStartKey) != 0 {
+		startKey, err := importItem(exclusiveStartKey)
+		if err != nil {
+			return nil, nil, newValidationError("invalid start key: %v", err)
+		}
+		startID, err := table.getItemKeyID(startKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, item := range source {
+			id, _ := table.getItemKeyID(item)
+			if id == startID {
+				source = source[i+1:]
+				break
+			}
+		}
+	}
+
+	if limit == nil || int(*limit) >= len(source) {
+		return source, nil, nil
+	}
+	if *limit <= 0 {
+		return nil, nil, newValidationError("limit has to be greater than 0")
+	}
+
+	source = source[:*limit]
+	last := source[len(source)-1]
+	lastKey := table.key.extractKey(last)
+	for name, value := range schema.extractKey(last) {
+		lastKey[name] = value
+	}
+	return source, lastKey, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,321 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type updateActionType string
+
+const (
+	updateActionSet    updateActionType = "SET"
+	updateActionRemove updateActionType = "REMOVE"
+	updateActionAdd    updateActionType = "ADD"
+	updateActionDelete updateActionType = "DELETE"
+)
+
+type updateAction struct {
+	Type  updateActionType
+	Path  path
+	Value operand
+}
+
+// updateExpression is a parsed update expression.
+type updateExpression []updateAction
+
+func parseUpdate(
+	source *string,
+	context *expressionContext,
+) (updateExpression, error) {
+	if source == nil {
+		return nil, nil
+	}
+	parser, err := newParser(*source, context)
+	if err != nil {
+		return nil, err
+	}
+
+	result := updateExpression{}
+	clauses := map[updateActionType]struct{}{}
+	for parser.peek().Type != tokenEnd {
+		var actionType updateActionType
+		for _, clause := range []updateActionType{
+			updateActionSet,
+			updateActionRemove,
+			updateActionAdd,
+			updateActionDelete,
+		} {
+			if parser.isKeyword(string(clause)) {
+				actionType = clause
+				break
+			}
+		}
+		if actionType == "" {
+			return nil, parser.newError("expected update clause")
+		}
+		if _, has := clauses[actionType]; has {
+			return nil, parser.newError("clause %s is repeated", actionType)
+		}
+		clauses[actionType] = struct{}{}
+		parser.next()
+
+		for {
+			action := updateAction{Type: actionType}
+			if action.Path, err = parser.parsePath(); err != nil {
+				return nil, err
+			}
+			switch actionType {
+			case updateActionSet:
+				if err := parser.expectPunct("="); err != nil {
+					return nil, err
+				}
+				if action.Value, err = parser.parseSetValue(); err != nil {
+					return nil, err
+				}
+			case updateActionAdd, updateActionDelete:
+				if parser.peek().Type != tokenValue {
+					return nil, parser.newError("expected value")
+				}
+				if action.Value, err = parser.parseOperand(); err != nil {
+					return nil, err
+				}
+			}
+			result = append(result, action)
+			if !parser.isPunct(",") {
+				break
+			}
+			parser.next()
+		}
+	}
+
+	return result, result.checkPaths()
+}
+
+// checkPaths checks that one path is not updated by several actions.
+func (expression updateExpression) checkPaths() error {
+	for i, action := range expression {
+		for _, other := range expression[i+1:] {
+			lhs := action.Path.String()
+			rhs := other.Path.String()
+			if lhs == rhs ||
+				strings.HasPrefix(lhs, rhs+".") ||
+				strings.HasPrefix(rhs, lhs+".") ||
+				strings.HasPrefix(lhs, rhs+"[") ||
+				strings.HasPrefix(rhs, lhs+"[") {
+				return fmt.Errorf("two document paths overlap: %q and %q", lhs, rhs)
+			}
+		}
+	}
+	return nil
+}
+
+func (parser *parser) parseSetValue() (operand, error) {
+	lhs, err := parser.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+	if !parser.isPunct("+") && !parser.isPunct("-") {
+		return lhs, nil
+	}
+	isPlus := parser.next().Text == "+"
+	rhs, err := parser.parseSetOperand()
+	if err != nil {
+		return nil, err
+	}
+	return arithmeticOperand{lhs: lhs, rhs: rhs, isPlus: isPlus}, nil
+}
+
+func (parser *parser) parseSetOperand() (operand, error) {
+	switch {
+	case parser.isFunction("if_not_exists"):
+		parser.next()
+		parser.next()
+		path, err := parser.parsePath()
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.expectPunct(","); err != nil {
+			return nil, err
+		}
+		defaultValue, err := parser.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		return ifNotExistsOperand{path: path, defaultValue: defaultValue},
+			parser.expectPunct(")")
+
+	case parser.isFunction("list_append"):
+		parser.next()
+		parser.next()
+		lhs, err := parser.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.expectPunct(","); err != nil {
+			return nil, err
+		}
+		rhs, err := parser.parseSetOperand()
+		if err != nil {
+			return nil, err
+		}
+		return listAppendOperand{lhs: lhs, rhs: rhs}, parser.expectPunct(")")
+	}
+	return parser.parseOperand()
+}
+
+// apply applies update to the item copy and returns the updated copy.
+func (expression updateExpression) apply(source item) (item, error) {
+	result := source.clone()
+	for _, action := range expression {
+		// All operands are evaluated by the original item state.
+		var operand value
+		if action.Value != nil {
+			var has bool
+			var err error
+			operand, has, err = action.Value.eval(source)
+			if err != nil {
+				return nil, err
+			}
+			if !has {
+				return nil, fmt.Errorf(
+					"operand for %q does not refer to an existing attribute",
+					action.Path.String())
+			}
+		}
+
+		switch action.Type {
+
+		case updateActionSet:
+			if err := action.Path.set(result, operand); err != nil {
+				return nil, err
+			}
+
+		case updateActionRemove:
+			action.Path.remove(result)
+
+		case updateActionAdd:
+			current, has := action.Path.get(source)
+			if !has {
+				if operand.Type != valueTypeN && !operand.isSet() {
+					return nil, fmt.Errorf(
+						"ADD supports only numbers and sets, but not %s",
+						operand.Type)
+				}
+				if err := action.Path.set(result, operand); err != nil {
+					return nil, err
+				}
+				break
+			}
+			var newValue value
+			switch {
+			case current.Type == valueTypeN && operand.Type == valueTypeN:
+				lhs, _ := current.getNumber()
+				rhs, _ := operand.getNumber()
+				newValue = newNumberValue(new(big.Rat).Add(lhs, rhs))
+			case current.isSet() && current.Type == operand.Type:
+				newValue = current
+				for _, member := range operand.Set {
+					newValue = newValue.addToSet(member)
+				}
+			default:
+				return nil, fmt.Errorf(
+					"ADD operand type %s does not match attribute %q type %s",
+					operand.Type,
+					action.Path.String(),
+					current.Type)
+			}
+			if err := action.Path.set(result, newValue); err != nil {
+				return nil, err
+			}
+
+		case updateActionDelete:
+			current, has := action.Path.get(source)
+			if !has {
+				break
+			}
+			if !current.isSet() || current.Type != operand.Type {
+				return nil, fmt.Errorf(
+					"DELETE operand type %s does not match attribute %q type %s",
+					operand.Type,
+					action.Path.String(),
+					current.Type)
+			}
+			for _, member := range operand.Set {
+				current = current.deleteFromSet(member)
+			}
+			if len(current.Set) == 0 {
+				// Empty sets are not allowed.
+				action.Path.remove(result)
+				break
+			}
+			if err := action.Path.set(result, current); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type arithmeticOperand struct {
+	lhs    operand
+	rhs    operand
+	isPlus bool
+}
+
+func (arithmetic arithmeticOperand) eval(source item) (value, bool, error) {
+	numbers := make([]*big.Rat, 2)
+	for i, operand := range []operand{arithmetic.lhs, arithmetic.rhs} {
+		attr, has, err := operand.eval(source)
+		if err != nil || !has {
+			return value{}, has, err
+		}
+		if numbers[i], err = attr.getNumber(); err != nil {
+			return value{}, false, err
+		}
+	}
+	if arithmetic.isPlus {
+		return newNumberValue(new(big.Rat).Add(numbers[0], numbers[1])), true, nil
+	}
+	return newNumberValue(new(big.Rat).Sub(numbers[0], numbers[1])), true, nil
+}
+
+type ifNotExistsOperand struct {
+	path         path
+	defaultValue operand
+}
+
+func (operand ifNotExistsOperand) eval(source item) (value, bool, error) {
+	if result, has := operand.path.get(source); has {
+		return result, true, nil
+	}
+	return operand.defaultValue.eval(source)
+}
+
+type listAppendOperand struct{ lhs, rhs operand }
+
+func (listAppend listAppendOperand) eval(source item) (value, bool, error) {
+	result := value{Type: valueTypeL}
+	for _, operand := range []operand{listAppend.lhs, listAppend.rhs} {
+		list, has, err := operand.eval(source)
+		if err != nil || !has {
+			return value{}, has, err
+		}
+		if list.Type != valueTypeL {
+			return value{}, false, fmt.Errorf(
+				"list_append operand has type %s, but not list",
+				list.Type)
+		}
+		result.List = append(result.List, list.List...)
+	}
+	return result, true, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
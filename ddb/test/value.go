@@ -0,0 +1,347 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddbtest
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// valueType is a DynamoDB attribute type name, as it used by attribute_type.
+type valueType string
+
+const (
+	valueTypeS    valueType = "S"
+	valueTypeN    valueType = "N"
+	valueTypeB    valueType = "B"
+	valueTypeBool valueType = "BOOL"
+	valueTypeNull valueType = "NULL"
+	valueTypeL    valueType = "L"
+	valueTypeM    valueType = "M"
+	valueTypeSS   valueType = "SS"
+	valueTypeNS   valueType = "NS"
+	valueTypeBS   valueType = "BS"
+)
+
+// value is an attribute value in the form independent from the SDK.
+// Binaries are stored as strings to be comparable and usable as set members.
+type value struct {
+	Type valueType
+	// Scalar is the value of S, N, B and BOOL ("true" or "false") types.
+	Scalar string
+	List   []value
+	Map    item
+	// Set is sorted and unique members of SS, NS and BS types.
+	Set []string
+}
+
+// item is a database record.
+type item map[string]value
+
+func newStringValue(source string) value {
+	return value{Type: valueTypeS, Scalar: source}
+}
+
+func newNumberValue(source *big.Rat) value {
+	return value{Type: valueTypeN, Scalar: formatNumber(source)}
+}
+
+func newSetValue(setType valueType, members []string) value {
+	result := value{Type: setType}
+	for _, member := range members {
+		result = result.addToSet(member)
+	}
+	return result
+}
+
+func (v value) isSet() bool {
+	return v.Type == valueTypeSS || v.Type == valueTypeNS || v.Type == valueTypeBS
+}
+
+func (v value) getNumber() (*big.Rat, error) {
+	if v.Type != valueTypeN {
+		return nil, fmt.Errorf("value of type %s is not a number", v.Type)
+	}
+	return parseNumber(v.Scalar)
+}
+
+func (v value) hasInSet(member string) bool {
+	index := sort.SearchStrings(v.Set, member)
+	return index < len(v.Set) && v.Set[index] == member
+}
+
+func (v value) addToSet(member string) value {
+	if v.Type == valueTypeNS {
+		// Numbers in sets are compared by values, not by text.
+		if number, err := parseNumber(member); err == nil {
+			member = formatNumber(number)
+		}
+	}
+	if v.hasInSet(member) {
+		return v
+	}
+	set := append(append([]string{}, v.Set...), member)
+	sort.Strings(set)
+	return value{Type: v.Type, Set: set}
+}
+
+func (v value) deleteFromSet(member string) value {
+	result := value{Type: v.Type, Set: make([]string, 0, len(v.Set))}
+	for _, existing := range v.Set {
+		if existing != member {
+			result.Set = append(result.Set, existing)
+		}
+	}
+	return result
+}
+
+// getSize returns the value size as it is calculated by function "size".
+func (v value) getSize() (int, bool) {
+	switch v.Type {
+	case valueTypeS, valueTypeB:
+		return len(v.Scalar), true
+	case valueTypeL:
+		return len(v.List), true
+	case valueTypeM:
+		return len(v.Map), true
+	case valueTypeSS, valueTypeNS, valueTypeBS:
+		return len(v.Set), true
+	}
+	return 0, false
+}
+
+// isEqual checks values equality by DynamoDB rules, values of different types
+// are never equal.
+func (v value) isEqual(rhs value) bool {
+	if v.Type != rhs.Type {
+		return false
+	}
+	switch v.Type {
+	case valueTypeN:
+		result, isComparable := v.compare(rhs)
+		return isComparable && result == 0
+	case valueTypeL:
+		if len(v.List) != len(rhs.List) {
+			return false
+		}
+		for i := range v.List {
+			if !v.List[i].isEqual(rhs.List[i]) {
+				return false
+			}
+		}
+		return true
+	case valueTypeM:
+		if len(v.Map) != len(rhs.Map) {
+			return false
+		}
+		for name, field := range v.Map {
+			rhsField, has := rhs.Map[name]
+			if !has || !field.isEqual(rhsField) {
+				return false
+			}
+		}
+		return true
+	case valueTypeSS, valueTypeNS, valueTypeBS:
+		if len(v.Set) != len(rhs.Set) {
+			return false
+		}
+		for i := range v.Set {
+			if v.Set[i] != rhs.Set[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return v.Scalar == rhs.Scalar
+}
+
+// compare compares values of types S, N and B, returns false as the second
+// value if values could not be compared.
+func (v value) compare(rhs value) (int, bool) {
+	if v.Type != rhs.Type {
+		return 0, false
+	}
+	switch v.Type {
+	case valueTypeS:
+		return strings.Compare(v.Scalar, rhs.Scalar), true
+	case valueTypeB:
+		return bytes.Compare([]byte(v.Scalar), []byte(rhs.Scalar)), true
+	case valueTypeN:
+		lhsNumber, err := parseNumber(v.Scalar)
+		if err != nil {
+			return 0, false
+		}
+		rhsNumber, err := parseNumber(rhs.Scalar)
+		if err != nil {
+			return 0, false
+		}
+		return lhsNumber.Cmp(rhsNumber), true
+	}
+	return 0, false
+}
+
+func (v value) clone() value {
+	result := v
+	if v.List != nil {
+		result.List = make([]value, len(v.List))
+		for i, element := range v.List {
+			result.List[i] = element.clone()
+		}
+	}
+	if v.Map != nil {
+		result.Map = v.Map.clone()
+	}
+	if v.Set != nil {
+		result.Set = append([]string{}, v.Set...)
+	}
+	return result
+}
+
+func (source item) clone() item {
+	result := make(item, len(source))
+	for name, field := range source {
+		result[name] = field.clone()
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func parseNumber(source string) (*big.Rat, error) {
+	result, isParsed := new(big.Rat).SetString(source)
+	if !isParsed {
+		return nil, fmt.Errorf("failed to parse number %q", source)
+	}
+	return result, nil
+}
+
+func formatNumber(source *big.Rat) string {
+	if source.IsInt() {
+		return source.Num().String()
+	}
+	return strings.TrimRight(source.FloatString(38), "0")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func importItem(source map[string]types.AttributeValue) (item, error) {
+	result := make(item, len(source))
+	for name, attr := range source {
+		field, err := importValue(attr)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %q: %w", name, err)
+		}
+		result[name] = field
+	}
+	return result, nil
+}
+
+func importValue(source types.AttributeValue) (value, error) {
+	switch source := source.(type) {
+	case nil:
+		return value{}, fmt.Errorf("value is nil")
+	case *types.AttributeValueMemberS:
+		return newStringValue(source.Value), nil
+	case *types.AttributeValueMemberN:
+		number, err := parseNumber(source.Value)
+		if err != nil {
+			return value{}, err
+		}
+		return newNumberValue(number), nil
+	case *types.AttributeValueMemberB:
+		return value{Type: valueTypeB, Scalar: string(source.Value)}, nil
+	case *types.AttributeValueMemberBOOL:
+		return value{Type: valueTypeBool, Scalar: fmt.Sprint(source.Value)}, nil
+	case *types.AttributeValueMemberNULL:
+		return value{Type: valueTypeNull}, nil
+	case *types.AttributeValueMemberL:
+		result := value{Type: valueTypeL, List: make([]value, len(source.Value))}
+		for i, element := range source.Value {
+			var err error
+			if result.List[i], err = importValue(element); err != nil {
+				return value{}, fmt.Errorf("list element %d: %w", i, err)
+			}
+		}
+		return result, nil
+	case *types.AttributeValueMemberM:
+		fields, err := importItem(source.Value)
+		if err != nil {
+			return value{}, err
+		}
+		return value{Type: valueTypeM, Map: fields}, nil
+	case *types.AttributeValueMemberSS:
+		return newSetValue(valueTypeSS, source.Value), nil
+	case *types.AttributeValueMemberNS:
+		for _, member := range source.Value {
+			if _, err := parseNumber(member); err != nil {
+				return value{}, err
+			}
+		}
+		return newSetValue(valueTypeNS, source.Value), nil
+	case *types.AttributeValueMemberBS:
+		members := make([]string, len(source.Value))
+		for i, member := range source.Value {
+			members[i] = string(member)
+		}
+		return newSetValue(valueTypeBS, members), nil
+	}
+	return value{}, fmt.Errorf("value does not have type")
+}
+
+func exportItem(source item) map[string]types.AttributeValue {
+	result := make(map[string]types.AttributeValue, len(source))
+	for name, field := range source {
+		result[name] = exportValue(field)
+	}
+	return result
+}
+
+func exportValue(source value) types.AttributeValue {
+	switch source.Type {
+	case valueTypeS:
+		return &types.AttributeValueMemberS{Value: source.Scalar}
+	case valueTypeN:
+		return &types.AttributeValueMemberN{Value: source.Scalar}
+	case valueTypeB:
+		return &types.AttributeValueMemberB{Value: []byte(source.Scalar)}
+	case valueTypeBool:
+		return &types.AttributeValueMemberBOOL{Value: source.Scalar == "true"}
+	case valueTypeL:
+		result := &types.AttributeValueMemberL{
+			Value: make([]types.AttributeValue, len(source.List)),
+		}
+		for i, element := range source.List {
+			result.Value[i] = exportValue(element)
+		}
+		return result
+	case valueTypeM:
+		return &types.AttributeValueMemberM{Value: exportItem(source.Map)}
+	case valueTypeSS:
+		return &types.AttributeValueMemberSS{
+			Value: append([]string{}, source.Set...),
+		}
+	case valueTypeNS:
+		return &types.AttributeValueMemberNS{
+			Value: append([]string{}, source.Set...),
+		}
+	case valueTypeBS:
+		result := &types.AttributeValueMemberBS{
+			Value: make([][]byte, len(source.Set)),
+		}
+		for i, member := range source.Set {
+			result.Value[i] = []byte(member)
+		}
+		return result
+	}
+	return &types.AttributeValueMemberNULL{Value: true}
+}
+
+////////
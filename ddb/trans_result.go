@@ -5,16 +5,21 @@ package ddb
 
 import (
 	"errors"
-	"strings"
 
-	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 	"github.com/palchukovsky/ss"
 )
 
 type TransResult interface {
 	IsSuccess() bool
 	ParseConditions() ConditionalCheckResult
+	// ReadConflictItem reads the item which failed the conditional check into
+	// the buffer, returns false if there is no such item. The item is returned
+	// only for transaction expressions which requested it by
+	// ReturnOnConditionalCheckFail.
+	ReadConflictItem(ConditionalTransCheckFailPermission, RecordBuffer) bool
 
 	MarshalLogMsg(destination map[string]interface{})
 }
@@ -33,24 +38,40 @@ func newTransResult(err error, trans WriteTrans) (TransResult, error) {
 		return newSuccessfulTransResult(trans), nil
 	}
 
-	{
-		var awsErr awserr.Error
-		if errors.As(err, &awsErr) {
-			switch awsErr.Code() {
-			case dynamodb.ErrCodeConditionalCheckFailedException,
-				dynamodb.ErrCodeTransactionCanceledException:
-				{
-					result, ok := newConditionalTransCheckFail(awsErr, trans)
-					if !ok {
-						return nil, err
-					}
-					return result, nil
-				}
-			}
+	var apiErr smithy.APIError
+	if getErrorKind(err) == ErrConditionFailed && errors.As(err, &apiErr) {
+		if err := checkTransVersionConflict(apiErr, trans); err != nil {
+			return nil, err
+		}
+		result, ok := newConditionalTransCheckFail(apiErr, trans)
+		if !ok {
+			return nil, newError(err)
 		}
+		return result, nil
 	}
 
-	return nil, err
+	return nil, newError(err)
+}
+
+// checkTransVersionConflict returns ErrVersionConflict if at least one
+// versioned item has failed the conditional check and has another version
+// in the database.
+func checkTransVersionConflict(source smithy.APIError, trans WriteTrans) error {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(source, &canceled) {
+		return nil
+	}
+	for i, check := range trans.getVersionChecks() {
+		if check == nil || i >= len(canceled.CancellationReasons) {
+			continue
+		}
+		reason := canceled.CancellationReasons[i]
+		if aws.ToString(reason.Code) == cancellationReasonConditionalCheckFailed &&
+			check.isConflict(reason.Item) {
+			return check.newConflictError(source)
+		}
+	}
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -64,6 +85,12 @@ func (successfulTransResult) IsSuccess() bool { return true }
 func (successfulTransResult) ParseConditions() ConditionalCheckResult {
 	return successfullyTestedConditions{}
 }
+func (successfulTransResult) ReadConflictItem(
+	ConditionalTransCheckFailPermission,
+	RecordBuffer,
+) bool {
+	return false
+}
 func (success successfulTransResult) MarshalLogMsg(
 	destination map[string]interface{},
 ) {
@@ -81,13 +108,13 @@ func (successfullyTestedConditions) IsPassed(
 ////////////////////////////////////////////////////////////////////////////////
 
 type conditionalTransCheckFail struct {
-	err                    awserr.Error
+	err                    smithy.APIError
 	trans                  WriteTrans
 	conditionalCheckResult ConditionalCheckResult
 }
 
 func newConditionalTransCheckFail(
-	err awserr.Error,
+	err smithy.APIError,
 	trans WriteTrans,
 ) (
 	conditionalTransCheckFail,
@@ -106,53 +133,68 @@ func newConditionalTransCheckFail(
 func (conditionalTransCheckFail) IsSuccess() bool { return false }
 
 func (fail conditionalTransCheckFail) ParseConditions() ConditionalCheckResult {
-	if fail.conditionalCheckResult == nil {
-		fail.parseConditions(nil)
+	if fail.conditionalCheckResult == nil && !fail.parseConditions(nil) {
+		// Reasons are unknown, so no one condition is known as passed.
+		return conditionalCheckFails{flags: make([]bool, fail.trans.GetSize())}
 	}
 	return fail.conditionalCheckResult
 }
 
+func (fail conditionalTransCheckFail) ReadConflictItem(
+	condition ConditionalTransCheckFailPermission,
+	result RecordBuffer,
+) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(fail.err, &canceled) ||
+		condition.GetIndex() >= len(canceled.CancellationReasons) {
+		return false
+	}
+	item := canceled.CancellationReasons[condition.GetIndex()].Item
+	if len(item) == 0 {
+		return false
+	}
+	if err := UnmarshalMap(item, result); err != nil {
+		ss.S.Log().Panic(
+			ss.NewLogMsg(`failed to read transaction conflict item`).
+				AddErr(err).
+				AddDump(item))
+	}
+	return true
+}
+
+// parseConditions parses the cancellation reasons of each transaction item,
+// returns false if at least one item is failed by not allowed conditional
+// check or by any other reason, which is not about conditions.
 func (fail *conditionalTransCheckFail) parseConditions(
 	allowedToFailConditionalChecks []bool,
 ) bool {
 
-	message := fail.err.Message()
-	begin := strings.LastIndex(message, "[")
-	end := strings.LastIndex(message, "]")
-	if begin >= end {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg("failed to parse conditional check fail message").
-				Add(fail).
-				AddDump(message))
+	reasons := getTransactionCancellationReasons(fail.err)
+	if len(reasons) == 0 {
+		return false
 	}
 
-	conditions := strings.Split(message[begin+1:end], ",")
-
-	result := conditionalCheckFails{flags: make([]bool, len(conditions))}
+	result := conditionalCheckFails{flags: make([]bool, len(reasons))}
 
-	for i, condition := range conditions {
-		switch strings.TrimSpace(condition) {
-		case "None":
+	for i, reason := range reasons {
+		switch reason {
+		case cancellationReasonNone:
 			result.flags[i] = true
-		case "ConditionalCheckFailed":
+		case cancellationReasonConditionalCheckFailed:
 			if allowedToFailConditionalChecks != nil {
 				// nil-check is required as if nil - means "do not check"
-				if !allowedToFailConditionalChecks[i] {
+				if i >= len(allowedToFailConditionalChecks) ||
+					!allowedToFailConditionalChecks[i] {
 					return false
 				}
 			}
 			result.flags[i] = false
 		default:
-			ss.S.Log().Panic(
-				ss.
-					NewLogMsg(
-						"unknown conditional check fail message status %q",
-						condition).
-					Add(fail).
-					AddDump(message))
+			// Throttling, transaction conflict, validation and other reasons
+			// are not about the data, so the result could not be used as
+			// conditional check result.
+			return false
 		}
-
 	}
 
 	fail.conditionalCheckResult = result
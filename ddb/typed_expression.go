@@ -0,0 +1,470 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Condition is a typed condition, filter or key condition expression,
+// it generates attribute name and value placeholders by itself.
+type Condition struct {
+	build func(*expressionBuilder) string
+}
+
+// Operand is an operand of typed expression: attribute path, value or
+// function result.
+type Operand struct {
+	build func(*expressionBuilder) string
+}
+
+// UpdateAction is an action of typed update expression.
+type UpdateAction struct {
+	clause string
+	build  func(*expressionBuilder) string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Name creates operand by attribute path, path elements are separated by
+// dot, list elements are set by index in brackets, like "a.b[1].c".
+func Name(path string) Operand {
+	return Operand{build: func(builder *expressionBuilder) string {
+		return builder.path(path)
+	}}
+}
+
+// Value creates operand by value.
+func Value(value interface{}) Operand {
+	return Operand{build: func(builder *expressionBuilder) string {
+		return builder.value(value)
+	}}
+}
+
+// Size creates operand with the attribute size.
+func Size(path string) Operand {
+	return Operand{build: func(builder *expressionBuilder) string {
+		return "size(" + builder.path(path) + ")"
+	}}
+}
+
+func (lhs Operand) Equal(rhs Operand) Condition    { return lhs.compare("=", rhs) }
+func (lhs Operand) NotEqual(rhs Operand) Condition { return lhs.compare("<>", rhs) }
+func (lhs Operand) Less(rhs Operand) Condition     { return lhs.compare("<", rhs) }
+func (lhs Operand) Greater(rhs Operand) Condition  { return lhs.compare(">", rhs) }
+func (lhs Operand) LessOrEqual(rhs Operand) Condition {
+	return lhs.compare("<=", rhs)
+}
+func (lhs Operand) GreaterOrEqual(rhs Operand) Condition {
+	return lhs.compare(">=", rhs)
+}
+
+func (lhs Operand) compare(comparator string, rhs Operand) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		return lhs.build(builder) + " " + comparator + " " + rhs.build(builder)
+	}}
+}
+
+func (operand Operand) Between(low, high Operand) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		return operand.build(builder) +
+			" between " + low.build(builder) +
+			" and " + high.build(builder)
+	}}
+}
+
+func (operand Operand) In(options ...Operand) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		result := make([]string, len(options))
+		for i, option := range options {
+			result[i] = option.build(builder)
+		}
+		return operand.build(builder) + " in (" + strings.Join(result, ", ") + ")"
+	}}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// And creates condition which is passed if all conditions are passed.
+func And(conditions ...Condition) Condition {
+	return joinConditions(" and ", conditions)
+}
+
+// Or creates condition which is passed if any condition is passed.
+func Or(conditions ...Condition) Condition {
+	return joinConditions(" or ", conditions)
+}
+
+func joinConditions(operator string, conditions []Condition) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		result := make([]string, len(conditions))
+		for i, condition := range conditions {
+			result[i] = "(" + condition.build(builder) + ")"
+		}
+		return strings.Join(result, operator)
+	}}
+}
+
+// Not creates condition which is passed if the condition is not passed.
+func Not(condition Condition) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		return "not (" + condition.build(builder) + ")"
+	}}
+}
+
+func AttributeExists(path string) Condition {
+	return newFunctionCondition("attribute_exists", path)
+}
+
+func AttributeNotExists(path string) Condition {
+	return newFunctionCondition("attribute_not_exists", path)
+}
+
+// AttributeType checks attribute type by DynamoDB type name, like "S" or "N".
+func AttributeType(path string, typeName string) Condition {
+	return newFunctionCondition("attribute_type", path, Value(typeName))
+}
+
+func BeginsWith(path string, prefix Operand) Condition {
+	return newFunctionCondition("begins_with", path, prefix)
+}
+
+func Contains(path string, operand Operand) Condition {
+	return newFunctionCondition("contains", path, operand)
+}
+
+func newFunctionCondition(name string, path string, args ...Operand) Condition {
+	return Condition{build: func(builder *expressionBuilder) string {
+		result := make([]string, 0, len(args)+1)
+		result = append(result, builder.path(path))
+		for _, arg := range args {
+			result = append(result, arg.build(builder))
+		}
+		return name + "(" + strings.Join(result, ", ") + ")"
+	}}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	updateClauseSet    = "set"
+	updateClauseRemove = "remove"
+	updateClauseAdd    = "add"
+	updateClauseDelete = "delete"
+)
+
+// updateClauses has the order of clauses in the update expression.
+var updateClauses = []string{
+	updateClauseSet,
+	updateClauseRemove,
+	updateClauseAdd,
+	updateClauseDelete,
+}
+
+// Set creates update action which sets the attribute, the value could be
+// Operand or any value to serialize.
+func Set(path string, value interface{}) UpdateAction {
+	operand, isOperand := value.(Operand)
+	if !isOperand {
+		operand = Value(value)
+	}
+	return UpdateAction{
+		clause: updateClauseSet,
+		build: func(builder *expressionBuilder) string {
+			return builder.path(path) + " = " + operand.build(builder)
+		},
+	}
+}
+
+// Remove creates update action which removes the attribute.
+func Remove(path string) UpdateAction {
+	return UpdateAction{
+		clause: updateClauseRemove,
+		build: func(builder *expressionBuilder) string {
+			return builder.path(path)
+		},
+	}
+}
+
+// SetIfNotExists creates update action which sets the attribute only if
+// the item doesn't have it.
+func SetIfNotExists(path string, value interface{}) UpdateAction {
+	return Set(path, Operand{build: func(builder *expressionBuilder) string {
+		return "if_not_exists(" +
+			builder.path(path) + ", " +
+			builder.value(value) + ")"
+	}})
+}
+
+// Increment creates update action which increases the numeric attribute
+// by delta, the attribute is created with the delta value if it doesn't
+// exist. Delta could be negative.
+func Increment(path string, delta interface{}) UpdateAction {
+	return Set(path, Operand{build: func(builder *expressionBuilder) string {
+		name := builder.path(path)
+		return "if_not_exists(" + name + ", " + builder.value(0) + ") + " +
+			builder.value(delta)
+	}})
+}
+
+// AppendToList creates update action which appends values to the end of
+// the list attribute, the list is created if it doesn't exist. Values have
+// to be a slice.
+func AppendToList(path string, values interface{}) UpdateAction {
+	return Set(path, Operand{build: func(builder *expressionBuilder) string {
+		name := builder.path(path)
+		return "list_append(if_not_exists(" + name + ", " +
+			builder.value([]interface{}{}) + "), " +
+			builder.value(values) + ")"
+	}})
+}
+
+// Add creates update action which adds the number to the numeric attribute,
+// or adds values to the set attribute. Values for the set have to be
+// a slice of strings, numbers or binaries, the slice is stored as a set.
+func Add(path string, value interface{}) UpdateAction {
+	return UpdateAction{
+		clause: updateClauseAdd,
+		build: func(builder *expressionBuilder) string {
+			return builder.path(path) + " " + builder.setOrValue(value)
+		},
+	}
+}
+
+// DeleteFromSet creates update action which deletes values from the set
+// attribute. Values have to be a slice of strings, numbers or binaries.
+func DeleteFromSet(path string, values interface{}) UpdateAction {
+	return UpdateAction{
+		clause: updateClauseDelete,
+		build: func(builder *expressionBuilder) string {
+			return builder.path(path) + " " + builder.setOrValue(values)
+		},
+	}
+}
+
+// buildUpdateActions builds update expression actions grouped by clause.
+func buildUpdateActions(
+	actions []UpdateAction,
+	builder *expressionBuilder,
+) map[string][]string {
+	result := map[string][]string{}
+	for _, action := range actions {
+		result[action.clause] = append(result[action.clause], action.build(builder))
+	}
+	return result
+}
+
+// buildUpdateExpression builds the whole update expression.
+func buildUpdateExpression(
+	actions []UpdateAction,
+	builder *expressionBuilder,
+) string {
+	clauses := buildUpdateActions(actions, builder)
+	result := make([]string, 0, len(clauses))
+	for _, clause := range updateClauses {
+		if actions := clauses[clause]; len(actions) != 0 {
+			result = append(result, clause+" "+strings.Join(actions, ", "))
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// expressionBuilder adds attribute names and values into the request
+// and generates placeholders for them.
+type expressionBuilder struct {
+	names  *map[string]string
+	values *map[string]types.AttributeValue
+	err    error
+}
+
+func newExpressionBuilder(
+	names *map[string]string,
+	values *map[string]types.AttributeValue,
+) *expressionBuilder {
+	return &expressionBuilder{names: names, values: values}
+}
+
+func (builder *expressionBuilder) buildCondition(condition Condition) (
+	*string,
+	error,
+) {
+	result := condition.build(builder)
+	return aws.String(result), builder.err
+}
+
+// addCondition builds the condition and adds it to the expression by "and".
+func (builder *expressionBuilder) addCondition(
+	expression **string,
+	condition Condition,
+) error {
+	source := "(" + condition.build(builder) + ")"
+	if *expression == nil {
+		*expression = &source
+	} else {
+		*expression = aws.String(**expression + " and " + source)
+	}
+	return builder.err
+}
+
+func (builder *expressionBuilder) path(path string) string {
+	elements := strings.Split(path, ".")
+	for i, element := range elements {
+		name := element
+		index := ""
+		if bracket := strings.Index(element, "["); bracket >= 0 {
+			name = element[:bracket]
+			index = element[bracket:]
+		}
+		elements[i] = builder.name(name) + index
+	}
+	return strings.Join(elements, ".")
+}
+
+func (builder *expressionBuilder) name(name string) string {
+	if *builder.names == nil {
+		*builder.names = map[string]string{}
+	}
+	if typedExpressionNameRegexp.MatchString(name) {
+		// Readable placeholder is used if it's possible, the same placeholder
+		// could be already added by aliasReservedInString.
+		result := "#" + name
+		if existing, has := (*builder.names)[result]; !has ||
+			existing == name {
+			(*builder.names)[result] = name
+			return result
+		}
+	}
+	for i := 0; ; i++ {
+		result := fmt.Sprintf("#en%d", i)
+		existing, has := (*builder.names)[result]
+		if !has || existing == name {
+			(*builder.names)[result] = name
+			return result
+		}
+	}
+}
+
+func (builder *expressionBuilder) value(source interface{}) string {
+	value, err := marshal(source)
+	if err != nil {
+		if builder.err == nil {
+			builder.err = newSerializationError(err, "failed to serialize value")
+		}
+		value = &types.AttributeValueMemberNULL{Value: true}
+	}
+	return builder.addValue(value)
+}
+
+func (builder *expressionBuilder) addValue(value types.AttributeValue) string {
+	if *builder.values == nil {
+		*builder.values = map[string]types.AttributeValue{}
+	}
+	for i := 0; ; i++ {
+		result := fmt.Sprintf(":ev%d", i)
+		if _, has := (*builder.values)[result]; !has {
+			(*builder.values)[result] = value
+			return result
+		}
+	}
+}
+
+// setOrValue adds the value as a set if the value is a slice, or as is
+// otherwise.
+func (builder *expressionBuilder) setOrValue(source interface{}) string {
+	value, err := marshal(source)
+	if err == nil {
+		value, err = convertListToSet(value)
+	}
+	if err != nil {
+		if builder.err == nil {
+			builder.err = newSerializationError(err, "failed to serialize set")
+		}
+		value = &types.AttributeValueMemberNULL{Value: true}
+	}
+	return builder.addValue(value)
+}
+
+var typedExpressionNameRegexp = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// convertListToSet converts the list of strings, numbers or binaries to set,
+// other values are returned as is.
+func convertListToSet(source types.AttributeValue) (types.AttributeValue, error) {
+	list, isList := source.(*types.AttributeValueMemberL)
+	if !isList {
+		return source, nil
+	}
+	if len(list.Value) == 0 {
+		return nil, errors.New("set could not be empty")
+	}
+	switch list.Value[0].(type) {
+	case *types.AttributeValueMemberS:
+		result := &types.AttributeValueMemberSS{}
+		for _, member := range list.Value {
+			if member, isValid := member.(*types.AttributeValueMemberS); isValid {
+				result.Value = append(result.Value, member.Value)
+			}
+		}
+		if len(result.Value) == len(list.Value) {
+			return result, nil
+		}
+	case *types.AttributeValueMemberN:
+		result := &types.AttributeValueMemberNS{}
+		for _, member := range list.Value {
+			if member, isValid := member.(*types.AttributeValueMemberN); isValid {
+				result.Value = append(result.Value, member.Value)
+			}
+		}
+		if len(result.Value) == len(list.Value) {
+			return result, nil
+		}
+	case *types.AttributeValueMemberB:
+		result := &types.AttributeValueMemberBS{}
+		for _, member := range list.Value {
+			if member, isValid := member.(*types.AttributeValueMemberB); isValid {
+				result.Value = append(result.Value, member.Value)
+			}
+		}
+		if len(result.Value) == len(list.Value) {
+			return result, nil
+		}
+	}
+	return nil, errors.New(
+		"set members have to be strings, numbers or binaries of one type")
+}
+
+// addToUpdateExpression adds the action into the update expression, into
+// the existing clause if the expression has it.
+func addToUpdateExpression(
+	expression string,
+	action UpdateAction,
+	builder *expressionBuilder,
+) string {
+	source := action.build(builder)
+	clause := updateClauseRegexps[action.clause]
+	if location := clause.FindStringIndex(expression); location != nil {
+		return expression[:location[1]] + source + ", " + expression[location[1]:]
+	}
+	if expression != "" {
+		expression += " "
+	}
+	return expression + action.clause + " " + source
+}
+
+var updateClauseRegexps = func() map[string]*regexp.Regexp {
+	result := make(map[string]*regexp.Regexp, len(updateClauses))
+	for _, clause := range updateClauses {
+		result[clause] = regexp.MustCompile(`(?i)(^|\s)` + clause + `\s+`)
+	}
+	return result
+}()
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,98 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_TypedExpression_Condition(test *testing.T) {
+	assert := assert.New(test)
+	client, db := newTestClient(test)
+
+	for _, record := range []testData{
+		newTestData("1", "a", 30),
+		newTestData("2", "b", 20),
+		newTestData("3", "a", 10),
+		newTestData("4", "ab", 20),
+	} {
+		client.CreateOrReplace(record).Request()
+	}
+
+	var index testUserIndex
+	all := client.
+		Index(&index).
+		QueryExpr(
+			ddb.And(
+				ddb.Name("user").Equal(ddb.Value("a")),
+				ddb.Name("time").Between(ddb.Value(10), ddb.Value(25)))).
+		RequestAll()
+	assert.Equal(1, all.GetSize())
+	assert.Equal("3", all.GetAt(0).(*testUserIndex).ID)
+
+	var record testBuffer
+	all = client.
+		Scan(&record).
+		FilterExpr(
+			ddb.Or(
+				ddb.BeginsWith("user", ddb.Value("a")),
+				ddb.Name("time").In(ddb.Value(20)))).
+		RequestAll()
+	assert.Equal(4, all.GetSize())
+	all = client.
+		Scan(&record).
+		FilterExpr(
+			ddb.And(
+				ddb.Size("user").Greater(ddb.Value(1)),
+				ddb.Not(ddb.AttributeNotExists("time")))).
+		RequestAll()
+	assert.Equal(1, all.GetSize())
+	assert.Equal("ab", all.GetAt(0).(*testBuffer).User)
+
+	{
+		update := client.
+			Update(newTestKey("1")).
+			Apply(ddb.Set("val", 7), ddb.Set("user", "c"), ddb.Remove("time")).
+			ConditionExpr(ddb.Name("user").Equal(ddb.Value("a")))
+		var updated testBuffer
+		assert.True(update.RequestAndReturn(&updated).IsSuccess())
+		assert.Equal(7, updated.Value)
+		assert.Equal("c", updated.User)
+		assert.Equal(0, updated.Time)
+	}
+	{
+		update := client.
+			Update(newTestKey("1")).
+			Apply(ddb.Set("val", ddb.Name("time"))).
+			ConditionExpr(ddb.AttributeExists("time"))
+		update.AllowConditionalCheckFail()
+		assert.False(update.Request().IsSuccess())
+	}
+	{
+		create := client.
+			CreateOrReplace(newTestData("1", "d", 1)).
+			ConditionExpr(ddb.Name("val").GreaterOrEqual(ddb.Value(7)))
+		assert.True(create.Request().IsSuccess())
+	}
+
+	trans := ddb.NewWriteTrans(false)
+	trans.
+		Check(newTestKey("2")).
+		ConditionExpr(ddb.Name("user").NotEqual(ddb.Value("a")))
+	trans.
+		UpdateExpr(newTestKey("3"), ddb.Set("val", 1)).
+		ConditionExpr(ddb.Name("time").LessOrEqual(ddb.Value(10)))
+	trans.
+		Delete(newTestKey("4")).
+		ConditionExpr(ddb.Contains("user", ddb.Value("b")))
+	assert.True(client.Write(trans).IsSuccess())
+	assert.Equal(3, db.GetSize(testRecord{}))
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -7,9 +7,9 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -20,6 +20,19 @@ type Update interface {
 	Set(expression string) Update
 	Remove(fieldName string) Update
 	Expression(expression string) Update
+	// Apply adds typed update actions.
+	Apply(actions ...UpdateAction) Update
+
+	// Add adds the number to the numeric attribute, or values to the set.
+	Add(field string, value interface{}) Update
+	// DeleteFromSet deletes values from the set.
+	DeleteFromSet(field string, values interface{}) Update
+	// Increment increases the numeric attribute, creates it if it doesn't exist.
+	Increment(field string, delta interface{}) Update
+	// AppendToList appends values to the list, creates it if it doesn't exist.
+	AppendToList(field string, values interface{}) Update
+	// SetIfNotExists sets the attribute only if the item doesn't have it.
+	SetIfNotExists(field string, value interface{}) Update
 
 	Values(Values) Update
 	Value(name string, value interface{}) Update
@@ -27,26 +40,34 @@ type Update interface {
 	Alias(name, value string) Update
 
 	Condition(string) Update
+	ConditionExpr(Condition) Update
 
 	Request() Result
 	RequestAndReturn(RecordBuffer) Result
+	RequestE() (Result, error)
+	RequestAndReturnE(RecordBuffer) (Result, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 func (client *client) Update(key KeyRecord) Update {
-	result := newUpdateTemplate(client.db, key)
+	result := newUpdateTemplate(client, key)
 	result.SetKey(key.GetKey())
+	if result.version = newVersionCheck(key); result.version != nil {
+		result.
+			ConditionExpr(result.version.getCondition()).
+			Apply(result.version.getUpdate())
+	}
 	return result
 }
 
 func newUpdateTemplate(
-	db *dynamodb.DynamoDB,
+	client *client,
 	record Record,
 ) *update {
 	result := update{
 		checkedExpression: newCheckedExpression(),
-		db:                db,
+		client:            client,
 		Input: dynamodb.UpdateItemInput{
 			TableName: aws.String(ss.S.NewBuildEntityName(record.GetTable())),
 		},
@@ -63,11 +84,15 @@ func newUpdateTemplate(
 type update struct {
 	checkedExpression
 
-	db      *dynamodb.DynamoDB       `json:"-"`
+	client  *client                  `json:"-"`
 	Input   dynamodb.UpdateItemInput `json:"input"`
 	Expr    string                   `json:"expression"`
 	Sets    []string                 `json:"sets"`
 	Removes []string                 `json:"removes"`
+	Adds    []string                 `json:"adds"`
+	Deletes []string                 `json:"deletes"`
+	version *versionCheck
+	err     error
 }
 
 func (update *update) Set(expression string) Update {
@@ -88,8 +113,39 @@ func (update *update) Expression(expression string) Update {
 	return update
 }
 
+func (update *update) Apply(actions ...UpdateAction) Update {
+	builder := update.newExpressionBuilder()
+	clauses := buildUpdateActions(actions, builder)
+	update.Sets = append(update.Sets, clauses[updateClauseSet]...)
+	update.Removes = append(update.Removes, clauses[updateClauseRemove]...)
+	update.Adds = append(update.Adds, clauses[updateClauseAdd]...)
+	update.Deletes = append(update.Deletes, clauses[updateClauseDelete]...)
+	update.setErr(builder.err)
+	return update
+}
+
+func (update *update) Add(field string, value interface{}) Update {
+	return update.Apply(Add(field, value))
+}
+
+func (update *update) DeleteFromSet(field string, values interface{}) Update {
+	return update.Apply(DeleteFromSet(field, values))
+}
+
+func (update *update) Increment(field string, delta interface{}) Update {
+	return update.Apply(Increment(field, delta))
+}
+
+func (update *update) AppendToList(field string, values interface{}) Update {
+	return update.Apply(AppendToList(field, values))
+}
+
+func (update *update) SetIfNotExists(field string, value interface{}) Update {
+	return update.Apply(SetIfNotExists(field, value))
+}
+
 func (update *update) Values(values Values) Update {
-	values.Marshal(&update.Input.ExpressionAttributeValues)
+	update.setErr(values.marshal(&update.Input.ExpressionAttributeValues))
 	return update
 }
 
@@ -99,11 +155,11 @@ func (update *update) Value(name string, value interface{}) Update {
 
 func (update *update) Alias(name, value string) Update {
 	if update.Input.ExpressionAttributeNames == nil {
-		update.Input.ExpressionAttributeNames = map[string]*string{
-			name: aws.String(value),
+		update.Input.ExpressionAttributeNames = map[string]string{
+			name: value,
 		}
 	} else {
-		update.Input.ExpressionAttributeNames[name] = aws.String(value)
+		update.Input.ExpressionAttributeNames[name] = value
 	}
 	return update
 }
@@ -115,66 +171,120 @@ func (update *update) Condition(condition string) Update {
 	return update
 }
 
+func (update *update) ConditionExpr(condition Condition) Update {
+	update.setErr(
+		update.
+			newExpressionBuilder().
+			addCondition(&update.Input.ConditionExpression, condition))
+	return update
+}
+
+func (update *update) newExpressionBuilder() *expressionBuilder {
+	return newExpressionBuilder(
+		&update.Input.ExpressionAttributeNames,
+		&update.Input.ExpressionAttributeValues)
+}
+
+func (update *update) setErr(err error) {
+	if err != nil && update.err == nil {
+		update.err = err
+	}
+}
+
 func (update *update) Request() Result {
-	result, _ := update.request()
+	result, err := update.RequestE()
+	if err != nil {
+		update.panic(err)
+	}
 	return result
 }
 
 func (update *update) RequestAndReturn(resultRecord RecordBuffer) Result {
-	update.Input.ReturnValues = aws.String(dynamodb.ReturnValueAllNew)
-	result, output := update.request()
-	if !result.IsSuccess() {
-		return result
-	}
-	err := dynamodbattribute.UnmarshalMap(output.Attributes, resultRecord)
+	result, err := update.RequestAndReturnE(resultRecord)
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					"failed to read update response from table %q",
-					update.getTable()).
-				AddErr(err))
+		update.panic(err)
 	}
 	return result
 }
 
-func (update *update) request() (Result, *dynamodb.UpdateItemOutput) {
+func (update *update) RequestE() (Result, error) {
+	result, _, err := update.request()
+	return result, err
+}
+
+func (update *update) RequestAndReturnE(
+	resultRecord RecordBuffer,
+) (Result, error) {
+	update.Input.ReturnValues = types.ReturnValueAllNew
+	result, output, err := update.request()
+	if err != nil || !result.IsSuccess() {
+		return result, err
+	}
+	err = UnmarshalMap(output.Attributes, resultRecord)
+	if err != nil {
+		return false, newSerializationError(err, "failed to read update response")
+	}
+	return result, nil
+}
+
+func (update *update) request() (Result, *dynamodb.UpdateItemOutput, error) {
+	if update.err != nil {
+		return false, nil, update.err
+	}
 	{
-		expression := make([]string, 0, 3)
+		expression := make([]string, 0, 5)
 		if update.Expr != "" {
 			expression = append(expression, update.Expr)
 		}
-		if sets := strings.Join(update.Sets, ","); sets != "" {
-			expression = append(expression, "set "+sets)
-		}
-		if removes := strings.Join(update.Removes, ","); removes != "" {
-			expression = append(expression, "remove "+removes)
+		for _, clause := range []struct {
+			name    string
+			actions []string
+		}{
+			{name: updateClauseSet, actions: update.Sets},
+			{name: updateClauseRemove, actions: update.Removes},
+			{name: updateClauseAdd, actions: update.Adds},
+			{name: updateClauseDelete, actions: update.Deletes},
+		} {
+			if actions := strings.Join(clause.actions, ","); actions != "" {
+				expression = append(expression, clause.name+" "+actions)
+			}
 		}
 		update.Input.UpdateExpression = aliasReservedInString(
 			strings.Join(expression, " "),
 			&update.Input.ExpressionAttributeNames)
 	}
-	request, output := update.db.UpdateItemRequest(&update.Input)
-	result, err := newResult(request.Send(), update.isConditionalCheckFailAllowed)
+	ctx, cancel := update.client.newRequestContext()
+	defer cancel()
+	var output *dynamodb.UpdateItemOutput
+	err := update.client.retry(ctx, func() (err error) {
+		output, err = update.client.db.UpdateItem(ctx, &update.Input)
+		return err
+	})
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg("failed to update item in table %q", update.getTable()).
-				AddDump(update).
-				AddErr(err))
+		err = update.client.checkVersionConflict(
+			ctx,
+			err,
+			update.version,
+			update.Input.TableName,
+			update.Input.Key)
 	}
-	return result, output
+	result, err := newResult(err, update.isConditionalCheckFailAllowed)
+	return result, output, err
+}
+
+func (update *update) panic(err error) {
+	ss.S.Log().Panic(
+		ss.
+			NewLogMsg("failed to update item in table %q", update.getTable()).
+			AddDump(update).
+			AddErr(err))
 }
 
 func (update *update) SetKey(source interface{}) {
-	key, err := dynamodbattribute.MarshalMap(source)
+	key, err := MarshalMap(source)
 	if err != nil {
-		ss.S.Log().Panic(
-			ss.
-				NewLogMsg(
-					`failed to serialize key to update table %q`,
-					update.getTable()).
-				AddErr(err))
+		update.err = newSerializationError(err, "failed to serialize key")
+		return
 	}
 	update.Input.Key = key
 }
@@ -0,0 +1,79 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testCollectionsBuffer struct {
+	testKey
+	Tags    []string `json:"tags"`
+	Log     []int    `json:"log"`
+	Counter int      `json:"counter"`
+	User    string   `json:"user"`
+}
+
+func (record *testCollectionsBuffer) Clear() {
+	*record = testCollectionsBuffer{}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Update_Actions(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	client.CreateOrReplace(newTestData("1", "a", 10)).Request()
+
+	var record testCollectionsBuffer
+	update := client.
+		Update(newTestKey("1")).
+		Set("#t = :t").Value(":t", 11).Alias("#t", "time").
+		Add("tags", []string{"x", "y", "z"}).
+		Increment("counter", 2).
+		AppendToList("log", []int{1}).
+		SetIfNotExists("user", "b")
+	assert.True(update.RequestAndReturn(&record).IsSuccess())
+	assert.ElementsMatch([]string{"x", "y", "z"}, record.Tags)
+	assert.Equal([]int{1}, record.Log)
+	assert.Equal(2, record.Counter)
+	assert.Equal("a", record.User)
+
+	update = client.
+		Update(newTestKey("1")).
+		Remove("time").
+		DeleteFromSet("tags", []string{"x", "z"}).
+		Increment("counter", -3).
+		AppendToList("log", []int{2, 3})
+	assert.True(update.RequestAndReturn(&record).IsSuccess())
+	assert.Equal([]string{"y"}, record.Tags)
+	assert.Equal([]int{1, 2, 3}, record.Log)
+	assert.Equal(-1, record.Counter)
+
+	update = client.Update(newTestKey("1")).Add("tags", []string{})
+	_, err := update.RequestE()
+	assert.ErrorIs(err, ddb.ErrValidation)
+
+	trans := ddb.NewWriteTrans(false)
+	trans.
+		Update(newTestKey("1"), "set #u = :u").
+		Value(":u", "c").
+		Alias("#u", "user").
+		Add("counter", 10).
+		DeleteFromSet("tags", []string{"y"})
+	assert.True(client.Write(trans).IsSuccess())
+	record = testCollectionsBuffer{testKey: newTestKey("1")}
+	assert.True(client.Find(&record).Request())
+	assert.Empty(record.Tags)
+	assert.Equal(9, record.Counter)
+	assert.Equal("c", record.User)
+}
+
+////////////////////////////////////////////////////////////////////////////////
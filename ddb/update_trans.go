@@ -6,9 +6,8 @@ package ddb
 import (
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -19,22 +18,68 @@ type UpdateTrans interface {
 	Value(name string, value interface{}) UpdateTrans
 	Alias(name, value string) UpdateTrans
 	Condition(string) UpdateTrans
+	ConditionExpr(Condition) UpdateTrans
+	ReturnOnConditionalCheckFail() UpdateTrans
+
+	// Apply adds typed update actions into the update expression.
+	Apply(actions ...UpdateAction) UpdateTrans
+	Add(field string, value interface{}) UpdateTrans
+	DeleteFromSet(field string, values interface{}) UpdateTrans
+	Increment(field string, delta interface{}) UpdateTrans
+	AppendToList(field string, values interface{}) UpdateTrans
+	SetIfNotExists(field string, value interface{}) UpdateTrans
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
 func (trans *writeTrans) Update(key KeyRecord, update string) UpdateTrans {
-	input := &dynamodb.Update{
+	result := trans.newUpdateTrans(key)
+	result.input.UpdateExpression = aliasReservedInString(
+		update,
+		&result.input.ExpressionAttributeNames)
+	if result.version != nil {
+		result.input.UpdateExpression = aws.String(
+			result.version.addToUpdateExpression(
+				*result.input.UpdateExpression,
+				result.newExpressionBuilder()))
+	}
+	return result
+}
+
+func (trans *writeTrans) UpdateExpr(
+	key KeyRecord,
+	actions ...UpdateAction,
+) UpdateTrans {
+	result := trans.newUpdateTrans(key)
+	if result.version != nil {
+		actions = append(actions, result.version.getUpdate())
+	}
+	builder := result.newExpressionBuilder()
+	result.input.UpdateExpression = aws.String(
+		buildUpdateExpression(actions, builder))
+	if builder.err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg(
+					"failed to build update expression for table %q",
+					key.GetTable()).
+				AddErr(builder.err))
+	}
+	return result
+}
+
+func (trans *writeTrans) newUpdateTrans(key KeyRecord) *updateTrans {
+	input := &types.Update{
 		TableName: aws.String(ss.S.NewBuildEntityName(key.GetTable())),
 	}
 	result := updateTrans{
 		writeTransExpression: newWriteTransExpression(
 			trans,
-			dynamodb.TransactWriteItem{Update: input}),
+			types.TransactWriteItem{Update: input}),
 		input: input,
 	}
 	var err error
-	result.input.Key, err = dynamodbattribute.MarshalMap(key.GetKey())
+	result.input.Key, err = MarshalMap(key.GetKey())
 	if err != nil {
 		ss.S.Log().Panic(
 			ss.
@@ -45,15 +90,18 @@ func (trans *writeTrans) Update(key KeyRecord, update string) UpdateTrans {
 				AddDump(input).
 				AddErr(err))
 	}
-	result.input.UpdateExpression = aliasReservedInString(
-		update,
-		&result.input.ExpressionAttributeNames)
 	result.input.ConditionExpression = aws.String(
 		fmt.Sprintf(
 			"attribute_exists(%s)",
 			aliasReservedWord(
 				key.GetKeyPartitionField(),
 				&result.input.ExpressionAttributeNames)))
+	if result.version = newVersionCheck(key); result.version != nil {
+		result.ConditionExpr(result.version.getCondition())
+		result.setVersionCheck(
+			result.version,
+			&result.input.ReturnValuesOnConditionCheckFailure)
+	}
 	return &result
 }
 
@@ -61,7 +109,14 @@ func (trans *writeTrans) Update(key KeyRecord, update string) UpdateTrans {
 
 type updateTrans struct {
 	writeTransExpression
-	input *dynamodb.Update
+	input   *types.Update
+	version *versionCheck
+}
+
+func (trans *updateTrans) newExpressionBuilder() *expressionBuilder {
+	return newExpressionBuilder(
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues)
 }
 
 func (trans *updateTrans) Values(values Values) UpdateTrans {
@@ -85,4 +140,69 @@ func (trans *updateTrans) Condition(condition string) UpdateTrans {
 	return trans
 }
 
+func (trans *updateTrans) ConditionExpr(condition Condition) UpdateTrans {
+	trans.addCondition(
+		condition,
+		&trans.input.ConditionExpression,
+		&trans.input.ExpressionAttributeNames,
+		&trans.input.ExpressionAttributeValues)
+	return trans
+}
+
+func (trans *updateTrans) Apply(actions ...UpdateAction) UpdateTrans {
+	builder := trans.newExpressionBuilder()
+	expression := aws.ToString(trans.input.UpdateExpression)
+	for _, action := range actions {
+		expression = addToUpdateExpression(expression, action, builder)
+	}
+	if builder.err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg(
+					"failed to build update expression for table %q",
+					*trans.input.TableName).
+				AddErr(builder.err))
+	}
+	trans.input.UpdateExpression = aws.String(expression)
+	return trans
+}
+
+func (trans *updateTrans) Add(field string, value interface{}) UpdateTrans {
+	return trans.Apply(Add(field, value))
+}
+
+func (trans *updateTrans) DeleteFromSet(
+	field string,
+	values interface{},
+) UpdateTrans {
+	return trans.Apply(DeleteFromSet(field, values))
+}
+
+func (trans *updateTrans) Increment(
+	field string,
+	delta interface{},
+) UpdateTrans {
+	return trans.Apply(Increment(field, delta))
+}
+
+func (trans *updateTrans) AppendToList(
+	field string,
+	values interface{},
+) UpdateTrans {
+	return trans.Apply(AppendToList(field, values))
+}
+
+func (trans *updateTrans) SetIfNotExists(
+	field string,
+	value interface{},
+) UpdateTrans {
+	return trans.Apply(SetIfNotExists(field, value))
+}
+
+func (trans *updateTrans) ReturnOnConditionalCheckFail() UpdateTrans {
+	trans.returnOnConditionalCheckFail(
+		&trans.input.ReturnValuesOnConditionCheckFailure)
+	return trans
+}
+
 ////////////////////////////////////////////////////////////////////////////////
@@ -4,8 +4,7 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -13,20 +12,27 @@ import (
 type Values map[string]interface{}
 
 // Marshal converts values into Dynamodb values format.
-func (values Values) Marshal(dest *map[string]*dynamodb.AttributeValue) {
-	result, err := dynamodbattribute.MarshalMap(values)
-	if err != nil {
+func (values Values) Marshal(dest *map[string]types.AttributeValue) {
+	if err := values.marshal(dest); err != nil {
 		ss.S.Log().Panic(
 			ss.
 				NewLogMsg(`failed to serialize DDB request values`).
 				AddErr(err).
 				AddDump(values))
 	}
+}
+
+func (values Values) marshal(dest *map[string]types.AttributeValue) error {
+	result, err := MarshalMap(values)
+	if err != nil {
+		return newSerializationError(err, "failed to serialize values")
+	}
 	if *dest == nil {
 		*dest = result
-		return
+		return nil
 	}
 	for k, v := range result {
 		(*dest)[k] = v
 	}
+	return nil
 }
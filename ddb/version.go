@@ -5,6 +5,12 @@ package ddb
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// RecordVersion is a version of the record with optimistic locking,
+// see Versioned.
+type RecordVersion uint64
+
+////////////////////////////////////////////////////////////////////////////////
+
 // MembershipVersion is a version of membership.
 type MembershipVersion uint
 
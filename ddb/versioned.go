@@ -0,0 +1,157 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Versioned describes the record with optimistic locking by the version
+// attribute. CreateIfNotExists writes version 1, Update and Replace write
+// only if the record has the expected version and increase it. CreateOrReplace
+// with the expected version 0 creates the record only if it doesn't exist,
+// otherwise it works as Replace. If the record
+// is changed by another write, the request returns ErrVersionConflict, even if
+// conditional check fail is allowed.
+type Versioned interface {
+	// GetVersionField returns version attribute name.
+	GetVersionField() string
+	// GetVersion returns the record version which is expected in the database.
+	GetVersion() RecordVersion
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// versionCheck is the expected record version.
+type versionCheck struct {
+	field    string
+	expected RecordVersion
+}
+
+// newVersionCheck returns the version check if the record is versioned,
+// or nil if it's not.
+func newVersionCheck(record interface{}) *versionCheck {
+	switch source := record.(type) {
+	case Versioned:
+		return &versionCheck{
+			field:    source.GetVersionField(),
+			expected: source.GetVersion(),
+		}
+	case keySource:
+		return newVersionCheck(source.getKeySource())
+	}
+	return nil
+}
+
+// keySource is implemented by internal key wrappers to provide the source key.
+type keySource interface{ getKeySource() Key }
+
+func (check versionCheck) getCondition() Condition {
+	return Name(check.field).Equal(Value(check.expected))
+}
+
+// getReplaceCondition returns the condition to create the record if
+// the expected version is 0, or to replace the record with the expected
+// version.
+func (check versionCheck) getReplaceCondition(partitionField string) Condition {
+	if check.expected == 0 {
+		return AttributeNotExists(partitionField)
+	}
+	return check.getCondition()
+}
+
+func (check versionCheck) getUpdate() UpdateAction {
+	return Set(check.field, check.expected+1)
+}
+
+// setItemVersion sets the version attribute in the item which will be written.
+func (check versionCheck) setItemVersion(
+	item map[string]types.AttributeValue,
+	version RecordVersion,
+) error {
+	value, err := marshal(version)
+	if err != nil {
+		return newSerializationError(err, "failed to serialize record version")
+	}
+	item[check.field] = value
+	return nil
+}
+
+// isConflict returns true if the database item doesn't have the expected
+// version, or doesn't exist.
+func (check versionCheck) isConflict(
+	item map[string]types.AttributeValue,
+) bool {
+	value, has := item[check.field]
+	if !has {
+		return true
+	}
+	var version RecordVersion
+	if err := unmarshal(value, &version); err != nil {
+		return true
+	}
+	return version != check.expected
+}
+
+func (check versionCheck) newConflictError(source error) error {
+	return Error{
+		kind: ErrVersionConflict,
+		err: fmt.Errorf(
+			"record is changed, expected version %d: %w",
+			check.expected,
+			source),
+	}
+}
+
+// addToUpdateExpression adds the version increasing into the update
+// expression, into the existing set-clause if it has it.
+func (check versionCheck) addToUpdateExpression(
+	expression string,
+	builder *expressionBuilder,
+) string {
+	return addToUpdateExpression(expression, check.getUpdate(), builder)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// checkVersionConflict reads the record version after conditional check fail,
+// and returns ErrVersionConflict if the version is changed, otherwise returns
+// the source error.
+func (client *client) checkVersionConflict(
+	ctx context.Context,
+	source error,
+	check *versionCheck,
+	table *string,
+	key map[string]types.AttributeValue,
+) error {
+	if check == nil || getErrorKind(source) != ErrConditionFailed {
+		return source
+	}
+	input := dynamodb.GetItemInput{
+		TableName:      table,
+		Key:            key,
+		ConsistentRead: aws.Bool(true),
+	}
+	input.ProjectionExpression = aws.String(
+		newExpressionBuilder(&input.ExpressionAttributeNames, nil).path(check.field))
+	var response *dynamodb.GetItemOutput
+	err := client.retry(ctx, func() (err error) {
+		response, err = client.db.GetItem(ctx, &input)
+		return err
+	})
+	if err != nil {
+		return newError(err)
+	}
+	if check.isConflict(response.Item) {
+		return check.newConflictError(source)
+	}
+	return source
+}
+
+////////////////////////////////////////////////////////////////////////////////
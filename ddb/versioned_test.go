@@ -0,0 +1,154 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package ddb_test
+
+import (
+	"testing"
+
+	"github.com/palchukovsky/ss/ddb"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type testVersionedKey struct {
+	testKey
+	Version ddb.RecordVersion `json:"-"`
+}
+
+func (testVersionedKey) GetVersionField() string { return "ver" }
+func (key testVersionedKey) GetVersion() ddb.RecordVersion {
+	return key.Version
+}
+
+type testVersionedData struct {
+	testData
+	Version ddb.RecordVersion `json:"ver"`
+}
+
+func (record testVersionedData) GetData() interface{} { return record }
+func (testVersionedData) GetVersionField() string     { return "ver" }
+func (record testVersionedData) GetVersion() ddb.RecordVersion {
+	return record.Version
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_DDB_Versioned_Write(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+
+	getVersion := func() ddb.RecordVersion {
+		record, _ := ddb.NewTable[testVersionedData](client).Get(newTestKey("1"))
+		return record.Version
+	}
+	newKey := func(version ddb.RecordVersion) testVersionedKey {
+		return testVersionedKey{testKey: newTestKey("1"), Version: version}
+	}
+
+	assert.True(
+		client.
+			CreateIfNotExists(testVersionedData{testData: newTestData("1", "a", 10)}).
+			Request().
+			IsSuccess())
+	assert.Equal(ddb.RecordVersion(1), getVersion())
+
+	assert.True(
+		client.Update(newKey(1)).Apply(ddb.Set("val", 5)).Request().IsSuccess())
+	assert.Equal(ddb.RecordVersion(2), getVersion())
+	{
+		update := client.Update(newKey(1)).Apply(ddb.Set("val", 6))
+		update.AllowConditionalCheckFail()
+		result, err := update.RequestE()
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+		assert.False(result.IsSuccess())
+	}
+	{
+		// Conditional check fail with the expected version is not a conflict.
+		update := client.
+			Update(newKey(2)).
+			Apply(ddb.Set("val", 6)).
+			ConditionExpr(ddb.Name("val").Equal(ddb.Value(0)))
+		update.AllowConditionalCheckFail()
+		result, err := update.RequestE()
+		assert.NoError(err)
+		assert.False(result.IsSuccess())
+	}
+	assert.Equal(ddb.RecordVersion(2), getVersion())
+
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.UpdateExpr(newKey(1), ddb.Set("val", 7))
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+	}
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.Update(newKey(2), "set val = :v").Value(":v", 7)
+		assert.True(client.Write(trans).IsSuccess())
+		assert.Equal(ddb.RecordVersion(3), getVersion())
+	}
+	{
+		record := testVersionedData{testData: newTestData("1", "b", 20), Version: 3}
+		trans := ddb.NewWriteTrans(false)
+		trans.Replace(record)
+		assert.True(client.Write(trans).IsSuccess())
+		assert.Equal(ddb.RecordVersion(4), getVersion())
+
+		trans = ddb.NewWriteTrans(false)
+		trans.Replace(record)
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+	}
+}
+
+func Test_DDB_Versioned_CreateOrReplace(test *testing.T) {
+	assert := assert.New(test)
+	client, _ := newTestClient(test)
+	table := ddb.NewTable[testVersionedData](client)
+
+	getVersion := func() ddb.RecordVersion {
+		record, _ := table.Get(newTestKey("1"))
+		return record.Version
+	}
+	newRecord := func(version ddb.RecordVersion) testVersionedData {
+		return testVersionedData{
+			testData: newTestData("1", "a", 10),
+			Version:  version,
+		}
+	}
+
+	assert.True(table.Put(newRecord(0)).Request().IsSuccess())
+	assert.Equal(ddb.RecordVersion(1), getVersion())
+	{
+		result, err := table.Put(newRecord(0)).RequestE()
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+		assert.False(result.IsSuccess())
+	}
+	assert.True(client.CreateOrReplace(newRecord(1)).Request().IsSuccess())
+	assert.Equal(ddb.RecordVersion(2), getVersion())
+	{
+		create := client.CreateOrReplace(newRecord(1))
+		create.AllowConditionalCheckFail()
+		result, err := create.RequestE()
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+		assert.False(result.IsSuccess())
+	}
+
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.CreateOrReplace(newRecord(2))
+		assert.True(client.Write(trans).IsSuccess())
+		assert.Equal(ddb.RecordVersion(3), getVersion())
+	}
+	{
+		trans := ddb.NewWriteTrans(false)
+		trans.CreateOrReplace(newRecord(2))
+		_, err := client.WriteE(trans)
+		assert.ErrorIs(err, ddb.ErrVersionConflict)
+	}
+	assert.Equal(ddb.RecordVersion(3), getVersion())
+}
+
+////////////////////////////////////////////////////////////////////////////////
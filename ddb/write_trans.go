@@ -4,8 +4,8 @@
 package ddb
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 )
 
@@ -23,19 +23,24 @@ type WriteTrans interface {
 	Replace(DataRecord) CreateTrans
 	Check(KeyRecord) CheckTrans
 	Update(key KeyRecord, update string) UpdateTrans
+	UpdateExpr(key KeyRecord, actions ...UpdateAction) UpdateTrans
 	Delete(KeyRecord) DeleteTrans
 	DeleteIfExisting(KeyRecord) DeleteTrans
+	// Outbox adds the message into the outbox table, so the message is sent
+	// only if the transaction is succeeded.
+	Outbox(OutboxMessage) CreateTrans
 
 	MarshalLogMsg(destination map[string]interface{})
 
 	GetResult() *dynamodb.TransactWriteItemsInput
 	getAllowedToFailConditionalChecks() []bool
+	getVersionChecks() []*versionCheck
 }
 
 // NewWriteTrans creates new write transaction builder.
 func NewWriteTrans(isConditionalCheckFail bool) WriteTrans {
 	return &writeTrans{
-		result:                 []*dynamodb.TransactWriteItem{},
+		result:                 []types.TransactWriteItem{},
 		isConditionalCheckFail: isConditionalCheckFail,
 	}
 }
@@ -49,10 +54,12 @@ type WriteTransExpression interface{ CheckedTransExpression }
 type writeTrans struct {
 	ss.NoCopyImpl
 
-	result []*dynamodb.TransactWriteItem
+	result []types.TransactWriteItem
 
 	isConditionalCheckFail         bool
 	allowedToFailConditionalChecks []bool
+	// versionChecks has the expected version for each versioned item.
+	versionChecks []*versionCheck
 }
 
 func (trans *writeTrans) GetResult() *dynamodb.TransactWriteItemsInput {
@@ -63,6 +70,10 @@ func (trans *writeTrans) getAllowedToFailConditionalChecks() []bool {
 	return trans.allowedToFailConditionalChecks
 }
 
+func (trans *writeTrans) getVersionChecks() []*versionCheck {
+	return trans.versionChecks
+}
+
 func (trans *writeTrans) IsEmpty() bool { return len(trans.result) == 0 }
 func (trans *writeTrans) GetSize() int  { return len(trans.result) }
 
@@ -79,12 +90,13 @@ type writeTransExpression struct {
 
 func newWriteTransExpression(
 	trans *writeTrans,
-	result dynamodb.TransactWriteItem,
+	result types.TransactWriteItem,
 ) writeTransExpression {
-	trans.result = append(trans.result, &result)
+	trans.result = append(trans.result, result)
 	trans.allowedToFailConditionalChecks = append(
 		trans.allowedToFailConditionalChecks,
 		trans.isConditionalCheckFail)
+	trans.versionChecks = append(trans.versionChecks, nil)
 	return writeTransExpression{
 		checkedTransExpression: newCheckedTransExpression(
 			len(trans.result)-1,
@@ -95,20 +107,51 @@ func newWriteTransExpression(
 
 func (trans *writeTransExpression) marshalValues(
 	source Values,
-	destination *map[string]*dynamodb.AttributeValue,
+	destination *map[string]types.AttributeValue,
 ) {
 	source.Marshal(destination)
 }
 
+func (trans *writeTransExpression) addCondition(
+	condition Condition,
+	expression **string,
+	names *map[string]string,
+	values *map[string]types.AttributeValue,
+) {
+	err := newExpressionBuilder(names, values).addCondition(expression, condition)
+	if err != nil {
+		ss.S.Log().Panic(
+			ss.NewLogMsg("failed to build transaction condition").AddErr(err))
+	}
+}
+
+// setVersionCheck sets the expected version of the item, so the version
+// conflict could be found in the transaction result.
+func (trans *writeTransExpression) setVersionCheck(
+	check *versionCheck,
+	returnValuesOnConditionCheckFailure *types.ReturnValuesOnConditionCheckFailure,
+) {
+	trans.trans.versionChecks[trans.index] = check
+	trans.returnOnConditionalCheckFail(returnValuesOnConditionCheckFailure)
+}
+
+// returnOnConditionalCheckFail requests the item which failed the conditional
+// check, see TransResult.ReadConflictItem.
+func (*writeTransExpression) returnOnConditionalCheckFail(
+	returnValuesOnConditionCheckFailure *types.ReturnValuesOnConditionCheckFailure,
+) {
+	*returnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+}
+
 func (*writeTransExpression) addAlias(
 	name string,
 	value string,
-	dest *map[string]*string,
+	dest *map[string]string,
 ) {
 	if *dest == nil {
-		*dest = map[string]*string{name: aws.String(value)}
+		*dest = map[string]string{name: value}
 	} else {
-		(*dest)[name] = aws.String(value)
+		(*dest)[name] = value
 	}
 }
 
@@ -5,9 +5,10 @@ package ss
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/uuid"
 )
 
@@ -68,23 +69,29 @@ func (id *EntityID) UnmarshalBinary(data []byte) error {
 }
 
 // MarshalDynamoDBAttributeValue implements serialization for Dynamodb.
-func (id EntityID) MarshalDynamoDBAttributeValue(
-	result *dynamodb.AttributeValue,
-) error {
-	var err error
-	result.B, err = id.MarshalBinary()
-	return err
+func (id EntityID) MarshalDynamoDBAttributeValue() (
+	types.AttributeValue,
+	error,
+) {
+	value, err := id.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &types.AttributeValueMemberB{Value: value}, nil
 }
 
 // UnmarshalDynamoDBAttributeValue implements reading from Dynamodb.
 func (id *EntityID) UnmarshalDynamoDBAttributeValue(
-	source *dynamodb.AttributeValue,
+	source types.AttributeValue,
 ) error {
-	if source.S != nil {
+	switch value := source.(type) {
+	case *types.AttributeValueMemberS:
 		// Entity ID could be represent as text.
-		return id.UnmarshalText([]byte(*source.S))
+		return id.UnmarshalText([]byte(value.Value))
+	case *types.AttributeValueMemberB:
+		return id.UnmarshalBinary(value.Value)
 	}
-	return id.UnmarshalBinary(source.B)
+	return errors.New("DynamoDB value is not binary or string")
 }
 
 ////////////////////////////////////////////////////////////////////////////////
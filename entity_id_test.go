@@ -9,7 +9,7 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/google/uuid"
 	"github.com/palchukovsky/ss"
 	"github.com/stretchr/testify/assert"
@@ -98,14 +98,14 @@ func Test_SS_EntityID_MarshalDynamoDB(test *testing.T) {
 	control, err := uuid.Parse(entityUUID)
 	assert.NoError(err)
 
-	ssValue, err := dynamodbattribute.Marshal(id)
+	ssValue, err := attributevalue.Marshal(id)
 	assert.NoError(err)
-	controlValue, err := dynamodbattribute.Marshal(control)
+	controlValue, err := attributevalue.Marshal(control)
 	assert.NoError(err)
-	assert.Equal(*controlValue, *ssValue)
+	assert.Equal(controlValue, ssValue)
 
 	restoredSSValue := &ss.EntityID{}
-	assert.NoError(dynamodbattribute.Unmarshal(controlValue, restoredSSValue))
+	assert.NoError(attributevalue.Unmarshal(controlValue, restoredSSValue))
 	assert.Equal(entityIDSource, restoredSSValue.String())
 
 	ssRecord := struct {
@@ -114,9 +114,9 @@ func Test_SS_EntityID_MarshalDynamoDB(test *testing.T) {
 	controlRecord := struct {
 		Value uuid.UUID `json:"value"`
 	}{Value: control}
-	ssRecordValue, err := dynamodbattribute.MarshalMap(ssRecord)
+	ssRecordValue, err := attributevalue.MarshalMap(ssRecord)
 	assert.NoError(err)
-	controlRecordValue, err := dynamodbattribute.MarshalMap(controlRecord)
+	controlRecordValue, err := attributevalue.MarshalMap(controlRecord)
 	assert.NoError(err)
 	assert.Equal(controlRecordValue, ssRecordValue)
 
@@ -124,6 +124,6 @@ func Test_SS_EntityID_MarshalDynamoDB(test *testing.T) {
 		Value ss.EntityID `json:"value"`
 	}{}
 	assert.NoError(
-		dynamodbattribute.UnmarshalMap(controlRecordValue, restoredSSRecord))
+		attributevalue.UnmarshalMap(controlRecordValue, restoredSSRecord))
 	assert.Equal(entityIDSource, restoredSSRecord.Value.String())
 }
@@ -4,45 +4,52 @@
 package lambda
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewaymanagementapi/types"
 	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
 // Gateway describes the interface of an output gateway.
 type Gateway struct {
-	client *apigatewaymanagementapi.ApiGatewayManagementApi
+	client *apigatewaymanagementapi.Client
 }
 
 // NewGateway creates new gateway instance.
 func NewGateway() Gateway {
 	config := ss.S.Config()
-	session, err := session.NewSession(
-		&aws.Config{
-			Region: aws.String(config.AWS.Region),
-			Credentials: credentials.NewStaticCredentials(
-				config.AWS.AccessKey.ID,
-				config.AWS.AccessKey.Secret,
-				"",
-			),
-			Endpoint: aws.String(config.AWS.Gateway.App.Endpoint),
-		},
-	)
-	if err != nil {
-		ss.S.Log().Panic(
-			ss.NewLogMsg(`failed to create lambda session`).AddErr(err))
+	endpoint := aws.Endpoint{
+		URL:           config.AWS.Gateway.App.Endpoint,
+		SigningRegion: config.AWS.Region,
 	}
-	return Gateway{client: apigatewaymanagementapi.New(session)}
+	client := apigatewaymanagementapi.New(apigatewaymanagementapi.Options{
+		Region: config.AWS.Region,
+		Credentials: credentials.NewStaticCredentialsProvider(
+			config.AWS.AccessKey.ID,
+			config.AWS.AccessKey.Secret,
+			"",
+		),
+		EndpointResolver: apigatewaymanagementapi.EndpointResolverFunc(
+			func(
+				string,
+				apigatewaymanagementapi.EndpointResolverOptions,
+			) (aws.Endpoint, error) {
+				return endpoint, nil
+			}),
+	})
+	return Gateway{client: client}
 }
 
 // NewSessionGatewaySendSession creates a new session to send data thought
@@ -67,6 +74,42 @@ func (gateway Gateway) NewSessionGatewaySendSession(
 }
 
 func (gateway Gateway) Serialize(data interface{}) []byte {
+	return serializeGatewayMessage(data)
+}
+
+// ErrGatewayConnectionGone is returned when the gateway connection is closed.
+var ErrGatewayConnectionGone = errors.New("gateway connection is gone")
+
+// Post sends serialized data to the connection synchronously.
+func (gateway Gateway) Post(connection ss.ConnectionID, data []byte) error {
+	_, err := gateway.client.PostToConnection(
+		context.Background(),
+		&apigatewaymanagementapi.PostToConnectionInput{
+			ConnectionId: aws.String(string(connection)),
+			Data:         data,
+		})
+	var goneErr *types.GoneException
+	if errors.As(err, &goneErr) {
+		return fmt.Errorf("%w: %v", ErrGatewayConnectionGone, err)
+	}
+	return err
+}
+
+// NewGatewayOutboxMessage creates the message for the connection, which is
+// sent by the outbox lambda if the transaction is succeeded, see
+// ddb.WriteTrans.Outbox.
+func NewGatewayOutboxMessage(
+	connection ss.ConnectionID,
+	data interface{},
+) ddb.OutboxMessage {
+	return ddb.OutboxMessage{
+		Kind:      ddb.OutboxMessageKindGateway,
+		Recipient: string(connection),
+		Data:      serializeGatewayMessage(data),
+	}
+}
+
+func serializeGatewayMessage(data interface{}) []byte {
 	result, err := json.Marshal(struct {
 		Method string      `json:"m"`
 		Data   interface{} `json:"d"`
@@ -199,20 +242,12 @@ func (session *gatewaySendSession) send(
 	data []byte,
 ) {
 
-	_, err := session.
-		gateway.
-		client.
-		PostToConnection(
-			&apigatewaymanagementapi.PostToConnectionInput{
-				ConnectionId: aws.String(string(connection)),
-				Data:         data,
-			})
+	err := session.gateway.Post(connection, data)
 
 	var processed uint32
 	if err != nil {
 
-		var goneErr *apigatewaymanagementapi.GoneException
-		if errors.As(err, &goneErr) {
+		if errors.Is(err, ErrGatewayConnectionGone) {
 			logMessage := ss.
 				NewLogMsg("no connection to send gateway message").
 				Add(connection)
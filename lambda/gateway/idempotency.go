@@ -0,0 +1,221 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package gatewaylambda
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/db"
+	"github.com/palchukovsky/ss/ddb"
+)
+
+// IdempotencyTTL is the time to keep the response to return it for retries.
+const IdempotencyTTL = 24 * time.Hour
+
+// idempotencyBeginAttempts is the max number of attempts to read or to set
+// the execution mark, if it's changed by other executions at the same time.
+const idempotencyBeginAttempts = 3
+
+// IdempotencyState is the state of the request with the idempotency key.
+type IdempotencyState int
+
+const (
+	// IdempotencyStateNew means that the request has to be executed.
+	IdempotencyStateNew IdempotencyState = iota
+	// IdempotencyStateInProgress means that the request is being executed
+	// by another lambda.
+	IdempotencyStateInProgress
+	// IdempotencyStateCompleted means that the request is already executed,
+	// and the stored response has to be returned.
+	IdempotencyStateCompleted
+	// IdempotencyStateMismatch means that the idempotency key is already used
+	// by the request with another payload, so the request has to be rejected.
+	IdempotencyStateMismatch
+)
+
+// Idempotency guards the request execution by the idempotency key, so
+// the retried request gets the stored response instead of the repeated
+// execution. Only the response of the request is stored, other effects, like
+// messages to connections, are not repeated for retries.
+type Idempotency struct {
+	ss.NoCopyImpl
+
+	client  ddb.Client
+	key     db.IdempotencyKey
+	token   string
+	request string
+	log     ss.LogStream
+}
+
+// NewIdempotency creates new idempotency guard for the request. The caller is
+// the identity of the request sender (the user ID, or credentials if
+// the request is anonymous), which is stored only as the hash. The request is
+// the request payload, the retry with the same key and another payload gets
+// IdempotencyStateMismatch.
+func NewIdempotency(
+	caller string,
+	command string,
+	key string,
+	request []byte,
+	log ss.LogStream,
+) *Idempotency {
+	return &Idempotency{
+		client: ddb.GetClientInstance(),
+		key: db.NewIdempotencyKey(
+			HashIdempotencyData([]byte(caller)),
+			command,
+			key),
+		token:   ss.NewEntityID().String(),
+		request: HashIdempotencyData(request),
+		log:     log,
+	}
+}
+
+// HashIdempotencyData returns the hash of the data parts, so the data could
+// be a part of the idempotency key, or could be compared with the data
+// of the previous request.
+func HashIdempotencyData(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		// The part size is hashed too, so the parts could not be moved into
+		// the neighbor part.
+		size := make([]byte, binary.MaxVarintLen64)
+		hash.Write(size[:binary.PutUvarint(size, uint64(len(part)))])
+		hash.Write(part)
+	}
+	return base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// Begin marks the request as executing and returns IdempotencyStateNew, or
+// returns the state of the previous execution, and the stored response if
+// the request is already executed. The execution which is not completed
+// in the lambda timeout is considered as failed, so the request could be
+// executed again.
+func (idempotency *Idempotency) Begin() (
+	IdempotencyState,
+	events.APIGatewayProxyResponse,
+) {
+	for i := 0; i < idempotencyBeginAttempts; i++ {
+		state, response, isSet := idempotency.begin()
+		if isSet {
+			return state, response
+		}
+	}
+	idempotency.log.Warn(
+		ss.NewLogMsg(
+			"execution mark is changed by other executions %d times",
+			idempotencyBeginAttempts))
+	return IdempotencyStateInProgress, events.APIGatewayProxyResponse{}
+}
+
+// begin tries to set the execution mark, or reads the previous execution,
+// returns false if the mark has to be checked again.
+func (idempotency *Idempotency) begin() (
+	IdempotencyState,
+	events.APIGatewayProxyResponse,
+	bool,
+) {
+	now := ss.Now()
+	record := idempotency.newRecord(now.Add(ss.S.Config().AWS.LambdaTimeout))
+
+	create := idempotency.client.CreateIfNotExists(record)
+	create.AllowConditionalCheckFail()
+	if create.Request().IsSuccess() {
+		return IdempotencyStateNew, events.APIGatewayProxyResponse{}, true
+	}
+
+	var previous db.Idempotency
+	previous.IdempotencyKeyValue = idempotency.key.IdempotencyKeyValue
+	if !idempotency.client.Find(&previous).Request() {
+		// The record is removed by the failed execution, or by the TTL.
+		return 0, events.APIGatewayProxyResponse{}, false
+	}
+	if previous.Request != idempotency.request {
+		idempotency.log.Warn(
+			ss.NewLogMsg("idempotency key is used by another request"))
+		return IdempotencyStateMismatch, events.APIGatewayProxyResponse{}, true
+	}
+	if previous.IsCompleted {
+		var result events.APIGatewayProxyResponse
+		if err := json.Unmarshal([]byte(previous.Response), &result); err != nil {
+			idempotency.log.Panic(
+				ss.
+					NewLogMsg("failed to parse stored response").
+					AddErr(err).
+					AddDump(previous))
+		}
+		return IdempotencyStateCompleted, result, true
+	}
+	if !previous.Expiration.Before(now) {
+		return IdempotencyStateInProgress, events.APIGatewayProxyResponse{}, true
+	}
+
+	idempotency.log.Warn(
+		ss.NewLogMsg("repeating expired execution").AddDump(previous))
+	replace := idempotency.client.
+		CreateOrReplace(record).
+		ConditionExpr(
+			ddb.And(
+				ddb.Name("done").Equal(ddb.Value(false)),
+				ddb.Name("token").Equal(ddb.Value(previous.Token))))
+	replace.AllowConditionalCheckFail()
+	if !replace.Request().IsSuccess() {
+		// The expired execution is taken by another lambda, or is completed.
+		return 0, events.APIGatewayProxyResponse{}, false
+	}
+	return IdempotencyStateNew, events.APIGatewayProxyResponse{}, true
+}
+
+// Complete stores the response of the executed request. The response is not
+// stored if the execution mark is taken by another execution as this
+// execution has been expired.
+func (idempotency *Idempotency) Complete(
+	response events.APIGatewayProxyResponse,
+) {
+	dump, err := json.Marshal(response)
+	if err != nil {
+		idempotency.log.Panic(
+			ss.
+				NewLogMsg("failed to marshal response to store").
+				AddErr(err).
+				AddResponse(response))
+	}
+	record := idempotency.newRecord(ss.Now().Add(IdempotencyTTL))
+	record.IsCompleted = true
+	record.Response = string(dump)
+	put := idempotency.client.
+		CreateOrReplace(record).
+		ConditionExpr(ddb.Name("token").Equal(ddb.Value(idempotency.token)))
+	put.AllowConditionalCheckFail()
+	if !put.Request().IsSuccess() {
+		idempotency.log.Warn(
+			ss.NewLogMsg(
+				"execution mark is taken by another execution, " +
+					"response is not stored"))
+	}
+}
+
+// Abort removes the execution mark, so the request could be executed again.
+// The mark of another execution is not removed.
+func (idempotency *Idempotency) Abort() {
+	request := idempotency.client.
+		DeleteIfExisting(idempotency.key).
+		ConditionExpr(ddb.Name("token").Equal(ddb.Value(idempotency.token)))
+	request.AllowConditionalCheckFail()
+	request.Request()
+}
+
+func (idempotency *Idempotency) newRecord(expiration ss.Time) db.Idempotency {
+	return db.NewIdempotency(
+		idempotency.key,
+		idempotency.token,
+		idempotency.request,
+		expiration)
+}
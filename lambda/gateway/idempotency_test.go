@@ -0,0 +1,147 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package gatewaylambda_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/db"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	gate "github.com/palchukovsky/ss/lambda/gateway"
+	mock_ss "github.com/palchukovsky/ss/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testLog accepts warnings of idempotency, other log methods are not
+// expected.
+type testLog struct{ ss.Log }
+
+func (testLog) Warn(*ss.LogMsg) {}
+
+// setTestService sets the service with the in-memory database, executions
+// are expired after the lambda timeout.
+func setTestService(test *testing.T, lambdaTimeout time.Duration) {
+	mock := gomock.NewController(test)
+	test.Cleanup(mock.Finish)
+
+	var config ss.ServiceConfig
+	config.AWS.LambdaTimeout = lambdaTimeout
+
+	service := mock_ss.NewMockService(mock)
+	service.EXPECT().
+		NewBuildEntityName(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(name string) string { return "test_" + name })
+	service.EXPECT().
+		SubscribeForLambdaTimeout().
+		AnyTimes().
+		Return(make(chan struct{}))
+	service.EXPECT().Config().AnyTimes().Return(config)
+	service.EXPECT().
+		AddLambdaScopeHandler(gomock.Any()).
+		AnyTimes().
+		Return(func() {})
+	ss.Set(service)
+
+	database := ddbtest.NewDB()
+	database.CreateTable(db.Idempotency{})
+	ddb.SetClientInstance(ddbtest.NewClient(database))
+	test.Cleanup(func() { ddb.SetClientInstance(nil) })
+}
+
+func newTestIdempotency(caller, request string) *gate.Idempotency {
+	return gate.NewIdempotency(
+		caller,
+		"command",
+		"key",
+		[]byte(request),
+		testLog{})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_GatewayLambda_Idempotency_Complete(test *testing.T) {
+	assert := assert.New(test)
+	setTestService(test, time.Minute)
+
+	first := newTestIdempotency("user", "request")
+	state, _ := first.Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+
+	state, _ = newTestIdempotency("user", "request").Begin()
+	assert.Equal(gate.IdempotencyStateInProgress, state)
+
+	// The key is scoped by the caller.
+	state, _ = newTestIdempotency("other", "request").Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+
+	response := events.APIGatewayProxyResponse{
+		StatusCode: http.StatusCreated,
+		Body:       "body",
+	}
+	first.Complete(response)
+
+	state, stored := newTestIdempotency("user", "request").Begin()
+	assert.Equal(gate.IdempotencyStateCompleted, state)
+	assert.Equal(response, stored)
+
+	// The key could not be reused by another request.
+	state, _ = newTestIdempotency("user", "another").Begin()
+	assert.Equal(gate.IdempotencyStateMismatch, state)
+}
+
+func Test_GatewayLambda_Idempotency_Abort(test *testing.T) {
+	assert := assert.New(test)
+	setTestService(test, time.Minute)
+
+	first := newTestIdempotency("user", "request")
+	state, _ := first.Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+
+	// Only the execution, which has set the mark, could remove it.
+	other := newTestIdempotency("user", "request")
+	state, _ = other.Begin()
+	assert.Equal(gate.IdempotencyStateInProgress, state)
+	other.Abort()
+	state, _ = newTestIdempotency("user", "request").Begin()
+	assert.Equal(gate.IdempotencyStateInProgress, state)
+
+	first.Abort()
+	state, _ = newTestIdempotency("user", "request").Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+}
+
+func Test_GatewayLambda_Idempotency_Expired(test *testing.T) {
+	assert := assert.New(test)
+	// Each execution is expired at once.
+	setTestService(test, -time.Minute)
+
+	expired := newTestIdempotency("user", "request")
+	state, _ := expired.Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+
+	next := newTestIdempotency("user", "request")
+	state, _ = next.Begin()
+	assert.Equal(gate.IdempotencyStateNew, state)
+
+	// The expired execution can't complete or remove the mark of the next
+	// execution.
+	expired.Complete(events.APIGatewayProxyResponse{Body: "expired"})
+	expired.Abort()
+	next.Complete(events.APIGatewayProxyResponse{Body: "next"})
+
+	state, response := newTestIdempotency("user", "request").Begin()
+	assert.Equal(gate.IdempotencyStateCompleted, state)
+	assert.Equal("next", response.Body)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -25,13 +25,34 @@ func NewService(lambda Lambda) lambda.Service {
 	}
 }
 
+// NewIdempotentService creates new lambda service instance for lambda which
+// works with REST route, and executes each request with the same idempotency
+// key header only once, retries get the stored response. The key is scoped by
+// the authenticated principal, or by credentials if the request is not
+// authorized by the gateway, the key of the anonymous request gets
+// "bad request" error. The retry with another body gets "unprocessable entity"
+// error.
+func NewIdempotentService(lambda Lambda) lambda.Service {
+	return &service{
+		Service:      gate.NewService(),
+		lambda:       lambda,
+		context:      context.Background(),
+		isIdempotent: true,
+	}
+}
+
+// idempotencyKeyHeader is the request header with the idempotency key,
+// headers have to be in lowercase, see validateRequest.
+const idempotencyKeyHeader = "idempotency-key"
+
 type awsRequest = events.APIGatewayProxyRequest
 type awsResponse = events.APIGatewayProxyResponse
 
 type service struct {
 	gate.Service
-	lambda  Lambda
-	context context.Context
+	lambda       Lambda
+	context      context.Context
+	isIdempotent bool
 }
 
 func (service service) Start() {
@@ -68,7 +89,67 @@ func (service service) handle(request awsRequest) awsResponse {
 		return awsResponse{StatusCode: http.StatusBadRequest}
 	}
 
-	if err := service.lambda.Execute(&lambdaRequest); err != nil {
+	var key string
+	if service.isIdempotent {
+		key = request.Headers[idempotencyKeyHeader]
+	}
+	if key == "" {
+		return service.execute(&lambdaRequest)
+	}
+	caller := getIdempotencyCaller(request)
+	if caller == "" {
+		lambdaRequest.Log().Warn(
+			ss.NewLogMsg(`idempotency key without caller identity`))
+		return awsResponse{StatusCode: http.StatusBadRequest}
+	}
+	idempotency := gate.NewIdempotency(
+		caller,
+		ss.S.Name(),
+		key,
+		[]byte(request.Body),
+		lambdaRequest.Log())
+	switch state, response := idempotency.Begin(); state {
+	case gate.IdempotencyStateCompleted:
+		return response
+	case gate.IdempotencyStateInProgress:
+		return awsResponse{StatusCode: http.StatusConflict}
+	case gate.IdempotencyStateMismatch:
+		return awsResponse{StatusCode: http.StatusUnprocessableEntity}
+	}
+	isCompleted := false
+	defer func() {
+		if !isCompleted {
+			idempotency.Abort()
+		}
+	}()
+	result := service.execute(&lambdaRequest)
+	idempotency.Complete(result)
+	isCompleted = true
+	return result
+}
+
+// getIdempotencyCaller returns the identity of the request sender to scope
+// the idempotency key: the principal from the gateway authorizer, or
+// credentials of the request if it's not authorized by the gateway. It returns
+// an empty string for anonymous requests, as anonymous senders could not be
+// separated, and one sender could get the response of another.
+func getIdempotencyCaller(request awsRequest) string {
+	principal, _ := request.RequestContext.Authorizer["principalId"].(string)
+	if principal != "" {
+		return "principal:" + principal
+	}
+	authorization := request.Headers["authorization"]
+	apiKey := request.RequestContext.Identity.APIKey
+	if authorization == "" && apiKey == "" {
+		return ""
+	}
+	return "credentials:" + gate.HashIdempotencyData(
+		[]byte(authorization),
+		[]byte(apiKey))
+}
+
+func (service service) execute(lambdaRequest *request) awsResponse {
+	if err := service.lambda.Execute(lambdaRequest); err != nil {
 		lambdaRequest.Log().Panic(
 			ss.NewLogMsg(`lambda execution error`).AddErr(err))
 	}
@@ -0,0 +1,167 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package restgatewaylambda
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/db"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	mock_ss "github.com/palchukovsky/ss/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testLog accepts all records, panics are repeated, so the test fails.
+type testLog struct{ ss.Log }
+
+func (testLog) Debug(*ss.LogMsg) {}
+func (testLog) Info(*ss.LogMsg)  {}
+func (testLog) Warn(*ss.LogMsg)  {}
+func (testLog) NewSession(func() ss.LogPrefix) ss.LogSession {
+	return testLog{}
+}
+func (testLog) CheckPanic(panicValue interface{}, _ string) {
+	if panicValue != nil {
+		panic(panicValue)
+	}
+}
+
+func setTestService(test *testing.T) {
+	mock := gomock.NewController(test)
+	test.Cleanup(mock.Finish)
+
+	var config ss.ServiceConfig
+	config.AWS.LambdaTimeout = time.Minute
+
+	service := mock_ss.NewMockService(mock)
+	service.EXPECT().Name().AnyTimes().Return("test")
+	service.EXPECT().Build().AnyTimes().Return(ss.Build{})
+	service.EXPECT().Config().AnyTimes().Return(config)
+	service.EXPECT().Log().AnyTimes().Return(testLog{})
+	service.EXPECT().StartLambda(gomock.Any()).AnyTimes()
+	service.EXPECT().CompleteLambda(gomock.Any()).AnyTimes()
+	service.EXPECT().
+		NewBuildEntityName(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(name string) string { return "test_" + name })
+	service.EXPECT().
+		SubscribeForLambdaTimeout().
+		AnyTimes().
+		Return(make(chan struct{}))
+	service.EXPECT().
+		AddLambdaScopeHandler(gomock.Any()).
+		AnyTimes().
+		Return(func() {})
+	ss.Set(service)
+
+	database := ddbtest.NewDB()
+	database.CreateTable(db.Idempotency{})
+	ddb.SetClientInstance(ddbtest.NewClient(database))
+	test.Cleanup(func() { ddb.SetClientInstance(nil) })
+}
+
+// testLambda responds with the number of executions.
+type testLambda struct{ executions *int }
+
+func (lambda testLambda) Execute(request Request) error {
+	*lambda.executions++
+	var data struct {
+		Value int `json:"v"`
+	}
+	request.ReadRequest(&data)
+	request.Respond(struct {
+		Value      int `json:"v"`
+		Executions int `json:"n"`
+	}{
+		Value:      data.Value,
+		Executions: *lambda.executions,
+	})
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_RESTGatewayLambda_Service_Idempotency(test *testing.T) {
+	assert := assert.New(test)
+	setTestService(test)
+
+	executions := 0
+	lambdaService := service{
+		lambda:       testLambda{executions: &executions},
+		isIdempotent: true,
+	}
+
+	newRequest := func(principal, key, body string) awsRequest {
+		result := awsRequest{
+			Headers: map[string]string{
+				"content-type": "application/json; charset=utf-8",
+			},
+			Body: body,
+		}
+		if key != "" {
+			result.Headers[idempotencyKeyHeader] = key
+		}
+		if principal != "" {
+			result.RequestContext.Authorizer = map[string]interface{}{
+				"principalId": principal,
+			}
+		}
+		return result
+	}
+
+	response := lambdaService.handle(newRequest("u1", "k", `{"v":1}`))
+	assert.Equal(http.StatusOK, response.StatusCode)
+	assert.Equal(`{"v":1,"n":1}`, response.Body)
+
+	// The retry gets the stored response.
+	assert.Equal(response, lambdaService.handle(newRequest("u1", "k", `{"v":1}`)))
+	assert.Equal(1, executions)
+
+	// The key is scoped by the principal.
+	assert.Equal(
+		`{"v":1,"n":2}`,
+		lambdaService.handle(newRequest("u2", "k", `{"v":1}`)).Body)
+
+	// The key is scoped by credentials if there is no principal.
+	{
+		request := newRequest("", "k", `{"v":1}`)
+		request.Headers["authorization"] = "a"
+		assert.Equal(`{"v":1,"n":3}`, lambdaService.handle(request).Body)
+		assert.Equal(`{"v":1,"n":3}`, lambdaService.handle(request).Body)
+		request.Headers["authorization"] = "b"
+		assert.Equal(`{"v":1,"n":4}`, lambdaService.handle(request).Body)
+	}
+
+	// The key could not be reused with another body.
+	assert.Equal(
+		events.APIGatewayProxyResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+		},
+		lambdaService.handle(newRequest("u1", "k", `{"v":2}`)))
+	assert.Equal(4, executions)
+
+	// Anonymous callers could not be separated, so the key is rejected.
+	assert.Equal(
+		events.APIGatewayProxyResponse{StatusCode: http.StatusBadRequest},
+		lambdaService.handle(newRequest("", "k", `{"v":1}`)))
+	assert.Equal(4, executions)
+
+	// Requests without the key are executed each time.
+	assert.Equal(
+		`{"v":2,"n":5}`,
+		lambdaService.handle(newRequest("u1", "", `{"v":2}`)).Body)
+	assert.Equal(
+		`{"v":2,"n":6}`,
+		lambdaService.handle(newRequest("u1", "", `{"v":2}`)).Body)
+}
+
+////////////////////////////////////////////////////////////////////////////////
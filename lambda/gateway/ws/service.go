@@ -22,12 +22,29 @@ func NewService(lambda Lambda) lambda.Service {
 	}
 }
 
+// NewIdempotentService creates new lambda service instance for lambda which
+// works with WebSocket route, and executes each request with the same remote
+// ID and the same payload from the same user only once, retries get
+// the stored response. The payload is a part of the key, as the client could
+// reuse remote IDs for other requests after reconnection. The stored response
+// is the whole response to the request, with the data set by
+// Request.Respond, but messages which the lambda sends to connections by
+// the gateway are not stored and are not repeated for retries.
+func NewIdempotentService(lambda Lambda) lambda.Service {
+	return &service{
+		Service:      gate.NewService(),
+		lambda:       lambda,
+		isIdempotent: true,
+	}
+}
+
 type awsResquest = events.APIGatewayWebsocketProxyRequest
 type awsResponse = events.APIGatewayProxyResponse
 
 type service struct {
 	gate.Service
-	lambda Lambda
+	lambda       Lambda
+	isIdempotent bool
 }
 
 func (service service) Start() {
@@ -55,8 +72,48 @@ func (service service) handle(request awsResquest) awsResponse {
 
 	lambdaRequest := newRequest(request, service.Gateway, log)
 
+	var id *string
+	if service.isIdempotent {
+		id = lambdaRequest.ReadRemoteID()
+	}
+	if id == nil {
+		result, _ := service.execute(lambdaRequest)
+		return result
+	}
+	payload := lambdaRequest.readRequest()["d"]
+	idempotency := gate.NewIdempotency(
+		lambdaRequest.GetUserID().String(),
+		ss.S.Name(),
+		*id+"/"+gate.HashIdempotencyData(payload),
+		payload,
+		lambdaRequest.Log())
+	switch state, response := idempotency.Begin(); state {
+	case gate.IdempotencyStateCompleted:
+		return response
+	case gate.IdempotencyStateInProgress:
+		return newResponse(newRejectedResponseBody(lambdaRequest, "in progress"))
+	case gate.IdempotencyStateMismatch:
+		return newResponse(
+			newRejectedResponseBody(lambdaRequest, "idempotency key mismatch"))
+	}
+	isCompleted := false
+	defer func() {
+		if !isCompleted {
+			idempotency.Abort()
+		}
+	}()
+	result, isSucceeded := service.execute(lambdaRequest)
+	if isSucceeded {
+		idempotency.Complete(result)
+		isCompleted = true
+	}
+	return result
+}
+
+func (service service) execute(lambdaRequest *request) (awsResponse, bool) {
 	var response interface{}
-	if err := service.lambda.Execute(lambdaRequest); err != nil {
+	err := service.lambda.Execute(lambdaRequest)
+	if err != nil {
 		lambdaRequest.Log().Error(
 			ss.
 				NewLogMsg(`lambda execution error`).
@@ -66,7 +123,10 @@ func (service service) handle(request awsResquest) awsResponse {
 	} else {
 		response = newSuccessResponseBody(lambdaRequest)
 	}
+	return newResponse(response), err == nil
+}
 
+func newResponse(response interface{}) awsResponse {
 	result := awsResponse{StatusCode: http.StatusOK}
 	if response != nil {
 		dump, err := json.Marshal(response)
@@ -110,3 +170,13 @@ func newErrorResponseBody(request *request) interface{} {
 		Error: "server error",
 	}
 }
+
+func newRejectedResponseBody(request *request, reason string) interface{} {
+	return struct {
+		ID    string `json:"i"`
+		Error string `json:"e"`
+	}{
+		ID:    *request.ReadRemoteID(),
+		Error: reason,
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021-2022, the SS project owners. All rights reserved.
+// Please see the OWNERS and LICENSE files for details.
+
+package wsgatewaylambda
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/db"
+	"github.com/palchukovsky/ss/ddb"
+	ddbtest "github.com/palchukovsky/ss/ddb/test"
+	mock_ss "github.com/palchukovsky/ss/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testLog accepts all records, panics are repeated, so the test fails.
+type testLog struct{ ss.Log }
+
+func (testLog) Debug(*ss.LogMsg) {}
+func (testLog) Info(*ss.LogMsg)  {}
+func (testLog) Warn(*ss.LogMsg)  {}
+func (testLog) Error(*ss.LogMsg) {}
+func (testLog) NewSession(func() ss.LogPrefix) ss.LogSession {
+	return testLog{}
+}
+func (testLog) CheckPanic(panicValue interface{}, _ string) {
+	if panicValue != nil {
+		panic(panicValue)
+	}
+}
+
+func setTestService(test *testing.T) {
+	mock := gomock.NewController(test)
+	test.Cleanup(mock.Finish)
+
+	var config ss.ServiceConfig
+	config.AWS.LambdaTimeout = time.Minute
+
+	service := mock_ss.NewMockService(mock)
+	service.EXPECT().Name().AnyTimes().Return("test")
+	service.EXPECT().Build().AnyTimes().Return(ss.Build{})
+	service.EXPECT().Config().AnyTimes().Return(config)
+	service.EXPECT().Log().AnyTimes().Return(testLog{})
+	service.EXPECT().StartLambda(gomock.Any()).AnyTimes()
+	service.EXPECT().CompleteLambda(gomock.Any()).AnyTimes()
+	service.EXPECT().
+		NewBuildEntityName(gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(name string) string { return "test_" + name })
+	service.EXPECT().
+		SubscribeForLambdaTimeout().
+		AnyTimes().
+		Return(make(chan struct{}))
+	service.EXPECT().
+		AddLambdaScopeHandler(gomock.Any()).
+		AnyTimes().
+		Return(func() {})
+	ss.Set(service)
+
+	database := ddbtest.NewDB()
+	database.CreateTable(db.Idempotency{})
+	ddb.SetClientInstance(ddbtest.NewClient(database))
+	test.Cleanup(func() { ddb.SetClientInstance(nil) })
+}
+
+// testLambda responds with the number of executions, or fails if it's set.
+type testLambda struct {
+	executions *int
+	isFailed   *bool
+}
+
+func (lambda testLambda) Execute(request Request) error {
+	*lambda.executions++
+	if *lambda.isFailed {
+		return errors.New("test error")
+	}
+	var data int
+	request.ReadRequest(&data)
+	request.Respond([]int{data, *lambda.executions})
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func Test_WSGatewayLambda_Service_Idempotency(test *testing.T) {
+	assert := assert.New(test)
+	setTestService(test)
+
+	executions := 0
+	isFailed := false
+	lambdaService := service{
+		lambda:       testLambda{executions: &executions, isFailed: &isFailed},
+		isIdempotent: true,
+	}
+
+	user := ss.NewUserID()
+	newRequest := func(connection, body string) awsResquest {
+		var result awsResquest
+		result.RequestContext.ConnectionID = connection
+		result.RequestContext.Authorizer = map[string]interface{}{
+			"principalId": user.String(),
+		}
+		result.Body = body
+		return result
+	}
+
+	response := lambdaService.handle(newRequest("c1", `{"i":"1","d":10}`))
+	assert.Equal(`{"i":"1","d":[10,1]}`, response.Body)
+
+	// The retry gets the stored response, even by another connection.
+	assert.Equal(
+		response,
+		lambdaService.handle(newRequest("c1", `{"i":"1","d":10}`)))
+	assert.Equal(
+		response,
+		lambdaService.handle(newRequest("c2", `{"i":"1","d":10}`)))
+	assert.Equal(1, executions)
+
+	// The remote ID could be reused by another request.
+	assert.Equal(
+		`{"i":"1","d":[20,2]}`,
+		lambdaService.handle(newRequest("c2", `{"i":"1","d":20}`)).Body)
+
+	// The failed execution is repeated.
+	isFailed = true
+	assert.Equal(
+		`{"i":"2","e":"server error"}`,
+		lambdaService.handle(newRequest("c1", `{"i":"2","d":30}`)).Body)
+	isFailed = false
+	assert.Equal(
+		`{"i":"2","d":[30,4]}`,
+		lambdaService.handle(newRequest("c1", `{"i":"2","d":30}`)).Body)
+	assert.Equal(
+		`{"i":"2","d":[30,4]}`,
+		lambdaService.handle(newRequest("c1", `{"i":"2","d":30}`)).Body)
+	assert.Equal(4, executions)
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -7,7 +7,7 @@ package mock_ddbinstall
 import (
 	reflect "reflect"
 
-	dynamodb "github.com/aws/aws-sdk-go/service/dynamodb"
+	dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -37,6 +37,20 @@ func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
+// AddLambdaScopeHandler mocks base method.
+func (m *MockService) AddLambdaScopeHandler(handler func()) func() {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddLambdaScopeHandler", handler)
+	ret0, _ := ret[0].(func())
+	return ret0
+}
+
+// AddLambdaScopeHandler indicates an expected call of AddLambdaScopeHandler.
+func (mr *MockServiceMockRecorder) AddLambdaScopeHandler(handler interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddLambdaScopeHandler", reflect.TypeOf((*MockService)(nil).AddLambdaScopeHandler), handler)
+}
+
 // Build mocks base method.
 func (m *MockService) Build() ss.Build {
 	m.ctrl.T.Helper()
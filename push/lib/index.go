@@ -11,7 +11,7 @@ import (
 type DeviceUserIndex struct {
 	db.DeviceUserIndex
 	FCMToken ss.FirebaseCloudMessagingToken `json:"fcm"`
-	Key      db.DeviceCryptoKey             `json:"key"`
+	Key      db.DeviceCryptoKey             `json:"key" ddb:"encrypt"`
 }
 
 func (r *DeviceUserIndex) Clear() { *r = DeviceUserIndex{} }
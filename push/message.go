@@ -3,6 +3,13 @@
 
 package push
 
+import (
+	"encoding/json"
+
+	"github.com/palchukovsky/ss"
+	"github.com/palchukovsky/ss/ddb"
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 
 const messageDataFieldName = "d"
@@ -20,3 +27,25 @@ type Message interface {
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+
+// NewOutboxMessage creates the push message for all user devices, which is
+// sent by the outbox lambda if the transaction is succeeded, see
+// ddb.WriteTrans.Outbox.
+func NewOutboxMessage(user ss.UserID, message Message) ddb.OutboxMessage {
+	data, err := json.Marshal(message.GetData())
+	if err != nil {
+		ss.S.Log().Panic(
+			ss.
+				NewLogMsg("failed to serialize outbox push message").
+				AddErr(err).
+				AddDump(message))
+	}
+	return ddb.OutboxMessage{
+		Kind:      ddb.OutboxMessageKindPush,
+		Recipient: user.String(),
+		Type:      string(message.GetType()),
+		Data:      data,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
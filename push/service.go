@@ -6,6 +6,7 @@ package push
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"firebase.google.com/go/messaging"
@@ -49,8 +50,7 @@ type Service struct {
 }
 
 func (service *Service) Sync(log ss.LogStream) {
-	service.taskChan <- nil
-	<-service.taskSyncChan
+	service.wait()
 
 	queue := service.successCount + service.expiredTokenCount
 	if queue == 0 {
@@ -77,7 +77,51 @@ func (service *Service) Push(
 	newMessage func() Message,
 	log ss.LogStream,
 ) {
+	newPush := func() *push {
+		result, err := service.newPush(newMessage, log)
+		if err != nil {
+			log.Panic(ss.NewLogMsg("failed to create push").AddErr(err))
+		}
+		return result
+	}
+	service.push(user, newPush, nil)
+}
+
+// PushE sends the message to user devices, except devices with tokens from
+// the sent list, and waits until it's sent. It returns tokens of devices which
+// got the message, and the first sending error instead of panic, so the caller
+// could retry the message later only for other devices.
+func (service *Service) PushE(
+	user ss.UserID,
+	newMessage func() Message,
+	sent []ss.FirebaseCloudMessagingToken,
+	log ss.LogStream,
+) ([]ss.FirebaseCloudMessagingToken, error) {
+	var err error
+	newPush := func() *push {
+		var result *push
+		result, err = service.newPush(newMessage, log)
+		return result
+	}
+	result := pushResult{
+		skip: make(map[ss.FirebaseCloudMessagingToken]struct{}, len(sent)),
+	}
+	for _, token := range sent {
+		result.skip[token] = struct{}{}
+	}
+	service.push(user, newPush, &result)
+	if err != nil {
+		return nil, err
+	}
+	service.wait()
+	return result.sent, result.err
+}
 
+func (service *Service) push(
+	user ss.UserID,
+	newPush func() *push,
+	result *pushResult,
+) {
 	var device lib.DeviceUserIndex
 	it := service.
 		db.
@@ -87,12 +131,20 @@ func (service *Service) Push(
 
 	var push *push
 	for it.Next() {
+		if result != nil {
+			if _, isSent := result.skip[device.FCMToken]; isSent {
+				continue
+			}
+		}
 		if push == nil {
-			push = service.newPush(newMessage, log)
+			if push = newPush(); push == nil {
+				return
+			}
 		}
 		service.taskChan <- &task{
 			Push:   push,
 			Device: device,
+			Result: result,
 		}
 	}
 }
@@ -100,7 +152,7 @@ func (service *Service) Push(
 func (service *Service) newPush(
 	newMessage func() Message,
 	log ss.LogStream,
-) *push {
+) (*push, error) {
 	messageSource := newMessage()
 
 	if ss.S.Config().IsExtraLogEnabled() {
@@ -112,17 +164,17 @@ func (service *Service) newPush(
 	})
 	if err != nil {
 		// Can't add events into error info as it cloud not be serialized.
-		log.Panic(ss.NewLogMsg("failed to serialize push message").AddErr(err))
+		return nil, fmt.Errorf("failed to serialize push message: %w", err)
 	}
 
 	if service.client == nil {
 		service.client, err = ss.S.Firebase().Messaging(context.Background())
 		if err != nil {
-			log.Panic(ss.NewLogMsg("failed to get Firebase Messaging").AddErr(err))
+			return nil, fmt.Errorf("failed to get Firebase Messaging: %w", err)
 		}
 	}
 
-	return newPush(message, service, log)
+	return newPush(message, service, log), nil
 }
 
 func (service *Service) execTasks() {
@@ -132,15 +184,41 @@ func (service *Service) execTasks() {
 			service.taskSyncChan <- struct{}{}
 			continue
 		}
-		task.Push.send(task.Device)
+		if task.Result == nil {
+			task.Push.send(task.Device)
+			continue
+		}
+		if err := task.Push.sendE(task.Device); err != nil {
+			if task.Result.err == nil {
+				task.Result.err = err
+			}
+			continue
+		}
+		task.Result.sent = append(task.Result.sent, task.Device.FCMToken)
 	}
 }
 
+// wait blocks until all queued tasks are executed.
+func (service *Service) wait() {
+	service.taskChan <- nil
+	<-service.taskSyncChan
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 type task struct {
 	Push   *push
 	Device lib.DeviceUserIndex
+	// Result receives the sending result instead of panic if it's set.
+	Result *pushResult
+}
+
+// pushResult has devices to skip, and devices which got the message, with
+// the first sending error.
+type pushResult struct {
+	skip map[ss.FirebaseCloudMessagingToken]struct{}
+	sent []ss.FirebaseCloudMessagingToken
+	err  error
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -163,6 +241,12 @@ type push struct {
 }
 
 func (push *push) send(device lib.DeviceUserIndex) {
+	if err := push.sendE(device); err != nil {
+		push.log.Panic(ss.NewLogMsg("failed to send push messages").AddErr(err))
+	}
+}
+
+func (push *push) sendE(device lib.DeviceUserIndex) error {
 
 	message := &messaging.Message{
 		Data:  push.newMessage(device.Key),
@@ -195,21 +279,16 @@ func (push *push) send(device lib.DeviceUserIndex) {
 					AddVal("fcm", device.FCMToken).
 					AddDump(string(push.message)))
 		}
-		return
+		return nil
 	}
 
 	if strings.Contains(err.Error(), "registration-token-not-registered") {
 		push.service.expiredTokenCount += 1
 		push.deleteExpiredToken(device)
-		return
+		return nil
 	}
 
-	push.log.Panic(
-		ss.
-			NewLogMsg("failed to send push messages").
-			AddErr(err).
-			AddDump(message))
-
+	return err
 }
 
 func (push *push) newMessage(key db.DeviceCryptoKey) map[string]string {
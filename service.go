@@ -42,6 +42,11 @@ type Service interface {
 	StartLambda(getFailInfo func() []LogMsgAttr)
 	CompleteLambda(panicValue interface{})
 	SubscribeForLambdaTimeout() <-chan struct{}
+	// AddLambdaScopeHandler adds handler which is called when the lambda starts
+	// and when it completes, so the handler could reset data which has to live
+	// only while one lambda invocation. It returns the function which removes
+	// the handler.
+	AddLambdaScopeHandler(handler func()) (remove func())
 
 	NewBuildEntityName(name string) string
 
@@ -133,6 +138,10 @@ type service struct {
 
 	lambdaTimeout lambdaTimeout
 
+	lambdaScopeHandlersMutex sync.Mutex
+	lambdaScopeHandlers      []lambdaScopeHandler
+	lastLambdaScopeHandlerID uint64
+
 	firebase unsafe.Pointer
 }
 
@@ -170,6 +179,8 @@ func (service *service) StartLambda(getFailInfo func() []LogMsgAttr) {
 	timeout -= (time.Duration(500) * time.Millisecond)
 
 	service.lambdaTimeout.Start(timeout, getFailInfo)
+
+	service.callLambdaScopeHandlers()
 }
 
 func (service *service) CompleteLambda(panicValue interface{}) {
@@ -180,12 +191,57 @@ func (service *service) CompleteLambda(panicValue interface{}) {
 	service.log.CheckExit(panicValue)
 
 	service.lambdaTimeout.Cancel()
+
+	service.callLambdaScopeHandlers()
 }
 
 func (service *service) SubscribeForLambdaTimeout() <-chan struct{} {
 	return service.lambdaTimeout.Subscribe()
 }
 
+func (service *service) AddLambdaScopeHandler(handler func()) func() {
+	service.lambdaScopeHandlersMutex.Lock()
+	defer service.lambdaScopeHandlersMutex.Unlock()
+	service.lastLambdaScopeHandlerID++
+	id := service.lastLambdaScopeHandlerID
+	service.lambdaScopeHandlers = append(
+		service.lambdaScopeHandlers,
+		lambdaScopeHandler{id: id, handler: handler})
+	return func() { service.removeLambdaScopeHandler(id) }
+}
+
+func (service *service) removeLambdaScopeHandler(id uint64) {
+	service.lambdaScopeHandlersMutex.Lock()
+	defer service.lambdaScopeHandlersMutex.Unlock()
+	// The new slice is created as handlers could be called at the same time
+	// by the copy of the slice.
+	handlers := make(
+		[]lambdaScopeHandler,
+		0,
+		len(service.lambdaScopeHandlers))
+	for _, handler := range service.lambdaScopeHandlers {
+		if handler.id != id {
+			handlers = append(handlers, handler)
+		}
+	}
+	service.lambdaScopeHandlers = handlers
+}
+
+func (service *service) callLambdaScopeHandlers() {
+	service.lambdaScopeHandlersMutex.Lock()
+	handlers := service.lambdaScopeHandlers
+	service.lambdaScopeHandlersMutex.Unlock()
+
+	for _, handler := range handlers {
+		handler.handler()
+	}
+}
+
+type lambdaScopeHandler struct {
+	id      uint64
+	handler func()
+}
+
 func (service *service) NewBuildEntityName(name string) string {
 	return fmt.Sprintf("%s_%s_%s",
 		service.Product(),
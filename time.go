@@ -11,8 +11,7 @@ import (
 	"time"
 	stdtime "time"
 
-	aws "github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -107,21 +106,24 @@ func (time *Time) UnmarshalText(source []byte) error {
 	return nil
 }
 
-func (time Time) MarshalDynamoDBAttributeValue(
-	result *dynamodb.AttributeValue,
-) error {
-	result.N = aws.String(strconv.FormatInt(time.value.Unix(), 10))
-	return nil
+func (time Time) MarshalDynamoDBAttributeValue() (
+	types.AttributeValue,
+	error,
+) {
+	return &types.AttributeValueMemberN{
+		Value: strconv.FormatInt(time.value.Unix(), 10),
+	}, nil
 }
 
 // UnmarshalDynamoDBAttributeValue implements reading from Dynamodb.
 func (time *Time) UnmarshalDynamoDBAttributeValue(
-	source *dynamodb.AttributeValue,
+	source types.AttributeValue,
 ) error {
-	if source.N == nil {
+	number, isNumber := source.(*types.AttributeValueMemberN)
+	if !isNumber {
 		return errors.New("DynamoDB value is not number")
 	}
-	seconds, err := strconv.ParseInt(*source.N, 10, 0)
+	seconds, err := strconv.ParseInt(number.Value, 10, 0)
 	if err != nil {
 		return err
 	}
@@ -206,9 +208,10 @@ func (time *DateOrTime) UnmarshalText(source []byte) error {
 }
 
 // MarshalDynamoDBAttributeValue implements serialization for Dynamodb.
-func (time DateOrTime) MarshalDynamoDBAttributeValue(
-	result *dynamodb.AttributeValue,
-) error {
+func (time DateOrTime) MarshalDynamoDBAttributeValue() (
+	types.AttributeValue,
+	error,
+) {
 	value := (time.Value.Get().Unix() * 10)
 	if !time.IsDateOnly {
 		// The same time at the day start will be placed in the order:
@@ -218,18 +221,20 @@ func (time DateOrTime) MarshalDynamoDBAttributeValue(
 		// for time ">= 101".
 		value += 1
 	}
-	result.N = aws.String(strconv.FormatInt(value, 10))
-	return nil
+	return &types.AttributeValueMemberN{
+		Value: strconv.FormatInt(value, 10),
+	}, nil
 }
 
 // UnmarshalDynamoDBAttributeValue implements reading from Dynamodb.
 func (time *DateOrTime) UnmarshalDynamoDBAttributeValue(
-	source *dynamodb.AttributeValue,
+	source types.AttributeValue,
 ) error {
-	if source.N == nil {
+	number, isNumber := source.(*types.AttributeValueMemberN)
+	if !isNumber {
 		return errors.New("DynamoDB value is not number")
 	}
-	seconds, err := strconv.ParseInt(*source.N, 10, 0)
+	seconds, err := strconv.ParseInt(number.Value, 10, 0)
 	if err != nil {
 		return err
 	}
@@ -312,20 +317,23 @@ func (date *Date) UnmarshalText(source []byte) error {
 	return nil
 }
 
-func (date Date) MarshalDynamoDBAttributeValue(
-	result *dynamodb.AttributeValue,
-) error {
-	result.N = aws.String(strconv.FormatUint(uint64(date.Number()), 10))
-	return nil
+func (date Date) MarshalDynamoDBAttributeValue() (
+	types.AttributeValue,
+	error,
+) {
+	return &types.AttributeValueMemberN{
+		Value: strconv.FormatUint(uint64(date.Number()), 10),
+	}, nil
 }
 
 func (date *Date) UnmarshalDynamoDBAttributeValue(
-	source *dynamodb.AttributeValue,
+	source types.AttributeValue,
 ) error {
-	if source.N == nil {
+	value, isNumber := source.(*types.AttributeValueMemberN)
+	if !isNumber {
 		return errors.New("DynamoDB value is not number")
 	}
-	number, err := strconv.ParseUint(*source.N, 10, 0)
+	number, err := strconv.ParseUint(value.Value, 10, 0)
 	if err != nil {
 		return err
 	}
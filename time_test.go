@@ -9,9 +9,8 @@ import (
 	"testing"
 	stdtime "time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/palchukovsky/ss"
 	"github.com/stretchr/testify/assert"
 )
@@ -48,24 +47,24 @@ func Test_SS_DateOrTime(test *testing.T) {
 	dateOrTime := ss.NewDateOrTime(time, false)
 	assert.Equal(time.Get().Unix(), dateOrTime.Value.Get().Unix())
 	assert.False(dateOrTime.IsDateOnly)
-	dbVal, err := dynamodbattribute.Marshal(dateOrTime)
+	dbVal, err := attributevalue.Marshal(dateOrTime)
 	assert.NoError(err)
-	assert.Equal(*dbVal.N, "12313179451")
+	assert.Equal(dbVal.(*types.AttributeValueMemberN).Value, "12313179451")
 
 	dateOrTime = ss.DateOrTime{}
-	assert.NoError(dynamodbattribute.Unmarshal(dbVal, &dateOrTime))
+	assert.NoError(attributevalue.Unmarshal(dbVal, &dateOrTime))
 	assert.Equal(time.Get().Unix(), dateOrTime.Value.Get().Unix())
 	assert.False(dateOrTime.IsDateOnly)
 
 	dateOrTime = ss.NewDateOrTime(time, true)
 	assert.Equal(time.Get().Unix(), dateOrTime.Value.Get().Unix())
 	assert.True(dateOrTime.IsDateOnly)
-	dbVal, err = dynamodbattribute.Marshal(dateOrTime)
+	dbVal, err = attributevalue.Marshal(dateOrTime)
 	assert.NoError(err)
-	assert.Equal(*dbVal.N, "12313179450")
+	assert.Equal(dbVal.(*types.AttributeValueMemberN).Value, "12313179450")
 
 	dateOrTime = ss.DateOrTime{}
-	assert.NoError(dynamodbattribute.Unmarshal(dbVal, &dateOrTime))
+	assert.NoError(attributevalue.Unmarshal(dbVal, &dateOrTime))
 	assert.Equal(time.Get().Unix(), dateOrTime.Value.Get().Unix())
 	assert.True(dateOrTime.IsDateOnly)
 
@@ -104,14 +103,14 @@ func Test_SS_Date(test *testing.T) {
 	{
 		date := ss.NewDate(2009, 1, 7)
 		assert.Equal(`"20090107"`, fmt.Sprintf("%q", date))
-		dbVal, err := dynamodbattribute.Marshal(date)
+		dbVal, err := attributevalue.Marshal(date)
 		assert.NoError(err)
-		assert.Equal(*dbVal.N, "20090107")
+		assert.Equal(dbVal.(*types.AttributeValueMemberN).Value, "20090107")
 	}
 	{
 		var date ss.Date
-		dbVal := dynamodb.AttributeValue{N: aws.String("19950101")}
-		assert.NoError(dynamodbattribute.Unmarshal(&dbVal, &date))
+		dbVal := &types.AttributeValueMemberN{Value: "19950101"}
+		assert.NoError(attributevalue.Unmarshal(dbVal, &date))
 		assert.Equal(`"19950101"`, fmt.Sprintf("%q", date))
 	}
 }